@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+	"github.com/tehbooom/elastic-data/internal/config"
+	"github.com/tehbooom/elastic-data/internal/session"
+)
+
+var (
+	cleanupCmd = &cobra.Command{
+		Use:          "cleanup",
+		Short:        "Delete the data streams written to by a session",
+		Long:         "Delete the data streams written to by a session.\nThe data streams of the latest session of the connection profile are listed with their document count and size, then deleted after confirmation.",
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	cleanupCmd.Flags().String(
+		"session",
+		"",
+		"ID of the session to clean up, defaults to the latest session",
+	)
+
+	cleanupCmd.Flags().Bool(
+		"all",
+		false,
+		"clean up every session of the connection profile",
+	)
+
+	cleanupCmd.Flags().Bool(
+		"list",
+		false,
+		"list the sessions of the connection profile without deleting anything",
+	)
+
+	cleanupCmd.Flags().BoolP(
+		"yes",
+		"y",
+		false,
+		"delete without asking for confirmation",
+	)
+
+	cleanupCmd.Flags().Bool(
+		"debug",
+		false,
+		"passing this flag will enable debug logging",
+	)
+
+	cleanupCmd.RunE = func(cmd *cobra.Command, _ []string) error {
+		sessionID, err := cmd.Flags().GetString("session")
+		if err != nil {
+			return fmt.Errorf("cannot parse session flag: %w", err)
+		}
+
+		all, err := cmd.Flags().GetBool("all")
+		if err != nil {
+			return fmt.Errorf("cannot parse all flag: %w", err)
+		}
+
+		if all && sessionID != "" {
+			return fmt.Errorf("all and session cannot be used together")
+		}
+
+		list, err := cmd.Flags().GetBool("list")
+		if err != nil {
+			return fmt.Errorf("cannot parse list flag: %w", err)
+		}
+
+		yes, err := cmd.Flags().GetBool("yes")
+		if err != nil {
+			return fmt.Errorf("cannot parse yes flag: %w", err)
+		}
+
+		profile, err := cmd.Flags().GetString("profile")
+		if err != nil {
+			return fmt.Errorf("cannot parse profile flag: %w", err)
+		}
+
+		debug, err := cmd.Flags().GetBool("debug")
+		if err != nil {
+			return fmt.Errorf("cannot parse debug flag: %w", err)
+		}
+
+		log.SetOutput(os.Stdout)
+		log.SetTimeFormat(time.RFC3339)
+		log.SetReportTimestamp(true)
+		log.SetLevel(log.InfoLevel)
+		if debug {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		return runCleanup(profile, sessionID, all, list, yes)
+	}
+
+	rootCmd.AddCommand(cleanupCmd)
+}
+
+// runCleanup lists the data streams of the selected sessions with their stats and deletes
+// them once confirmed. Data streams that no longer exist are forgotten without asking.
+func runCleanup(profile, sessionID string, all, list, yes bool) error {
+	cfg, cfgPath, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+
+	if profile != "" {
+		if err := cfg.OverrideProfile(profile); err != nil {
+			return err
+		}
+	}
+
+	sessions, err := session.Load(cfgPath)
+	if err != nil {
+		return err
+	}
+	sessions = session.ForProfile(sessions, cfg.Profile())
+
+	if list {
+		for _, recorded := range sessions {
+			log.Info("Session", "id", recorded.ID, "started", recorded.Started.Format(time.RFC3339), "data_streams", len(recorded.DataStreams))
+		}
+		return nil
+	}
+
+	selected, err := selectSessions(sessions, sessionID, all)
+	if err != nil {
+		return err
+	}
+
+	if len(selected) == 0 {
+		log.Info("No sessions to clean up", "profile", cfg.Profile())
+		return nil
+	}
+
+	esConfig, _, err := newClients(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := esConfig.TestConnection(); err != nil {
+		return err
+	}
+
+	dataStreams := session.DataStreams(selected)
+	stats, err := esConfig.DataStreamStats(dataStreams)
+	if err != nil {
+		return err
+	}
+
+	var existing []string
+	var documents int64
+	for _, stat := range stats {
+		log.Info("Data stream", "name", stat.Name, "documents", stat.Documents, "size", stat.Size())
+		existing = append(existing, stat.Name)
+		documents += stat.Documents
+	}
+
+	if len(existing) > 0 {
+		if !yes && !confirm(fmt.Sprintf("Delete %d data streams holding %d documents?", len(existing), documents)) {
+			log.Info("Nothing deleted")
+			return nil
+		}
+
+		if err := esConfig.DeleteDataStreams(existing); err != nil {
+			return err
+		}
+		log.Info("Deleted data streams", "count", len(existing), "documents", documents)
+	} else {
+		log.Info("The data streams of the session no longer exist")
+	}
+
+	for _, recorded := range selected {
+		if err := session.Forget(cfgPath, recorded.ID, recorded.DataStreams); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// selectSessions returns the session with the ID, every session or the latest session
+func selectSessions(sessions []session.Session, sessionID string, all bool) ([]session.Session, error) {
+	switch {
+	case all:
+		return sessions, nil
+	case sessionID != "":
+		for _, recorded := range sessions {
+			if recorded.ID == sessionID {
+				return []session.Session{recorded}, nil
+			}
+		}
+		return nil, fmt.Errorf("session %s not found, use --list to see the sessions", sessionID)
+	case len(sessions) > 0:
+		return sessions[:1], nil
+	default:
+		return nil, nil
+	}
+}
+
+// confirm asks a yes or no question on stdin, anything but yes is a no
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		log.Debug(err)
+		return false
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
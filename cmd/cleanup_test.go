@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/tehbooom/elastic-data/internal/session"
+)
+
+func TestSelectSessions(t *testing.T) {
+	sessions := []session.Session{
+		{ID: "20250102-120000-a1b2c3"},
+		{ID: "20250101-120000-d4e5f6"},
+	}
+
+	tests := []struct {
+		name      string
+		sessions  []session.Session
+		sessionID string
+		all       bool
+		want      []string
+		wantErr   bool
+	}{
+		{name: "latest", sessions: sessions, want: []string{"20250102-120000-a1b2c3"}},
+		{name: "by id", sessions: sessions, sessionID: "20250101-120000-d4e5f6", want: []string{"20250101-120000-d4e5f6"}},
+		{name: "all", sessions: sessions, all: true, want: []string{"20250102-120000-a1b2c3", "20250101-120000-d4e5f6"}},
+		{name: "missing id", sessions: sessions, sessionID: "20250103-120000-000000", wantErr: true},
+		{name: "no sessions", sessions: nil},
+		{name: "no sessions with all", sessions: nil, all: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := selectSessions(tt.sessions, tt.sessionID, tt.all)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+
+			var ids []string
+			for _, recorded := range selected {
+				ids = append(ids, recorded.ID)
+			}
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("got sessions %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
@@ -30,7 +30,7 @@ func Execute() {
 	}
 }
 
-func createModel(debug bool) (ui.Model, *os.File) {
+func createModel(debug bool, profile string) (ui.Model, *os.File) {
 	var loggerFile *os.File
 
 	if debug {
@@ -51,7 +51,7 @@ func createModel(debug bool) (ui.Model, *os.File) {
 		log.SetLevel(log.FatalLevel)
 	}
 
-	return ui.NewModel(), loggerFile
+	return ui.NewModel(profile), loggerFile
 }
 
 func init() {
@@ -62,6 +62,12 @@ func init() {
 		"passing this flag will allow writing debug output to debug.log",
 	)
 
+	rootCmd.PersistentFlags().String(
+		"profile",
+		"",
+		"connection profile to use instead of active_profile of the config",
+	)
+
 	rootCmd.Flags().BoolP(
 		"help",
 		"h",
@@ -76,10 +82,15 @@ func init() {
 			log.Fatal("Cannot parse debug flag", err)
 		}
 
+		profile, err := rootCmd.Flags().GetString("profile")
+		if err != nil {
+			log.Fatal("Cannot parse profile flag", err)
+		}
+
 		// see https://github.com/charmbracelet/lipgloss/issues/73
 		lipgloss.SetHasDarkBackground(termenv.HasDarkBackground())
 
-		model, logger := createModel(debug)
+		model, logger := createModel(debug, profile)
 		if logger != nil {
 			defer func() {
 				if err := logger.Close(); err != nil {
@@ -103,7 +114,7 @@ func init() {
 		if m, ok := finalModel.(ui.Model); ok {
 			if hasFatal, message := m.HasFatalError(); hasFatal {
 				fmt.Fprintf(os.Stderr, "Fatal error: %s\n", message)
-				log.Debug("Fatal error: %s", message)
+				log.Debug(fmt.Sprintf("Fatal error: %s", message))
 				os.Exit(1)
 			}
 		}
@@ -0,0 +1,422 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+	"github.com/tehbooom/elastic-data/internal/config"
+	"github.com/tehbooom/elastic-data/internal/elasticsearch"
+	"github.com/tehbooom/elastic-data/internal/integrations"
+	"github.com/tehbooom/elastic-data/internal/kibana"
+	"github.com/tehbooom/elastic-data/internal/scenario"
+	"github.com/tehbooom/elastic-data/internal/session"
+	programContext "github.com/tehbooom/elastic-data/ui/context"
+	"github.com/tehbooom/elastic-data/ui/tabs/run"
+)
+
+var (
+	runCmd = &cobra.Command{
+		Use:          "run",
+		Short:        "Generate data for every enabled dataset without the TUI",
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	runCmd.Flags().Duration(
+		"duration",
+		0,
+		"stop generating after this duration, by default runs until every byte threshold is met or interrupted",
+	)
+
+	runCmd.Flags().Duration(
+		"interval",
+		10*time.Second,
+		"how often progress is reported",
+	)
+
+	runCmd.Flags().String(
+		"backfill-start",
+		"",
+		"send historical events starting at this RFC3339 time instead of generating live data",
+	)
+
+	runCmd.Flags().String(
+		"backfill-end",
+		"",
+		"RFC3339 time the backfill ends at, defaults to now",
+	)
+
+	runCmd.Flags().String(
+		"scenario",
+		"",
+		"play a scenario by name from the scenarios directory or by path alongside the enabled datasets",
+	)
+
+	runCmd.Flags().Bool(
+		"debug",
+		false,
+		"passing this flag will enable debug logging",
+	)
+
+	runCmd.RunE = func(cmd *cobra.Command, _ []string) error {
+		duration, err := cmd.Flags().GetDuration("duration")
+		if err != nil {
+			return fmt.Errorf("cannot parse duration flag: %w", err)
+		}
+
+		interval, err := cmd.Flags().GetDuration("interval")
+		if err != nil {
+			return fmt.Errorf("cannot parse interval flag: %w", err)
+		}
+
+		if interval <= 0 {
+			return fmt.Errorf("interval must be greater than 0")
+		}
+
+		backfillStart, err := cmd.Flags().GetString("backfill-start")
+		if err != nil {
+			return fmt.Errorf("cannot parse backfill-start flag: %w", err)
+		}
+
+		backfillEnd, err := cmd.Flags().GetString("backfill-end")
+		if err != nil {
+			return fmt.Errorf("cannot parse backfill-end flag: %w", err)
+		}
+
+		var backfill *run.Backfill
+		if backfillStart != "" {
+			parsed, err := run.ParseBackfill(backfillStart, backfillEnd)
+			if err != nil {
+				return err
+			}
+			backfill = &parsed
+		} else if backfillEnd != "" {
+			return fmt.Errorf("backfill-end requires backfill-start")
+		}
+
+		scenarioName, err := cmd.Flags().GetString("scenario")
+		if err != nil {
+			return fmt.Errorf("cannot parse scenario flag: %w", err)
+		}
+
+		profile, err := cmd.Flags().GetString("profile")
+		if err != nil {
+			return fmt.Errorf("cannot parse profile flag: %w", err)
+		}
+
+		debug, err := cmd.Flags().GetBool("debug")
+		if err != nil {
+			return fmt.Errorf("cannot parse debug flag: %w", err)
+		}
+
+		log.SetOutput(os.Stdout)
+		log.SetTimeFormat(time.RFC3339)
+		log.SetReportTimestamp(true)
+		log.SetLevel(log.InfoLevel)
+		if debug {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if duration > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, duration)
+			defer cancel()
+		}
+
+		return runHeadless(ctx, profile, interval, backfill, scenarioName)
+	}
+
+	rootCmd.AddCommand(runCmd)
+}
+
+// runHeadless starts a generator for every enabled dataset and reports progress
+// until every generator has finished or ctx is done. When backfill is set the
+// generators send historical events for its range. A scenario, when named, is
+// played alongside the generators. A profile, when named, is used instead of
+// the active connection profile.
+func runHeadless(ctx context.Context, profile string, interval time.Duration, backfill *run.Backfill, scenarioName string) error {
+	cfg, cfgPath, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+
+	if profile != "" {
+		if err := cfg.OverrideProfile(profile); err != nil {
+			return err
+		}
+	}
+
+	integrations.SetTemplatesDir(cfg.GetTemplatesDir(cfgPath))
+
+	// Keep the logs out of the events written to stdout
+	if usesOutput(cfg, config.OutputStdout) {
+		log.SetOutput(os.Stderr)
+	}
+
+	var playbook *scenario.Scenario
+	if scenarioName != "" {
+		playbook, err = scenario.Load(cfgPath, scenarioName)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Datasets writing to files, stdout or syslog do not need a cluster
+	needsCluster := playbook != nil || usesOutput(cfg, config.OutputElasticsearch)
+
+	var esConfig *elasticsearch.Config
+	var kbConfig *kibana.Config
+	var installed map[string]string
+	if needsCluster {
+		// In-flight bulk requests are allowed to complete after ctx is done
+		// so that stopping does not count as a failure
+		esConfig, kbConfig, err = newClients(cfg)
+		if err != nil {
+			return err
+		}
+
+		if err := esConfig.TestConnection(); err != nil {
+			return err
+		}
+
+		if err := kbConfig.TestConnection(); err != nil {
+			return err
+		}
+
+		installed, err = kbConfig.GetInstalledPackages()
+		if err != nil {
+			return err
+		}
+	}
+
+	recorder := session.NewRecorder(cfgPath, cfg.Profile())
+
+	var wg sync.WaitGroup
+	generators := make(map[string]*run.DataGenerator)
+
+	for integrationName, integration := range cfg.Integrations {
+		if !integration.Enabled {
+			continue
+		}
+
+		for datasetName, dataset := range integration.Datasets {
+			if !dataset.Enabled {
+				continue
+			}
+
+			if needsCluster && kibana.NeedsInstall(installed, integrationName, integration.Version) {
+				log.Info("Installing package", "integration", integrationName, "version", integration.Version)
+				if err := kbConfig.InstallPackage(integrationName, integration.Version); err != nil {
+					return err
+				}
+				installed[integrationName] = integration.Version
+			}
+
+			stats := &run.IntegrationStats{
+				Unit:  dataset.Unit,
+				Trend: "neutral",
+			}
+
+			datasetConfig := programContext.NewDatasetConfig(datasetName, dataset)
+			if needsCluster {
+				datasetConfig = run.ResolveDataStreamType(kbConfig, integrationName, integration.Version, datasetConfig)
+			}
+			generator, err := run.NewDataGenerator(ctx, integrationName, datasetConfig, cfg, esConfig, stats, &wg)
+			if err != nil {
+				return err
+			}
+
+			if backfill != nil {
+				generator.SetBackfill(*backfill)
+			}
+			generator.SetSession(recorder)
+
+			generators[fmt.Sprintf("%s:%s", integrationName, datasetName)] = generator
+		}
+	}
+
+	if len(generators) == 0 && playbook == nil {
+		return fmt.Errorf("no enabled datasets found in config")
+	}
+
+	for name, generator := range generators {
+		log.Info("Starting generation", "dataset", name)
+		generator.Start()
+	}
+
+	var scenarioErr error
+	var scenarioResults []scenario.StepResult
+	if playbook != nil {
+		runner := &scenario.Runner{
+			Config: cfg,
+			ES:     esConfig,
+			KB:     kbConfig,
+			OnStep: func(index int, step scenario.Step) {
+				log.Info("Scenario step", "scenario", playbook.Name, "step", index+1, "name", step.Name)
+			},
+			Session: recorder,
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			scenarioResults, scenarioErr = runner.Run(ctx, playbook)
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// quarantined templates already logged, keyed by dataset and template
+	quarantined := make(map[string]bool)
+
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		case <-ctx.Done():
+			log.Info("Stopping generation")
+			<-done
+			running = false
+		case <-ticker.C:
+			reportProgress(generators, quarantined)
+		}
+	}
+
+	bulkErrors, failedEvents := reportProgress(generators, quarantined)
+
+	for _, result := range scenarioResults {
+		log.Info("Scenario", "step", result.Step, "sent", result.Sent, "indexed", result.Indexed, "failed", result.Failed)
+		failedEvents += result.Failed
+	}
+
+	if scenarioErr != nil && ctx.Err() == nil {
+		return scenarioErr
+	}
+
+	if bulkErrors > 0 || failedEvents > 0 {
+		return fmt.Errorf("%d bulk requests failed and %d events were not indexed", bulkErrors, failedEvents)
+	}
+
+	return nil
+}
+
+// newClients returns the Elasticsearch and Kibana clients of the active connection profile
+func newClients(cfg *config.Config) (*elasticsearch.Config, *kibana.Config, error) {
+	connection, err := cfg.ActiveConnection()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	esClient, err := elasticsearch.SetClient(connection)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error setting up Elasticsearch client: %w", err)
+	}
+
+	kbClient, err := kibana.SetClient(connection)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error setting up Kibana client: %w", err)
+	}
+
+	esConfig := &elasticsearch.Config{
+		Client: esClient,
+		Ctx:    context.Background(),
+		Retry:  cfg.Retry,
+	}
+	kbConfig := &kibana.Config{
+		Client: kbClient,
+		Ctx:    context.Background(),
+	}
+
+	return esConfig, kbConfig, nil
+}
+
+// usesOutput reports whether an enabled dataset writes to the output type
+func usesOutput(cfg *config.Config, outputType string) bool {
+	for _, integration := range cfg.Integrations {
+		if !integration.Enabled {
+			continue
+		}
+		for _, dataset := range integration.Datasets {
+			if dataset.Enabled && dataset.Output.WithDefaults().Type == outputType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// reportProgress logs the stats of every generator and returns the total number
+// of failed bulk requests and events that were not indexed. Templates are only
+// logged the first time they are quarantined, quarantined keeps track of them.
+func reportProgress(generators map[string]*run.DataGenerator, quarantined map[string]bool) (int, int) {
+	var names []string
+	for name := range generators {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var bulkErrors, failedEvents int
+	for _, name := range names {
+		stats := generators[name].Snapshot()
+		bulkErrors += stats.BulkErrors
+		failedEvents += stats.FailedEvents
+
+		keyvals := []interface{}{
+			"dataset", name,
+			"sent", stats.SentEvents,
+			"indexed", stats.IndexedEvents,
+			"failed", stats.FailedEvents,
+			"latency_ms", stats.Current,
+			"bulk_errors", stats.BulkErrors,
+		}
+		if stats.Unit == "eps" {
+			keyvals = append(keyvals,
+				"target_eps", fmt.Sprintf("%.1f", stats.TargetRate),
+				"achieved_eps", fmt.Sprintf("%.1f", stats.AchievedRate),
+				"lag", stats.Lag.Round(100*time.Millisecond),
+				"skipped", stats.SkippedEvents,
+			)
+		} else {
+			keyvals = append(keyvals, "bytes", fmt.Sprintf("%.1f%s", stats.SentBytes, stats.SentBytesUnit))
+		}
+		log.Info("Progress", keyvals...)
+
+		for errorType, count := range stats.FailureReasons {
+			log.Warn("Failed events", "dataset", name, "type", errorType, "count", count)
+		}
+
+		health := generators[name].Health()
+		for kind, count := range health.Failures {
+			log.Warn("Template failures", "dataset", name, "kind", kind, "count", count)
+		}
+		for template, reason := range health.Quarantined {
+			key := name + "/" + template
+			if quarantined[key] {
+				continue
+			}
+			quarantined[key] = true
+			log.Warn("Quarantined template", "dataset", name, "template", template, "error", reason)
+		}
+	}
+
+	return bulkErrors, failedEvents
+}
@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/spf13/cobra"
+	"github.com/tehbooom/elastic-data/internal/config"
+	"github.com/tehbooom/elastic-data/internal/integrations"
+	"github.com/tehbooom/elastic-data/internal/pipeline"
+)
+
+var (
+	validateCmd = &cobra.Command{
+		Use:          "validate [integration[:dataset]]...",
+		Short:        "Run sample events of datasets through the ingest pipeline of the installed package without indexing them",
+		Long:         "Run sample events of datasets through the ingest pipeline of the installed package without indexing them.\nWithout arguments every enabled dataset is validated, an integration without a dataset validates its enabled datasets.",
+		SilenceUsage: true,
+	}
+)
+
+func init() {
+	validateCmd.Flags().Int(
+		"samples",
+		1,
+		"number of events rendered from every template",
+	)
+
+	validateCmd.Flags().Bool(
+		"failed-only",
+		false,
+		"only report templates that failed",
+	)
+
+	validateCmd.Flags().Bool(
+		"debug",
+		false,
+		"passing this flag will enable debug logging",
+	)
+
+	validateCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		samples, err := cmd.Flags().GetInt("samples")
+		if err != nil {
+			return fmt.Errorf("cannot parse samples flag: %w", err)
+		}
+
+		if samples <= 0 {
+			return fmt.Errorf("samples must be greater than 0")
+		}
+
+		failedOnly, err := cmd.Flags().GetBool("failed-only")
+		if err != nil {
+			return fmt.Errorf("cannot parse failed-only flag: %w", err)
+		}
+
+		profile, err := cmd.Flags().GetString("profile")
+		if err != nil {
+			return fmt.Errorf("cannot parse profile flag: %w", err)
+		}
+
+		debug, err := cmd.Flags().GetBool("debug")
+		if err != nil {
+			return fmt.Errorf("cannot parse debug flag: %w", err)
+		}
+
+		log.SetOutput(os.Stdout)
+		log.SetTimeFormat(time.RFC3339)
+		log.SetReportTimestamp(true)
+		log.SetLevel(log.InfoLevel)
+		if debug {
+			log.SetLevel(log.DebugLevel)
+		}
+
+		return runValidate(args, profile, samples, failedOnly)
+	}
+
+	rootCmd.AddCommand(validateCmd)
+}
+
+// runValidate validates the datasets named by args and logs the result of every template
+func runValidate(args []string, profile string, samples int, failedOnly bool) error {
+	cfg, cfgPath, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("error loading config: %w", err)
+	}
+
+	if profile != "" {
+		if err := cfg.OverrideProfile(profile); err != nil {
+			return err
+		}
+	}
+
+	integrations.SetTemplatesDir(cfg.GetTemplatesDir(cfgPath))
+
+	datasets, err := validateTargets(cfg, args)
+	if err != nil {
+		return err
+	}
+
+	if len(datasets) == 0 {
+		return fmt.Errorf("no enabled datasets found in config")
+	}
+
+	esConfig, kbConfig, err := newClients(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := esConfig.TestConnection(); err != nil {
+		return err
+	}
+
+	if err := kbConfig.TestConnection(); err != nil {
+		return err
+	}
+
+	validator := &pipeline.Validator{
+		Config:  cfg,
+		ES:      esConfig,
+		KB:      kbConfig,
+		Samples: samples,
+	}
+
+	var failed int
+	for _, name := range datasets {
+		integration, dataset, _ := strings.Cut(name, ":")
+
+		report, err := validator.Validate(integration, dataset)
+		if err != nil {
+			return fmt.Errorf("failed to validate %s: %w", name, err)
+		}
+
+		for _, result := range report.Results {
+			if result.Error != "" {
+				log.Warn("Failed", "template", result.Template, "error", result.Error)
+			} else if !failedOnly {
+				log.Info("Passed", "template", result.Template)
+			}
+		}
+
+		log.Info("Validated", "dataset", name, "pipeline", report.Pipeline, "templates", len(report.Results), "failed", report.Failed())
+		failed += report.Failed()
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d templates failed, add their names to exclude_templates of the dataset to skip them", failed)
+	}
+
+	return nil
+}
+
+// validateTargets returns the integration:dataset names to validate. Without arguments
+// every enabled dataset of the config is returned, an integration without a dataset
+// expands to its enabled datasets.
+func validateTargets(cfg *config.Config, args []string) ([]string, error) {
+	if len(args) == 0 {
+		var datasets []string
+		for integrationName := range cfg.Integrations {
+			datasets = append(datasets, enabledDatasets(cfg, integrationName)...)
+		}
+		slices.Sort(datasets)
+		return datasets, nil
+	}
+
+	var datasets []string
+	for _, arg := range args {
+		integration, dataset, hasDataset := strings.Cut(arg, ":")
+
+		names, err := integrations.GetDatasetsFromTemplates(integration)
+		if err != nil {
+			log.Debug(err)
+			return nil, fmt.Errorf("unknown integration %s", integration)
+		}
+
+		if hasDataset {
+			if !slices.Contains(names, dataset) {
+				return nil, fmt.Errorf("unknown dataset %s, %s has the datasets %s", arg, integration, strings.Join(names, ", "))
+			}
+			datasets = append(datasets, arg)
+			continue
+		}
+
+		enabled := enabledDatasets(cfg, integration)
+		if len(enabled) == 0 {
+			return nil, fmt.Errorf("%s has no enabled datasets, name one of %s as %s:<dataset>", integration, strings.Join(names, ", "), integration)
+		}
+		datasets = append(datasets, enabled...)
+	}
+
+	return datasets, nil
+}
+
+// enabledDatasets returns the integration:dataset names of the enabled datasets of an
+// enabled integration
+func enabledDatasets(cfg *config.Config, integrationName string) []string {
+	integration, exists := cfg.Integrations[integrationName]
+	if !exists || !integration.Enabled {
+		return nil
+	}
+
+	var datasets []string
+	for datasetName, dataset := range integration.Datasets {
+		if dataset.Enabled {
+			datasets = append(datasets, fmt.Sprintf("%s:%s", integrationName, datasetName))
+		}
+	}
+	slices.Sort(datasets)
+
+	return datasets
+}
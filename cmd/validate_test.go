@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/tehbooom/elastic-data/internal/config"
+)
+
+func TestValidateTargets(t *testing.T) {
+	cfg := &config.Config{
+		Integrations: map[string]config.Integration{
+			"nginx": {
+				Enabled: true,
+				Datasets: map[string]config.Dataset{
+					"access": {Enabled: true},
+					"error":  {Enabled: false},
+				},
+			},
+			"system": {
+				Enabled: true,
+				Datasets: map[string]config.Dataset{
+					"auth":   {Enabled: true},
+					"syslog": {Enabled: true},
+				},
+			},
+			"apache": {
+				Enabled: false,
+				Datasets: map[string]config.Dataset{
+					"access": {Enabled: true},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want []string
+		err  string
+	}{
+		{name: "enabled datasets", want: []string{"nginx:access", "system:auth", "system:syslog"}},
+		{name: "integration", args: []string{"system"}, want: []string{"system:auth", "system:syslog"}},
+		{name: "disabled datasets are skipped", args: []string{"nginx"}, want: []string{"nginx:access"}},
+		{name: "dataset", args: []string{"nginx:error", "system:auth"}, want: []string{"nginx:error", "system:auth"}},
+		{name: "unknown integration", args: []string{"nope"}, err: "unknown integration nope"},
+		{name: "unknown dataset", args: []string{"nginx:nope"}, err: "unknown dataset nginx:nope, nginx has the datasets access, error"},
+		{name: "disabled integration", args: []string{"apache"}, err: "apache has no enabled datasets"},
+		{name: "unconfigured integration", args: []string{"zeek"}, err: "zeek has no enabled datasets"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateTargets(cfg, tt.args)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("validateTargets() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("validateTargets() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,10 +3,13 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"io/fs"
 	"log"
+	"math"
+	"net"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -14,9 +17,30 @@ import (
 	"text/template"
 
 	"github.com/elastic/beats/v7/libbeat/reader/multiline"
+	"github.com/tehbooom/elastic-data/internal/common"
+	"github.com/tehbooom/elastic-data/internal/generator"
+)
+
+var (
+	userAgentRegex  = regexp.MustCompile(`Mozilla/\d\.\d \([^)]*\)[^"\\]*`)
+	uuidRegex       = regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`)
+	bracedUUIDRegex = regexp.MustCompile(`\{[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\}`)
+	macRegex        = regexp.MustCompile(`\b[0-9a-fA-F]{2}(?::[0-9a-fA-F]{2}){5}\b|\b[0-9a-fA-F]{2}(?:-[0-9a-fA-F]{2}){5}\b`)
+	// ipv6Regex matches whole runs of hex digits and colons, checked by replaceIPv6
+	ipv6Regex = regexp.MustCompile(`[0-9a-fA-F:]*[0-9a-fA-F]:[0-9a-fA-F:]*|[0-9a-fA-F:]*:[0-9a-fA-F][0-9a-fA-F:]*`)
 )
 
 func main() {
+	rewrite := flag.Bool("rewrite", false, "apply the generator function rules to the existing templates")
+	flag.Parse()
+
+	if *rewrite {
+		if err := rewriteTemplates("../internal/integrations/templates"); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	validIntegrations, allIntegrations, err := getIntegrations()
 	if err != nil {
 		log.Fatal(err)
@@ -347,6 +371,10 @@ func processLogFileForTemplates(filePath string, multilineConfig *multiline.Conf
 func processLogLineForTemplate(line []byte) string {
 	original := string(line)
 
+	// Values replaced with a generator function run before the variables so that
+	// the variable patterns do not match inside them
+	templateStr := replaceFunctionValues(original)
+
 	// Apply common pattern replacements
 	patterns := []struct {
 		name  string
@@ -361,18 +389,26 @@ func processLogLineForTemplate(line []byte) string {
 		{"timestamp_snort_no_year", regexp.MustCompile(`\d{2}/\d{2}-\d{2}:\d{2}:\d{2}\.\d{6}`)},
 		{"timestamp_unix_s", regexp.MustCompile(`\b1[0-9]{9}\b`)},
 		{"timestamp_unix_ms", regexp.MustCompile(`\b1[0-9]{12}\b`)},
+		{"Users", common.UserFieldRegex},
+		{"Users", common.NestedUserFieldRegex},
+		{"Hosts", common.HostFieldRegex},
+		{"Hosts", common.NestedHostFieldRegex},
+		{"Hosts", common.SyslogHostRegex},
+		{"Hosts", common.Syslog5424HostRegex},
 		{"IPs", regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)},
 		{"Emails", regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`)},
 		{"Domains", regexp.MustCompile(`[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`)},
 	}
 
-	templateStr := original
 	for _, p := range patterns {
-		templateStr = p.regex.ReplaceAllString(templateStr, fmt.Sprintf("{{.%s}}", p.name))
+		placeholder := fmt.Sprintf("{{.%s}}", p.name)
+		templateStr = common.ReplaceValues(p.regex, templateStr, func(string) string {
+			return placeholder
+		})
 	}
 
 	// Validate template
-	_, err := template.New("test").Parse(templateStr)
+	_, err := template.New("test").Funcs(generator.Funcs()).Parse(templateStr)
 	if err != nil {
 		log.Printf("        Warning: invalid template created from line: %s", original[:min(len(original), 100)])
 		return original // Return original if template is invalid
@@ -384,14 +420,15 @@ func processLogLineForTemplate(line []byte) string {
 func processMapForTemplating(data map[string]interface{}) {
 	// Create a value tracker for this processing session
 	valueTracker := make(map[string]map[string]int)
-	processMapForTemplatingWithTracker(data, valueTracker)
+	processMapForTemplatingWithTracker(data, "", valueTracker)
 }
 
-func processMapForTemplatingWithTracker(data map[string]interface{}, valueTracker map[string]map[string]int) {
+func processMapForTemplatingWithTracker(data map[string]interface{}, parent string, valueTracker map[string]map[string]int) {
 	for key, value := range data {
+		field := joinField(parent, key)
 		switch v := value.(type) {
 		case string:
-			placeholder := convertStringValueToTemplateWithTracker(key, v, valueTracker)
+			placeholder := convertStringValueToTemplateWithTracker(field, v, valueTracker)
 			data[key] = placeholder
 		case float64:
 			placeholder := convertNumericValueToTemplateWithTracker(key, v, valueTracker)
@@ -404,23 +441,23 @@ func processMapForTemplatingWithTracker(data map[string]interface{}, valueTracke
 				data[key] = placeholder
 			}
 		case map[string]interface{}:
-			processMapForTemplatingWithTracker(v, valueTracker)
+			processMapForTemplatingWithTracker(v, field, valueTracker)
 		case []interface{}:
-			processSliceForTemplatingWithTracker(key, v, valueTracker)
+			processSliceForTemplatingWithTracker(field, v, valueTracker)
 		}
 	}
 }
 
-func processSliceForTemplatingWithTracker(key string, slice []interface{}, valueTracker map[string]map[string]int) {
+func processSliceForTemplatingWithTracker(field string, slice []interface{}, valueTracker map[string]map[string]int) {
 	for i, value := range slice {
 		switch v := value.(type) {
 		case string:
-			placeholder := convertStringValueToTemplateWithTracker(key, v, valueTracker)
+			placeholder := convertStringValueToTemplateWithTracker(field, v, valueTracker)
 			slice[i] = placeholder
 		case map[string]interface{}:
-			processMapForTemplatingWithTracker(v, valueTracker)
+			processMapForTemplatingWithTracker(v, field, valueTracker)
 		case []interface{}:
-			processSliceForTemplatingWithTracker(key, v, valueTracker)
+			processSliceForTemplatingWithTracker(field, v, valueTracker)
 		}
 	}
 }
@@ -428,6 +465,16 @@ func processSliceForTemplatingWithTracker(key string, slice []interface{}, value
 func convertNumericValueToTemplateWithTracker(k string, value float64, valueTracker map[string]map[string]int) string {
 	key := strings.ToLower(k)
 
+	switch {
+	case isPortKey(key) && value > 0 && value <= 65535:
+		return "{{port}}"
+	case strings.Contains(key, "status_code") && value >= 100 && value < 600:
+		return "{{http_status}}"
+	case strings.Contains(key, "bytes") && !strings.Contains(key, "ratio") && value >= 1:
+		// Vary the size around the original value
+		return fmt.Sprintf("{{int_range %.0f %.0f}}", math.Max(1, math.Floor(value/2)), value*2)
+	}
+
 	if strings.Contains(key, "time") || strings.Contains(key, "timestamp") {
 		valueStr := fmt.Sprintf("%.0f", value)
 
@@ -441,7 +488,19 @@ func convertNumericValueToTemplateWithTracker(k string, value float64, valueTrac
 	return ""
 }
 
-func convertStringValueToTemplateWithTracker(k, value string, valueTracker map[string]map[string]int) string {
+// isPortKey reports whether the lowercase key names a port, unlike words such as report
+func isPortKey(key string) bool {
+	for _, word := range []string{"report", "support", "transport", "export", "import", "passport", "airport"} {
+		if strings.HasSuffix(key, word) {
+			return false
+		}
+	}
+	return strings.HasSuffix(key, "port")
+}
+
+// convertStringValueToTemplateWithTracker replaces value with a template variable or function
+// based on its content and the dotted path of its field
+func convertStringValueToTemplateWithTracker(field, value string, valueTracker map[string]map[string]int) string {
 	value = strings.TrimSpace(value)
 
 	// Check if this is a JSON string - if so, parse and template it recursively
@@ -449,7 +508,7 @@ func convertStringValueToTemplateWithTracker(k, value string, valueTracker map[s
 		var innerJSON map[string]interface{}
 		if err := json.Unmarshal([]byte(value), &innerJSON); err == nil {
 			// This is valid JSON, process it recursively with the same tracker
-			processMapForTemplatingWithTracker(innerJSON, valueTracker)
+			processMapForTemplatingWithTracker(innerJSON, "", valueTracker)
 			// Convert back to JSON string
 			if templatedJSON, err := json.Marshal(innerJSON); err == nil {
 				return string(templatedJSON)
@@ -457,6 +516,26 @@ func convertStringValueToTemplateWithTracker(k, value string, valueTracker map[s
 		}
 	}
 
+	if strings.HasPrefix(value, "Mozilla/") || strings.Contains(strings.ToLower(field), "user_agent") {
+		return "{{user_agent}}"
+	}
+
+	if uuidRegex.MatchString(value) && len(uuidRegex.FindString(value)) == len(value) {
+		return "{{uuid}}"
+	}
+
+	if macRegex.MatchString(value) && len(macRegex.FindString(value)) == len(value) {
+		return macPlaceholder(value)
+	}
+
+	if ip := net.ParseIP(value); ip != nil && ip.To4() == nil {
+		return "{{ipv6}}"
+	}
+
+	if variable := fieldVariable(field); variable != "" && value != "" && value != "-" {
+		return getUniqueVariableName(variable, value, valueTracker)
+	}
+
 	// Apply pattern matching for non-JSON strings
 	if regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`).MatchString(value) {
 		return getUniqueVariableName("Emails", value, valueTracker)
@@ -506,15 +585,134 @@ func convertStringValueToTemplateWithTracker(k, value string, valueTracker map[s
 		return getUniqueVariableName("timestamp_unix_s", value, valueTracker)
 	}
 
-	key := strings.ToLower(k)
+	return replaceFunctionValues(value)
+}
+
+// replaceFunctionValues replaces the values of s that have a generator function with it
+func replaceFunctionValues(s string) string {
+	s = userAgentRegex.ReplaceAllString(s, "{{user_agent}}")
+	// A brace before the action would be read as part of its delimiter
+	s = bracedUUIDRegex.ReplaceAllString(s, "{{printf `{%s}` uuid}}")
+	s = uuidRegex.ReplaceAllString(s, "{{uuid}}")
+	s = macRegex.ReplaceAllStringFunc(s, macPlaceholder)
+	return replaceIPv6(s)
+}
+
+// replaceIPv6 replaces the IPv6 addresses of s with the ipv6 function. A run of hex digits
+// and colons is only replaced when it is a whole address, or an address followed by a
+// decimal port which is kept. Compressed addresses followed by a port of up to four digits
+// are valid addresses themselves and are replaced whole.
+func replaceIPv6(s string) string {
+	var result strings.Builder
+	last := 0
+	for _, match := range ipv6Regex.FindAllStringIndex(s, -1) {
+		start, end := match[0], match[1]
+		// A single colon separates the address from a label, as in Remote:2001:db8::1
+		if start > 0 && isWordByte(s[start-1]) {
+			start += strings.Index(s[start:end], ":")
+		}
+		if s[start] == ':' && start+1 < end && s[start+1] != ':' {
+			start++
+		}
+		if start > 0 && isAddressNeighbour(s[start-1]) || end < len(s) && isAddressNeighbour(s[end]) {
+			continue
+		}
+		// The run is part of a dotted value such as ::ffff:10.0.0.1 or a version
+		if start > 0 && s[start-1] == '.' || end+1 < len(s) && s[end] == '.' && isDecimal(s[end+1:end+2]) {
+			continue
+		}
+
+		run := s[start:end]
+		replacement := ""
+		if isIPv6(run) {
+			replacement = "{{ipv6}}"
+		} else if i := strings.LastIndex(run, ":"); i > 0 && isIPv6(run[:i]) && isDecimal(run[i+1:]) {
+			replacement = "{{ipv6}}" + run[i:]
+		} else {
+			continue
+		}
 
-	if strings.Contains(key, "username") || strings.Contains(key, "user") {
-		return getUniqueVariableName("Users", value, valueTracker)
-	} else if strings.Contains(key, "hostname") || strings.Contains(key, "host") {
-		return getUniqueVariableName("Hosts", value, valueTracker)
+		result.WriteString(s[last:start])
+		result.WriteString(replacement)
+		last = end
 	}
 
-	return value
+	if last == 0 {
+		return s
+	}
+	result.WriteString(s[last:])
+	return result.String()
+}
+
+// isIPv6 reports whether s is an IPv6 address written with colons
+func isIPv6(s string) bool {
+	return strings.Contains(s, ":") && net.ParseIP(s) != nil
+}
+
+func isDecimal(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isAddressNeighbour reports whether b continues a word or a template action next to a run
+func isAddressNeighbour(b byte) bool {
+	return isWordByte(b) || b == '{' || b == '}'
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+// fieldVariable returns Users or Hosts when field holds a username or hostname
+func fieldVariable(field string) string {
+	field = strings.ToLower(field)
+
+	matches := func(names, compoundNames []string) bool {
+		for _, name := range names {
+			if field == name || strings.HasSuffix(field, "."+name) {
+				return true
+			}
+		}
+		for _, name := range compoundNames {
+			if strings.HasSuffix(field, name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case matches([]string{"user.name", "user", "users"}, []string{"username", "user_name"}):
+		return "Users"
+	case matches([]string{"host.name", "host", "hosts"}, []string{"hostname", "host_name", "computername", "computer_name"}):
+		return "Hosts"
+	}
+
+	return ""
+}
+
+// joinField returns the dotted path of key inside parent
+func joinField(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+// macPlaceholder returns the mac function keeping the separator of the original address.
+// A raw string is used for the separator since quotes would be escaped in JSON events.
+func macPlaceholder(value string) string {
+	if strings.Contains(value, ":") {
+		return "{{mac `:`}}"
+	}
+	return "{{mac}}"
 }
 
 // getUniqueVariableName tracks unique values for each variable type and returns numbered variants
@@ -555,12 +753,9 @@ func writeTemplateFile(filePath string, events []string) error {
 		}
 	}()
 
-	// Use a special delimiter between events that won't conflict with log content
-	delimiter := "\n---EVENT_DELIMITER---\n"
-
 	for i, event := range events {
 		if i > 0 {
-			if _, err := file.WriteString(delimiter); err != nil {
+			if _, err := file.WriteString(eventDelimiter); err != nil {
 				return err
 			}
 		}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestProcessLogLineForTemplate(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{
+			name: "uuid",
+			line: "request 7fcf1548-d2d4-41cd-a9a8-6ae47c51f765 done",
+			want: "request {{uuid}} done",
+		},
+		{
+			name: "braced uuid",
+			line: "key {AFDE1595-0AEA-6E48-B48E-A69F8263607D} changed",
+			want: "key {{printf `{%s}` uuid}} changed",
+		},
+		{
+			name: "mac with colons",
+			line: "client 00:1a:2b:3c:4d:5e joined",
+			want: "client {{mac `:`}} joined",
+		},
+		{
+			name: "mac with dashes",
+			line: "client 00-1A-2B-3C-4D-5E joined",
+			want: "client {{mac}} joined",
+		},
+		{
+			name: "full ipv6",
+			line: "from 2001:0db8:85a3:0000:0000:8a2e:0370:7334 port",
+			want: "from {{ipv6}} port",
+		},
+		{
+			name: "compressed ipv6",
+			line: "from fe80::1ff:fe23:4567:890a port",
+			want: "from {{ipv6}} port",
+		},
+		{
+			name: "user agent",
+			line: `GET / "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 Chrome/81.0 Safari/537.36" 200`,
+			want: `GET / "{{user_agent}}" 200`,
+		},
+		{
+			name: "user agent in escaped JSON",
+			line: `{"event":"{\"agent\":\"Mozilla/5.0 (X11; Linux x86_64) Firefox/125.0\"}"}`,
+			want: `{"event":"{\"agent\":\"{{user_agent}}\"}"}`,
+		},
+		{
+			name: "user agent before variables",
+			line: `10.0.0.1 "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Gecko/20100101 Firefox/125.0"`,
+			want: `{{.IPs}} "{{user_agent}}"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := processLogLineForTemplate([]byte(tt.line)); got != tt.want {
+				t.Errorf("processLogLineForTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplaceFunctionValues(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"uuid", "7fcf1548-d2d4-41cd-a9a8-6ae47c51f765", "{{uuid}}"},
+		{"uppercase uuid", "AFDE1595-0AEA-6E48-B48E-A69F8263607D", "{{uuid}}"},
+		{"uuid in a word", "x7fcf1548-d2d4-41cd-a9a8-6ae47c51f765", "x7fcf1548-d2d4-41cd-a9a8-6ae47c51f765"},
+		{"short uuid", "7fcf1548-d2d4-41cd-a9a8-6ae47c51f76", "7fcf1548-d2d4-41cd-a9a8-6ae47c51f76"},
+		{"mac with colons", "00:1a:2b:3c:4d:5e", "{{mac `:`}}"},
+		{"mac with dashes", "00-1A-2B-3C-4D-5E", "{{mac}}"},
+		{"too few mac octets", "00:1a:2b:3c:4d", "00:1a:2b:3c:4d"},
+		{"full ipv6", "2001:0db8:85a3:0000:0000:8a2e:0370:7334", "{{ipv6}}"},
+		{"compressed ipv6", "fe80::1ff:fe23:4567:890a", "{{ipv6}}"},
+		{"trailing compression", "ipv6s 2a02:cf40:: up", "ipv6s {{ipv6}} up"},
+		{"loopback", "from ::1 to", "from {{ipv6}} to"},
+		{"zone", "fe80::1%eth0", "{{ipv6}}%eth0"},
+		{"brackets", "[2001:db8::1]:443", "[{{ipv6}}]:443"},
+		{"port after full ipv6", "localhost/0:0:0:0:0:0:0:1:2181", "localhost/{{ipv6}}:2181"},
+		{"port after compressed ipv6", "2001:db8::1:50329", "{{ipv6}}:50329"},
+		{"label", "Local:fe80::1 Remote:2001:db8::1:50329", "Local:{{ipv6}} Remote:{{ipv6}}:50329"},
+		{"cidr", "2001:db8::/32", "{{ipv6}}/32"},
+		{"ipv4 mapped", "::ffff:10.0.0.1", "::ffff:10.0.0.1"},
+		{"version", "firefox:121.0.1::x64", "firefox:121.0.1::x64"},
+		{"time", "12:34:56", "12:34:56"},
+		{"scope operator", "std::string", "std::string"},
+		{"separator", "a :: b", "a :: b"},
+		{"too many groups", "1:2:3:4:5:6:7:8:9:a", "1:2:3:4:5:6:7:8:9:a"},
+		{"template action", "fe80::{{.IPs}}", "fe80::{{.IPs}}"},
+		{"user agent", `"Mozilla/5.0 (X11; Linux x86_64) Firefox/125.0"`, `"{{user_agent}}"`},
+		{"user agent without platform", "Mozilla/5.0 Firefox/125.0", "Mozilla/5.0 Firefox/125.0"},
+		{"other agents are kept", "curl/8.5.0", "curl/8.5.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := replaceFunctionValues(tt.value); got != tt.want {
+				t.Errorf("replaceFunctionValues(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertStringValueToTemplate(t *testing.T) {
+	tests := []struct {
+		field string
+		value string
+		want  string
+	}{
+		{"event.id", "7fcf1548-d2d4-41cd-a9a8-6ae47c51f765", "{{uuid}}"},
+		{"message", "session 7fcf1548-d2d4-41cd-a9a8-6ae47c51f765 closed", "session {{uuid}} closed"},
+		{"source.mac", "00-1A-2B-3C-4D-5E", "{{mac}}"},
+		{"source.mac", "00:1a:2b:3c:4d:5e", "{{mac `:`}}"},
+		{"source.ip", "2001:db8::1", "{{ipv6}}"},
+		{"source.ip", "10.0.0.1", "{{.IPs}}"},
+		{"user_agent.original", "curl/8.5.0", "{{user_agent}}"},
+		{"http.agent", "Mozilla/5.0 (X11; Linux x86_64) Firefox/125.0", "{{user_agent}}"},
+		{"user.name", "alice", "{{.Users}}"},
+		{"event.action", "login", "login"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field+"="+tt.value, func(t *testing.T) {
+			got := convertStringValueToTemplateWithTracker(tt.field, tt.value, make(map[string]map[string]int))
+			if got != tt.want {
+				t.Errorf("convertStringValueToTemplateWithTracker() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertNumericValueToTemplate(t *testing.T) {
+	tests := []struct {
+		key   string
+		value float64
+		want  string
+	}{
+		{"port", 443, "{{port}}"},
+		{"SourcePort", 51234, "{{port}}"},
+		{"destination_transport_port", 53, "{{port}}"},
+		{"port", 0, ""},
+		{"port", 70000, ""},
+		{"transport", 6, ""},
+		{"MatchCountSinceLastReport", 3, ""},
+		{"status_code", 404, "{{http_status}}"},
+		{"status_code", 0, ""},
+		{"bytes", 1000, "{{int_range 500 2000}}"},
+		{"BytesSent", 1, "{{int_range 1 2}}"},
+		{"bytes", 0, ""},
+		{"CacheHitBytesRatio", 50, ""},
+		{"timestamp", 1700000000, "{{.timestamp_unix_s}}"},
+		{"time", 1700000000000, "{{.timestamp_unix_ms}}"},
+		{"count", 42, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got := convertNumericValueToTemplateWithTracker(tt.key, tt.value, make(map[string]map[string]int))
+			if got != tt.want {
+				t.Errorf("convertNumericValueToTemplateWithTracker(%q, %v) = %q, want %q", tt.key, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessMapForTemplating(t *testing.T) {
+	event := map[string]interface{}{
+		"source": map[string]interface{}{
+			"port": float64(443),
+			"mac":  "00-1A-2B-3C-4D-5E",
+		},
+		"http": map[string]interface{}{
+			"response": map[string]interface{}{"status_code": float64(200)},
+		},
+		"ids": []interface{}{"7fcf1548-d2d4-41cd-a9a8-6ae47c51f765"},
+	}
+
+	processMapForTemplating(event)
+
+	if got := event["source"].(map[string]interface{})["port"]; got != "{{port}}" {
+		t.Errorf("source.port = %v, want {{port}}", got)
+	}
+	if got := event["source"].(map[string]interface{})["mac"]; got != "{{mac}}" {
+		t.Errorf("source.mac = %v, want {{mac}}", got)
+	}
+	if got := event["http"].(map[string]interface{})["response"].(map[string]interface{})["status_code"]; got != "{{http_status}}" {
+		t.Errorf("http.response.status_code = %v, want {{http_status}}", got)
+	}
+	if got := event["ids"].([]interface{})[0]; got != "{{uuid}}" {
+		t.Errorf("ids = %v, want {{uuid}}", got)
+	}
+}
+
+func TestRewriteEvent(t *testing.T) {
+	tests := []struct {
+		name  string
+		event string
+		want  string
+	}{
+		{
+			name:  "JSON event",
+			event: `{"id":"7fcf1548-d2d4-41cd-a9a8-6ae47c51f765","source":{"port":443,"bytes":100},"user_agent":{"original":"curl/8.5.0"},"ts":{{.timestamp_unix_s}}}`,
+			want:  `{"id":"{{uuid}}","source":{"port":{{port}},"bytes":{{int_range 50 200}}},"user_agent":{"original":"{{user_agent}}"},"ts":{{.timestamp_unix_s}}}`,
+		},
+		{
+			name:  "numeric members",
+			event: `{"destination":{"port":53,"bytes":4000},"http":{"response":{"status_code":503}},"count":7}`,
+			want:  `{"destination":{"port":{{port}},"bytes":{{int_range 2000 8000}}},"http":{"response":{"status_code":{{http_status}}}},"count":7}`,
+		},
+		{
+			name:  "ipv6 with port",
+			event: `{"message":"connected to localhost/0:0:0:0:0:0:0:1:2181"}`,
+			want:  `{"message":"connected to localhost/{{ipv6}}:2181"}`,
+		},
+		{
+			name:  "rewritten ipv6 is kept",
+			event: `{"message":"connected to localhost/{{ipv6}}:2181"}`,
+			want:  `{"message":"connected to localhost/{{ipv6}}:2181"}`,
+		},
+		{
+			name:  "user agent member",
+			event: `{"http_user_agent":"curl/8.5.0","host":"{{.Hosts}}"}`,
+			want:  `{"http_user_agent":"{{user_agent}}","host":"{{.Hosts}}"}`,
+		},
+		{
+			name:  "numbers in plain text events are kept",
+			event: `{{.IPs}} port:443 7fcf1548-d2d4-41cd-a9a8-6ae47c51f765`,
+			want:  `{{.IPs}} port:443 {{uuid}}`,
+		},
+		{
+			name:  "invalid templates are kept",
+			event: `{"id":"7fcf1548-d2d4-41cd-a9a8-6ae47c51f765","text":"{{vmId}}"}`,
+			want:  `{"id":"7fcf1548-d2d4-41cd-a9a8-6ae47c51f765","text":"{{vmId}}"}`,
+		},
+		{
+			name:  "unchanged",
+			event: `{"message":"{{.Users}} logged in"}`,
+			want:  `{"message":"{{.Users}} logged in"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rewriteEvent(tt.event); got != tt.want {
+				t.Errorf("rewriteEvent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/tehbooom/elastic-data/internal/generator"
+)
+
+// eventDelimiter separates the events of a template file, it won't conflict with log content
+const eventDelimiter = "\n---EVENT_DELIMITER---\n"
+
+var (
+	numericMemberRegex   = regexp.MustCompile(`"([A-Za-z0-9_@.]+)":(-?\d+(?:\.\d+)?)([,}\]])`)
+	userAgentMemberRegex = regexp.MustCompile(`(?i)("[^"]*user_agent[^"]*":(?:\{"original":)?)"((?:[^"\\{]|\\.)+)"`)
+	variableRegex        = regexp.MustCompile(`\{\{\.[A-Za-z0-9_]+\}\}`)
+)
+
+// rewriteTemplates applies the generator function rules to the templates already in dir,
+// so that they can be updated without downloading the integrations again
+func rewriteTemplates(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".tmpl" {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read template file %s: %w", path, err)
+		}
+
+		events := strings.Split(string(content), eventDelimiter)
+		changed := 0
+		for i, event := range events {
+			rewritten := rewriteEvent(event)
+			if rewritten != event {
+				events[i] = rewritten
+				changed++
+			}
+		}
+
+		if changed == 0 {
+			return nil
+		}
+
+		log.Printf("Rewrote %d of %d events in %s", changed, len(events), path)
+		return writeTemplateFile(path, events)
+	})
+}
+
+// rewriteEvent replaces the values of an event template that have a generator function,
+// keeping the event unchanged when the result is not a valid template
+func rewriteEvent(event string) string {
+	rewritten := replaceFunctionValues(event)
+
+	if isJSONEvent(event) {
+		rewritten = userAgentMemberRegex.ReplaceAllString(rewritten, `$1"{{user_agent}}"`)
+		rewritten = numericMemberRegex.ReplaceAllStringFunc(rewritten, func(member string) string {
+			match := numericMemberRegex.FindStringSubmatch(member)
+			value, err := strconv.ParseFloat(match[2], 64)
+			if err != nil {
+				return member
+			}
+
+			placeholder := convertNumericValueToTemplateWithTracker(match[1], value, make(map[string]map[string]int))
+			if placeholder == "" || strings.HasPrefix(placeholder, "{{.") {
+				return member
+			}
+			return fmt.Sprintf("%q:%s%s", match[1], placeholder, match[3])
+		})
+	}
+
+	if rewritten == event || !validTemplate(rewritten, isJSONEvent(event)) {
+		return event
+	}
+	return rewritten
+}
+
+// isJSONEvent reports whether the event is a JSON document once its variables are set
+func isJSONEvent(event string) bool {
+	return json.Valid([]byte(variableRegex.ReplaceAllString(event, "0")))
+}
+
+// validTemplate reports whether event parses and, for JSON events, still renders a JSON document
+func validTemplate(event string, isJSON bool) bool {
+	tmpl, err := template.New("rewrite").Funcs(generator.Funcs()).Parse(variableRegex.ReplaceAllString(event, "0"))
+	if err != nil {
+		return false
+	}
+
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, nil); err != nil {
+		return false
+	}
+
+	return !isJSON || json.Valid(rendered.Bytes())
+}
@@ -20,8 +20,43 @@ var (
 	SnortNoYearRegex     = regexp.MustCompile(`\d{2}/\d{2}-\d{2}:\d{2}:\d{2}\.\d+`)
 	HostnameRegex        = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
 	UsernameRegex        = regexp.MustCompile(`^[a-zA-Z0-9._-]+$`)
+
+	// The field regexes capture the value of username and hostname fields in JSON,
+	// including JSON escaped inside a string, as the value group
+	UserFieldRegex       = regexp.MustCompile(`(?i)\\?"(?:user[._]?name|user)\\?"\s*:\s*\\?"(?P<value>[^"\\{\-][^"\\]*)\\?"`)
+	NestedUserFieldRegex = regexp.MustCompile(`(?i)\\?"user\\?"\s*:\s*\{[^{}]*?\\?"name\\?"\s*:\s*\\?"(?P<value>[^"\\{\-][^"\\]*)\\?"`)
+	HostFieldRegex       = regexp.MustCompile(`(?i)\\?"(?:host[._]?name|host|computer[._]?name)\\?"\s*:\s*\\?"(?P<value>[^"\\{\-][^"\\]*)\\?"`)
+	NestedHostFieldRegex = regexp.MustCompile(`(?i)\\?"host\\?"\s*:\s*\{[^{}]*?\\?"name\\?"\s*:\s*\\?"(?P<value>[^"\\{\-][^"\\]*)\\?"`)
+	// SyslogHostRegex captures the host following an RFC 3164 timestamp, or its template variable, at the start of a line
+	SyslogHostRegex = regexp.MustCompile(`(?m)^(?:<\d+>)?(?:[A-Za-z]{3}\s+\d{1,2}\s+\d{2}:\d{2}:\d{2}|\{\{\.timestamp_syslog\}\})\s+(?P<value>[A-Za-z0-9][A-Za-z0-9._-]*)\s`)
+	// Syslog5424HostRegex captures the host following the version and timestamp of an RFC 5424 message
+	Syslog5424HostRegex = regexp.MustCompile(`(?m)^<\d+>1 \S+ (?P<value>[A-Za-z0-9][A-Za-z0-9._-]*)\s`)
 )
 
+// ReplaceValues returns s with every match of regex replaced by the result of replace.
+// When regex has a group named value only the text of that group is replaced.
+func ReplaceValues(regex *regexp.Regexp, s string, replace func(string) string) string {
+	group := regex.SubexpIndex("value")
+	if group < 0 {
+		return regex.ReplaceAllStringFunc(s, replace)
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, match := range regex.FindAllStringSubmatchIndex(s, -1) {
+		start, end := match[2*group], match[2*group+1]
+		if start < 0 {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(replace(s[start:end]))
+		last = end
+	}
+	b.WriteString(s[last:])
+
+	return b.String()
+}
+
 func IsEmail(s string) bool {
 	_, err := mail.ParseAddress(s)
 	regex := regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	defaultRenderWorkers = 2
+	defaultBulkWorkers   = 2
+	defaultFlushBytes    = 5 * 1024 * 1024
+	defaultFlushInterval = time.Second
+	defaultQueueSize     = 10000
+)
+
+// BulkConfig controls the pipeline a dataset sends its events through. Render workers
+// fill a bounded queue that bulk workers drain, each bulk worker sending a request once
+// it holds flush_bytes of events or flush_interval has passed.
+type BulkConfig struct {
+	// RenderWorkers number of goroutines rendering events, defaults to 2
+	RenderWorkers int `yaml:"render_workers,omitempty"`
+	// Workers number of bulk requests in flight at once, defaults to 2
+	Workers int `yaml:"workers,omitempty"`
+	// FlushBytes size of the events a bulk request is sent at, defaults to 5MB
+	FlushBytes int `yaml:"flush_bytes,omitempty"`
+	// FlushInterval longest time an event waits before it is sent, defaults to 1s
+	FlushInterval time.Duration `yaml:"flush_interval,omitempty"`
+	// QueueSize number of rendered events waiting for a bulk worker before rendering blocks, defaults to 10000
+	QueueSize int `yaml:"queue_size,omitempty"`
+}
+
+// WithDefaults returns the bulk config with unset values replaced by their defaults
+func (b BulkConfig) WithDefaults() BulkConfig {
+	if b.RenderWorkers == 0 {
+		b.RenderWorkers = defaultRenderWorkers
+	}
+	if b.Workers == 0 {
+		b.Workers = defaultBulkWorkers
+	}
+	if b.FlushBytes == 0 {
+		b.FlushBytes = defaultFlushBytes
+	}
+	if b.FlushInterval == 0 {
+		b.FlushInterval = defaultFlushInterval
+	}
+	if b.QueueSize == 0 {
+		b.QueueSize = defaultQueueSize
+	}
+	return b
+}
+
+func validateBulk(bulk BulkConfig) error {
+	if bulk.RenderWorkers < 0 || bulk.Workers < 0 || bulk.QueueSize < 0 {
+		return fmt.Errorf("render_workers, workers and queue_size cannot be negative")
+	}
+
+	if bulk.FlushBytes < 0 || bulk.FlushInterval < 0 {
+		return fmt.Errorf("flush_bytes and flush_interval cannot be negative")
+	}
+
+	return nil
+}
@@ -4,21 +4,34 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/log"
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Connection   ConfigConnection       `yaml:"connection"`
-	Integrations map[string]Integration `yaml:"integrations,omitempty"`
-	Replacements Replacements           `yaml:"replacements"`
+	Connection    ConfigConnection            `yaml:"connection"`
+	Profiles      map[string]ConfigConnection `yaml:"profiles,omitempty"`
+	ActiveProfile string                      `yaml:"active_profile,omitempty"`
+	Integrations  map[string]Integration      `yaml:"integrations,omitempty"`
+	Replacements  Replacements                `yaml:"replacements"`
+	TemplatesDir  string                      `yaml:"templates_dir,omitempty"`
+	Retry         RetryConfig                 `yaml:"retry,omitempty"`
+	Entities      EntitiesConfig              `yaml:"entities,omitempty"`
+
+	// profileOverride profile used instead of ActiveProfile for this run, it is never saved
+	profileOverride string
 }
 
+// ConfigConnection is how to reach and authenticate with a cluster. Credentials, endpoints and
+// the cloud ID may hold ${NAME} references to environment variables, see Resolve.
 type ConfigConnection struct {
-	KibanaEndpoints        []string `yaml:"kibana_endpoints"`
-	ElasticsearchEndpoints []string `yaml:"elasticsearch_endpoints"`
+	KibanaEndpoints        []string `yaml:"kibana_endpoints,omitempty"`
+	ElasticsearchEndpoints []string `yaml:"elasticsearch_endpoints,omitempty"`
+	CloudID                string   `yaml:"cloud_id,omitempty"`
 	APIKey                 string   `yaml:"api_key,omitempty"`
 	Username               string   `yaml:"username"`
 	Password               string   `yaml:"password"`
@@ -28,19 +41,83 @@ type ConfigConnection struct {
 	Key                    string   `yaml:"key,omitempty"`
 }
 
+// RetryConfig controls how bulk requests rejected with a retryable status
+// (429 or 5xx) are retried with exponential backoff
+type RetryConfig struct {
+	// MaxRetries number of retries, 0 uses the default and a negative value disables retries
+	MaxRetries     int           `yaml:"max_retries,omitempty"`
+	InitialBackoff time.Duration `yaml:"initial_backoff,omitempty"`
+	MaxBackoff     time.Duration `yaml:"max_backoff,omitempty"`
+}
+
+// EntitiesConfig controls the simulated users shared by every dataset. While enabled
+// the IP, user, host, email and domain of an event belong to the same entity.
+type EntitiesConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Count number of entities built from the replacements
+	Count int `yaml:"count,omitempty"`
+	// SessionDuration how long the same entities stay active across datasets
+	SessionDuration time.Duration `yaml:"session_duration,omitempty"`
+	// ConcurrentSessions number of entities active at the same time
+	ConcurrentSessions int `yaml:"concurrent_sessions,omitempty"`
+}
+
+// Integration is an integration package and its datasets. Version pins the package
+// version to install, the latest version is installed when it is empty.
 type Integration struct {
-	Enabled  bool               `yaml:"enabled"`
-	Datasets map[string]Dataset `yaml:"datasets,omitempty"`
+	Enabled   bool               `yaml:"enabled"`
+	Namespace string             `yaml:"namespace,omitempty"`
+	Version   string             `yaml:"version,omitempty"`
+	Datasets  map[string]Dataset `yaml:"datasets,omitempty"`
 }
 
 type Dataset struct {
-	Enabled               bool     `yaml:"enabled"`
-	Threshold             int      `yaml:"threshold"`
-	PreserveEventOriginal bool     `yaml:"preserve_original_event"`
-	Unit                  string   `yaml:"unit"`
-	Events                []string `yaml:"events,omitempty"`
+	Enabled               bool          `yaml:"enabled"`
+	Threshold             int           `yaml:"threshold"`
+	PreserveEventOriginal bool          `yaml:"preserve_original_event"`
+	Unit                  string        `yaml:"unit"`
+	Events                []string      `yaml:"events,omitempty"`
+	Type                  string        `yaml:"type,omitempty"`
+	Namespace             string        `yaml:"namespace,omitempty"`
+	TimestampDistribution string        `yaml:"timestamp_distribution,omitempty"`
+	TimestampJitter       time.Duration `yaml:"timestamp_jitter,omitempty"`
+	BackfillVolume        int           `yaml:"backfill_volume,omitempty"`
+	Output                OutputConfig  `yaml:"output,omitempty"`
+	ExcludeTemplates      []string      `yaml:"exclude_templates,omitempty"`
+	Rate                  RateProfile   `yaml:"rate,omitempty"`
+	Bulk                  BulkConfig    `yaml:"bulk,omitempty"`
 }
 
+const (
+	DefaultNamespace      = "default"
+	DefaultDataStreamType = "logs"
+
+	// DefaultProfile name of the connection profile made of the connection section
+	DefaultProfile = "default"
+
+	// DistributionEven spaces timestamps evenly across the batch interval
+	DistributionEven = "even"
+	// DistributionRandom places timestamps uniformly at random across the batch interval
+	DistributionRandom = "random"
+	// DistributionPoisson spaces timestamps with exponentially distributed gaps
+	// like independent arrivals
+	DistributionPoisson = "poisson"
+
+	defaultMaxRetries     = 3
+	defaultInitialBackoff = 500 * time.Millisecond
+	defaultMaxBackoff     = 10 * time.Second
+
+	defaultEntityCount        = 20
+	defaultSessionDuration    = 15 * time.Minute
+	defaultConcurrentSessions = 3
+)
+
+var (
+	timestampDistributions     = []string{DistributionEven, DistributionRandom, DistributionPoisson}
+	dataStreamTypes            = []string{"logs", "metrics", "traces", "synthetics", "profiling"}
+	invalidNamespaceCharacters = `\/*?"<>| ,#:-`
+)
+
 // LoadConfig returns the config, configuration directory and errors
 func LoadConfig() (*Config, string, error) {
 	// Follow XDG Base Directory Specification
@@ -113,14 +190,187 @@ func LoadConfig() (*Config, string, error) {
 	return config, appConfigDir, nil
 }
 
+// GetTemplatesDir returns the directory holding template overrides. Relative
+// paths are resolved against the configuration directory and it defaults to
+// the templates directory next to the config file.
+func (c *Config) GetTemplatesDir(configDir string) string {
+	if c.TemplatesDir == "" {
+		return filepath.Join(configDir, "templates")
+	}
+
+	if filepath.IsAbs(c.TemplatesDir) {
+		return c.TemplatesDir
+	}
+
+	return filepath.Join(configDir, c.TemplatesDir)
+}
+
+// ActiveConnection returns the resolved connection of the active profile
+func (c *Config) ActiveConnection() (ConfigConnection, error) {
+	return c.ProfileConnection(c.activeProfile())
+}
+
+// ProfileNames returns the default profile followed by the configured profiles in order
+func (c *Config) ProfileNames() []string {
+	names := []string{DefaultProfile}
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names[1:])
+
+	return names
+}
+
+// ProfileConnection returns the resolved connection of the named profile
+func (c *Config) ProfileConnection(name string) (ConfigConnection, error) {
+	connection := c.Connection
+	if name != DefaultProfile && name != "" {
+		var exists bool
+		connection, exists = c.Profiles[name]
+		if !exists {
+			return ConfigConnection{}, fmt.Errorf("profile %s not found, available profiles are %s", name, strings.Join(c.ProfileNames(), ", "))
+		}
+	}
+
+	if err := validateConnection(&connection); err != nil {
+		log.Debug(err)
+		return ConfigConnection{}, fmt.Errorf("invalid connection for profile %s: %w", c.profileName(name), err)
+	}
+
+	resolved, err := connection.Resolve()
+	if err != nil {
+		log.Debug(err)
+		return ConfigConnection{}, fmt.Errorf("invalid connection for profile %s: %w", c.profileName(name), err)
+	}
+
+	return resolved, nil
+}
+
+func (c *Config) profileName(name string) string {
+	if name == "" {
+		return DefaultProfile
+	}
+	return name
+}
+
+// UseProfile makes the named connection profile active, it is saved with the config
+func (c *Config) UseProfile(name string) error {
+	if _, err := c.ProfileConnection(name); err != nil {
+		return err
+	}
+
+	c.ActiveProfile = name
+	if name == DefaultProfile {
+		c.ActiveProfile = ""
+	}
+	c.profileOverride = ""
+
+	return nil
+}
+
+// OverrideProfile uses the named connection profile for this run only, leaving the
+// saved active profile unchanged
+func (c *Config) OverrideProfile(name string) error {
+	if _, err := c.ProfileConnection(name); err != nil {
+		return err
+	}
+
+	c.profileOverride = c.profileName(name)
+
+	return nil
+}
+
+// Profile returns the name of the active connection profile
+func (c *Config) Profile() string {
+	return c.profileName(c.activeProfile())
+}
+
+// activeProfile returns the profile overridden for this run or else the saved active profile
+func (c *Config) activeProfile() string {
+	if c.profileOverride != "" {
+		return c.profileOverride
+	}
+	return c.ActiveProfile
+}
+
+// GetNamespace returns the data stream namespace for a dataset of an integration.
+// The dataset namespace takes precedence over the integration namespace.
+func (c *Config) GetNamespace(integration, datasetNamespace string) string {
+	if datasetNamespace != "" {
+		return datasetNamespace
+	}
+
+	if integrationConfig, exists := c.Integrations[integration]; exists && integrationConfig.Namespace != "" {
+		return integrationConfig.Namespace
+	}
+
+	return DefaultNamespace
+}
+
+// DataStreamName returns the name of the data stream <type>-<integration>.<dataset>-<namespace>
+func DataStreamName(dataStreamType, integration, dataset, namespace string) string {
+	return fmt.Sprintf("%s-%s.%s-%s", dataStreamType, integration, dataset, namespace)
+}
+
+// WithDefaults returns the retry config with unset values replaced by their defaults
+func (r RetryConfig) WithDefaults() RetryConfig {
+	if r.MaxRetries == 0 {
+		r.MaxRetries = defaultMaxRetries
+	} else if r.MaxRetries < 0 {
+		r.MaxRetries = 0
+	}
+
+	if r.InitialBackoff <= 0 {
+		r.InitialBackoff = defaultInitialBackoff
+	}
+
+	if r.MaxBackoff <= 0 {
+		r.MaxBackoff = defaultMaxBackoff
+	}
+
+	if r.MaxBackoff < r.InitialBackoff {
+		r.MaxBackoff = r.InitialBackoff
+	}
+
+	return r
+}
+
+// Backoff returns the time to wait before the given retry attempt, starting at 1
+func (r RetryConfig) Backoff(attempt int) time.Duration {
+	backoff := r.InitialBackoff
+	for i := 1; i < attempt && backoff < r.MaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, r.MaxBackoff)
+}
+
+// WithDefaults returns the entities config with unset values replaced by their defaults
+func (e EntitiesConfig) WithDefaults() EntitiesConfig {
+	if e.Count <= 0 {
+		e.Count = defaultEntityCount
+	}
+
+	if e.SessionDuration <= 0 {
+		e.SessionDuration = defaultSessionDuration
+	}
+
+	if e.ConcurrentSessions <= 0 {
+		e.ConcurrentSessions = defaultConcurrentSessions
+	}
+
+	return e
+}
+
 func isConfigEmpty(config *Config) bool {
-	kibanaEndpointsEmpty := len(config.Connection.KibanaEndpoints) == 0 ||
-		(len(config.Connection.KibanaEndpoints) > 0 && config.Connection.KibanaEndpoints[0] == "")
-	esEndpointsEmpty := len(config.Connection.ElasticsearchEndpoints) == 0 ||
-		(len(config.Connection.ElasticsearchEndpoints) > 0 && config.Connection.ElasticsearchEndpoints[0] == "")
-	endpointsEmpty := kibanaEndpointsEmpty && esEndpointsEmpty
-	usernameEmpty := config.Connection.Username == ""
-	return endpointsEmpty && usernameEmpty
+	return config.Connection.isEmpty() && len(config.Profiles) == 0
+}
+
+// isEmpty reports whether the connection sets no endpoints, cloud ID or credentials
+func (c ConfigConnection) isEmpty() bool {
+	endpointsEmpty := endpointsEmpty(c.KibanaEndpoints) && endpointsEmpty(c.ElasticsearchEndpoints) && c.CloudID == ""
+	credentialsEmpty := c.Username == "" && c.APIKey == ""
+	return endpointsEmpty && credentialsEmpty
 }
 
 func SaveConfig(config *Config, configDir string) error {
@@ -172,12 +422,20 @@ func SaveConfig(config *Config, configDir string) error {
 	return nil
 }
 
+// setDefaults fills in a local cluster. Values set by the ELASTIC_DATA_* environment
+// variables are left empty, the connection only reads them when its fields are empty.
 func setDefaults(config *Config) {
-	if len(config.Connection.KibanaEndpoints) == 0 {
-		config.Connection.KibanaEndpoints = []string{"http://localhost:5601"}
+	if os.Getenv(EnvCloudID) == "" {
+		if len(config.Connection.KibanaEndpoints) == 0 {
+			config.Connection.KibanaEndpoints = []string{"http://localhost:5601"}
+		}
+		if len(config.Connection.ElasticsearchEndpoints) == 0 {
+			config.Connection.ElasticsearchEndpoints = []string{"http://localhost:9200"}
+		}
 	}
-	if len(config.Connection.ElasticsearchEndpoints) == 0 {
-		config.Connection.ElasticsearchEndpoints = []string{"http://localhost:9200"}
+
+	if os.Getenv(EnvAPIKey) != "" || os.Getenv(EnvUsername) != "" || os.Getenv(EnvPassword) != "" {
+		return
 	}
 	if config.Connection.Username == "" {
 		config.Connection.Username = "elastic"
@@ -193,22 +451,29 @@ func ValidateConfig(config *Config) error {
 		return fmt.Errorf("config is nil")
 	}
 
-	if err := validateEndpoints(config.Connection.KibanaEndpoints, "kibana_endpoints"); err != nil {
-		return err
+	// The connection section is only checked when it is the active profile. A config with
+	// nothing but profiles leaves it empty and picks a profile with --profile.
+	if config.ActiveProfile == "" && !(config.Connection.isEmpty() && len(config.Profiles) > 0) {
+		if err := validateConnection(&config.Connection); err != nil {
+			return err
+		}
 	}
 
-	if err := validateEndpoints(config.Connection.ElasticsearchEndpoints, "elasticsearch_endpoints"); err != nil {
-		return err
-	}
+	for name, connection := range config.Profiles {
+		if name == DefaultProfile || name == "" {
+			return fmt.Errorf("invalid profile name %q, the connection section is the %s profile", name, DefaultProfile)
+		}
 
-	hasAPIKey := config.Connection.APIKey != ""
-	hasUserPass := config.Connection.Username != "" && config.Connection.Password != ""
+		if err := validateConnection(&connection); err != nil {
+			return fmt.Errorf("invalid connection for profile %s: %w", name, err)
+		}
+	}
 
-	if !hasAPIKey && !hasUserPass {
-		return fmt.Errorf("authentication required: must provide either api_key or both username and password")
+	if _, exists := config.Profiles[config.ActiveProfile]; config.ActiveProfile != "" && !exists {
+		return fmt.Errorf("active_profile %s is not one of the profiles", config.ActiveProfile)
 	}
 
-	if err := validateTLSConfig(&config.Connection); err != nil {
+	if err := validateEntities(config.Entities); err != nil {
 		return err
 	}
 
@@ -223,6 +488,39 @@ func ValidateConfig(config *Config) error {
 	return nil
 }
 
+// validateConnection ensures a connection has endpoints, credentials and a consistent TLS configuration.
+// Values referring to environment variables are checked once resolved, when the connection is used.
+func validateConnection(conn *ConfigConnection) error {
+	hasCloudID := conn.CloudID != "" || os.Getenv(EnvCloudID) != ""
+	if conn.CloudID != "" && !hasEnvReference(conn.CloudID) {
+		if _, _, err := ParseCloudID(conn.CloudID); err != nil {
+			return err
+		}
+	}
+
+	if !hasCloudID || !endpointsEmpty(conn.KibanaEndpoints) {
+		if err := validateEndpoints(conn.KibanaEndpoints, "kibana_endpoints"); err != nil {
+			return err
+		}
+	}
+
+	if !hasCloudID || !endpointsEmpty(conn.ElasticsearchEndpoints) {
+		if err := validateEndpoints(conn.ElasticsearchEndpoints, "elasticsearch_endpoints"); err != nil {
+			return err
+		}
+	}
+
+	hasAPIKey := conn.APIKey != "" || os.Getenv(EnvAPIKey) != ""
+	hasUserPass := (conn.Username != "" || os.Getenv(EnvUsername) != "") &&
+		(conn.Password != "" || os.Getenv(EnvPassword) != "")
+
+	if !hasAPIKey && !hasUserPass {
+		return fmt.Errorf("authentication required: must provide either api_key or both username and password, in the config or with the %s, %s and %s environment variables", EnvAPIKey, EnvUsername, EnvPassword)
+	}
+
+	return validateTLSConfig(conn)
+}
+
 func validateEndpoints(endpoints []string, fieldName string) error {
 	if len(endpoints) == 0 {
 		return fmt.Errorf("%s cannot be empty", fieldName)
@@ -233,6 +531,10 @@ func validateEndpoints(endpoints []string, fieldName string) error {
 			return fmt.Errorf("%s[%d] cannot be empty", fieldName, i)
 		}
 
+		if hasEnvReference(endpoint) {
+			continue
+		}
+
 		if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
 			return fmt.Errorf("%s[%d] must be a valid URL starting with http:// or https://: %s", fieldName, i, endpoint)
 		}
@@ -279,6 +581,23 @@ func validateTLSConfig(conn *ConfigConnection) error {
 	return nil
 }
 
+// validateEntities ensures the entities configuration has no negative values
+func validateEntities(entities EntitiesConfig) error {
+	if entities.Count < 0 {
+		return fmt.Errorf("entities count cannot be negative")
+	}
+
+	if entities.SessionDuration < 0 {
+		return fmt.Errorf("entities session_duration cannot be negative")
+	}
+
+	if entities.ConcurrentSessions < 0 {
+		return fmt.Errorf("entities concurrent_sessions cannot be negative")
+	}
+
+	return nil
+}
+
 // validateIntegrations validates the integrations configuration
 func validateIntegrations(integrations map[string]Integration) error {
 	for integrationName, integration := range integrations {
@@ -286,6 +605,10 @@ func validateIntegrations(integrations map[string]Integration) error {
 			return fmt.Errorf("integration name cannot be empty")
 		}
 
+		if err := validateNamespace(integration.Namespace); err != nil {
+			return fmt.Errorf("invalid namespace for integration %s: %w", integrationName, err)
+		}
+
 		for datasetName, dataset := range integration.Datasets {
 			if datasetName == "" {
 				return fmt.Errorf("dataset name cannot be empty in integration %s", integrationName)
@@ -300,8 +623,65 @@ func validateIntegrations(integrations map[string]Integration) error {
 					return fmt.Errorf("invalid unit %s for dataset %s in integration %s. Valid units are eps or bytes", dataset.Unit, datasetName, integrationName)
 				}
 			}
+
+			if dataset.Type != "" && !slices.Contains(dataStreamTypes, dataset.Type) {
+				return fmt.Errorf("invalid type %s for dataset %s in integration %s. Valid types are %s", dataset.Type, datasetName, integrationName, strings.Join(dataStreamTypes, ", "))
+			}
+
+			if err := validateNamespace(dataset.Namespace); err != nil {
+				return fmt.Errorf("invalid namespace for dataset %s in integration %s: %w", datasetName, integrationName, err)
+			}
+
+			if dataset.TimestampDistribution != "" && !slices.Contains(timestampDistributions, dataset.TimestampDistribution) {
+				return fmt.Errorf("invalid timestamp distribution %s for dataset %s in integration %s. Valid distributions are %s", dataset.TimestampDistribution, datasetName, integrationName, strings.Join(timestampDistributions, ", "))
+			}
+
+			if dataset.TimestampJitter < 0 {
+				return fmt.Errorf("timestamp jitter cannot be negative for dataset %s in integration %s", datasetName, integrationName)
+			}
+
+			if dataset.BackfillVolume < 0 {
+				return fmt.Errorf("backfill volume cannot be negative for dataset %s in integration %s", datasetName, integrationName)
+			}
+
+			if err := validateOutput(dataset.Output); err != nil {
+				return fmt.Errorf("invalid output for dataset %s in integration %s: %w", datasetName, integrationName, err)
+			}
+
+			if !dataset.Rate.IsConstant() && dataset.Unit != "eps" {
+				return fmt.Errorf("rate of dataset %s in integration %s requires the eps unit", datasetName, integrationName)
+			}
+
+			if err := validateRate(dataset.Rate, dataset.Threshold); err != nil {
+				return fmt.Errorf("invalid rate for dataset %s in integration %s: %w", datasetName, integrationName, err)
+			}
+
+			if err := validateBulk(dataset.Bulk); err != nil {
+				return fmt.Errorf("invalid bulk settings for dataset %s in integration %s: %w", datasetName, integrationName, err)
+			}
 		}
 	}
 
 	return nil
 }
+
+// validateNamespace checks a data stream namespace against the Elasticsearch naming restrictions
+func validateNamespace(namespace string) error {
+	if namespace == "" {
+		return nil
+	}
+
+	if len(namespace) > 100 {
+		return fmt.Errorf("namespace %s must be at most 100 bytes", namespace)
+	}
+
+	if strings.ToLower(namespace) != namespace {
+		return fmt.Errorf("namespace %s must be lowercase", namespace)
+	}
+
+	if strings.ContainsAny(namespace, invalidNamespaceCharacters) {
+		return fmt.Errorf("namespace %s cannot contain any of %s", namespace, invalidNamespaceCharacters)
+	}
+
+	return nil
+}
@@ -0,0 +1,338 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func profilesConfig() *Config {
+	cfg := &Config{
+		Connection: ConfigConnection{
+			ElasticsearchEndpoints: []string{"http://localhost:9200"},
+			KibanaEndpoints:        []string{"http://localhost:5601"},
+			Username:               "elastic",
+			Password:               "changeme",
+		},
+		Profiles: map[string]ConfigConnection{
+			"staging": {
+				ElasticsearchEndpoints: []string{"https://staging.example.com:9200"},
+				KibanaEndpoints:        []string{"https://staging.example.com:5601"},
+				APIKey:                 "abcd1234",
+			},
+		},
+	}
+	cfg.Replacements.setDefaults()
+	return cfg
+}
+
+// clearConnectionEnv unsets the environment variables filling in connections
+func clearConnectionEnv(t *testing.T) {
+	t.Helper()
+
+	for _, name := range []string{EnvCloudID, EnvAPIKey, EnvUsername, EnvPassword} {
+		t.Setenv(name, "")
+	}
+}
+
+func savedConfig(t *testing.T, cfg *Config) *Config {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := SaveConfig(cfg, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	saved := &Config{}
+	if err := yaml.Unmarshal(data, saved); err != nil {
+		t.Fatal(err)
+	}
+	return saved
+}
+
+func TestOverrideProfile(t *testing.T) {
+	cfg := profilesConfig()
+
+	if err := cfg.OverrideProfile("staging"); err != nil {
+		t.Fatal(err)
+	}
+
+	connection, err := cfg.ActiveConnection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Profile() != "staging" || connection.APIKey != "abcd1234" {
+		t.Errorf("active profile %s with %+v", cfg.Profile(), connection)
+	}
+
+	if saved := savedConfig(t, cfg); saved.ActiveProfile != "" {
+		t.Errorf("saved active_profile %q, the override must not be saved", saved.ActiveProfile)
+	}
+
+	if err := cfg.OverrideProfile("missing"); err == nil || !strings.Contains(err.Error(), "profile missing not found") {
+		t.Errorf("OverrideProfile() error = %v", err)
+	}
+	if cfg.Profile() != "staging" {
+		t.Errorf("failed override changed the profile to %s", cfg.Profile())
+	}
+}
+
+func TestOverrideDefaultProfile(t *testing.T) {
+	cfg := profilesConfig()
+	cfg.ActiveProfile = "staging"
+
+	// The default profile overrides a saved active profile
+	if err := cfg.OverrideProfile(DefaultProfile); err != nil {
+		t.Fatal(err)
+	}
+
+	connection, err := cfg.ActiveConnection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Profile() != DefaultProfile || connection.Username != "elastic" {
+		t.Errorf("active profile %s with %+v", cfg.Profile(), connection)
+	}
+
+	if saved := savedConfig(t, cfg); saved.ActiveProfile != "staging" {
+		t.Errorf("saved active_profile %q, want staging", saved.ActiveProfile)
+	}
+}
+
+func TestUseProfile(t *testing.T) {
+	cfg := profilesConfig()
+	if err := cfg.OverrideProfile(DefaultProfile); err != nil {
+		t.Fatal(err)
+	}
+
+	// Switching profiles replaces the override and is saved
+	if err := cfg.UseProfile("staging"); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Profile() != "staging" {
+		t.Errorf("active profile %s, want staging", cfg.Profile())
+	}
+	if saved := savedConfig(t, cfg); saved.ActiveProfile != "staging" {
+		t.Errorf("saved active_profile %q, want staging", saved.ActiveProfile)
+	}
+
+	if err := cfg.UseProfile(DefaultProfile); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ActiveProfile != "" || cfg.Profile() != DefaultProfile {
+		t.Errorf("active_profile %q after switching to the default profile", cfg.ActiveProfile)
+	}
+}
+
+func TestValidateConfigConnection(t *testing.T) {
+	broken := ConfigConnection{ElasticsearchEndpoints: []string{"localhost:9200"}, KibanaEndpoints: []string{"http://localhost:5601"}, APIKey: "key"}
+
+	tests := []struct {
+		name   string
+		config func(*Config)
+		err    string
+	}{
+		{name: "default profile", config: func(*Config) {}},
+		{
+			name:   "only profiles",
+			config: func(c *Config) { c.Connection = ConfigConnection{} },
+		},
+		{
+			name: "connection not in use",
+			config: func(c *Config) {
+				c.Connection = broken
+				c.ActiveProfile = "staging"
+			},
+		},
+		{
+			name:   "connection in use",
+			config: func(c *Config) { c.Connection = broken },
+			err:    "elasticsearch_endpoints[0] must be a valid URL",
+		},
+		{
+			name: "empty connection without profiles",
+			config: func(c *Config) {
+				c.Connection = ConfigConnection{}
+				c.Profiles = nil
+			},
+			err: "kibana_endpoints cannot be empty",
+		},
+		{
+			name:   "broken profile",
+			config: func(c *Config) { c.Profiles["prod"] = broken },
+			err:    "invalid connection for profile prod",
+		},
+		{
+			name:   "unknown active profile",
+			config: func(c *Config) { c.ActiveProfile = "prod" },
+			err:    "active_profile prod is not one of the profiles",
+		},
+	}
+
+	clearConnectionEnv(t)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := profilesConfig()
+			tt.config(cfg)
+
+			err := ValidateConfig(cfg)
+			if tt.err == "" && err != nil {
+				t.Fatalf("ValidateConfig() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Fatalf("ValidateConfig() error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestProfileConnectionValidates(t *testing.T) {
+	clearConnectionEnv(t)
+	cfg := profilesConfig()
+	cfg.Connection = ConfigConnection{}
+
+	// A config with only profiles cannot use the default profile
+	if _, err := cfg.ActiveConnection(); err == nil || !strings.Contains(err.Error(), "invalid connection for profile default") {
+		t.Errorf("ActiveConnection() error = %v", err)
+	}
+
+	if err := cfg.OverrideProfile("staging"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cfg.ActiveConnection(); err != nil {
+		t.Errorf("ActiveConnection() error = %v", err)
+	}
+}
+
+func TestGetNamespace(t *testing.T) {
+	cfg := &Config{
+		Integrations: map[string]Integration{
+			"nginx":  {Namespace: "web"},
+			"system": {},
+		},
+	}
+
+	tests := []struct {
+		name             string
+		integration      string
+		datasetNamespace string
+		want             string
+	}{
+		{name: "dataset namespace", integration: "nginx", datasetNamespace: "prod", want: "prod"},
+		{name: "integration namespace", integration: "nginx", want: "web"},
+		{name: "integration without namespace", integration: "system", want: DefaultNamespace},
+		{name: "unknown integration", integration: "apache", want: DefaultNamespace},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cfg.GetNamespace(tt.integration, tt.datasetNamespace); got != tt.want {
+				t.Errorf("GetNamespace(%q, %q) = %q, want %q", tt.integration, tt.datasetNamespace, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDataStreamName(t *testing.T) {
+	tests := []struct {
+		dataStreamType, integration, dataset, namespace string
+		want                                            string
+	}{
+		{"logs", "nginx", "access", "default", "logs-nginx.access-default"},
+		{"metrics", "system", "cpu", "prod", "metrics-system.cpu-prod"},
+		{"logs", "aws", "cloudtrail", "team_a", "logs-aws.cloudtrail-team_a"},
+	}
+
+	for _, tt := range tests {
+		if got := DataStreamName(tt.dataStreamType, tt.integration, tt.dataset, tt.namespace); got != tt.want {
+			t.Errorf("DataStreamName(%q, %q, %q, %q) = %q, want %q", tt.dataStreamType, tt.integration, tt.dataset, tt.namespace, got, tt.want)
+		}
+	}
+}
+
+func TestValidateNamespace(t *testing.T) {
+	tests := []struct {
+		namespace string
+		err       string
+	}{
+		{namespace: ""},
+		{namespace: "default"},
+		{namespace: "team_a.prod"},
+		{namespace: strings.Repeat("a", 100)},
+		{namespace: strings.Repeat("a", 101), err: "must be at most 100 bytes"},
+		{namespace: "Prod", err: "must be lowercase"},
+		{namespace: "team-a", err: "cannot contain any of"},
+		{namespace: "team a", err: "cannot contain any of"},
+		{namespace: "team:a", err: "cannot contain any of"},
+		{namespace: "team/a", err: "cannot contain any of"},
+		{namespace: `team\a`, err: "cannot contain any of"},
+		{namespace: "team*", err: "cannot contain any of"},
+		{namespace: "team?", err: "cannot contain any of"},
+		{namespace: `team"a`, err: "cannot contain any of"},
+		{namespace: "<team>", err: "cannot contain any of"},
+		{namespace: "team|a", err: "cannot contain any of"},
+		{namespace: "team,a", err: "cannot contain any of"},
+		{namespace: "team#a", err: "cannot contain any of"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.namespace, func(t *testing.T) {
+			err := validateNamespace(tt.namespace)
+			if tt.err == "" && err != nil {
+				t.Fatalf("validateNamespace(%q) error = %v", tt.namespace, err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Fatalf("validateNamespace(%q) error = %v, want %q", tt.namespace, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestValidateIntegrationNamespaces(t *testing.T) {
+	clearConnectionEnv(t)
+
+	tests := []struct {
+		name        string
+		integration Integration
+		err         string
+	}{
+		{
+			name:        "valid namespaces",
+			integration: Integration{Namespace: "web", Datasets: map[string]Dataset{"access": {Namespace: "prod"}}},
+		},
+		{
+			name:        "invalid integration namespace",
+			integration: Integration{Namespace: "Web"},
+			err:         "invalid namespace for integration nginx",
+		},
+		{
+			name:        "invalid dataset namespace",
+			integration: Integration{Datasets: map[string]Dataset{"access": {Namespace: "prod-1"}}},
+			err:         "invalid namespace for dataset access in integration nginx",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := profilesConfig()
+			cfg.Integrations = map[string]Integration{"nginx": tt.integration}
+
+			err := ValidateConfig(cfg)
+			if tt.err == "" && err != nil {
+				t.Fatalf("ValidateConfig() error = %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Fatalf("ValidateConfig() error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/log"
+)
+
+const (
+	// EnvCloudID environment variable used when cloud_id is not set
+	EnvCloudID = "ELASTIC_DATA_CLOUD_ID"
+	// EnvAPIKey environment variable used when neither api_key nor username and password are set
+	EnvAPIKey = "ELASTIC_DATA_API_KEY"
+	// EnvUsername environment variable used when username is not set
+	EnvUsername = "ELASTIC_DATA_USERNAME"
+	// EnvPassword environment variable used when password is not set
+	EnvPassword = "ELASTIC_DATA_PASSWORD"
+)
+
+// envReference matches a ${NAME} reference to an environment variable
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Resolve returns the connection as used by the clients. ${NAME} references are replaced
+// by their environment variable, missing credentials are read from the ELASTIC_DATA_*
+// environment variables and endpoints are derived from the cloud ID when not set.
+// The connection itself keeps the references so that saving the config never writes secrets.
+func (c ConfigConnection) Resolve() (ConfigConnection, error) {
+	resolved := c
+	resolved.ElasticsearchEndpoints = slices.Clone(c.ElasticsearchEndpoints)
+	resolved.KibanaEndpoints = slices.Clone(c.KibanaEndpoints)
+
+	fields := map[string]*string{
+		"cloud_id": &resolved.CloudID,
+		"api_key":  &resolved.APIKey,
+		"username": &resolved.Username,
+		"password": &resolved.Password,
+	}
+	for i := range resolved.ElasticsearchEndpoints {
+		fields[fmt.Sprintf("elasticsearch_endpoints[%d]", i)] = &resolved.ElasticsearchEndpoints[i]
+	}
+	for i := range resolved.KibanaEndpoints {
+		fields[fmt.Sprintf("kibana_endpoints[%d]", i)] = &resolved.KibanaEndpoints[i]
+	}
+
+	for field, value := range fields {
+		expanded, err := expandEnv(*value)
+		if err != nil {
+			return ConfigConnection{}, fmt.Errorf("%s: %w", field, err)
+		}
+		*value = expanded
+	}
+
+	if resolved.CloudID == "" {
+		resolved.CloudID = os.Getenv(EnvCloudID)
+	}
+	if resolved.APIKey == "" && resolved.Username == "" && resolved.Password == "" {
+		resolved.APIKey = os.Getenv(EnvAPIKey)
+	}
+	if resolved.APIKey == "" {
+		if resolved.Username == "" {
+			resolved.Username = os.Getenv(EnvUsername)
+		}
+		if resolved.Password == "" {
+			resolved.Password = os.Getenv(EnvPassword)
+		}
+	}
+
+	if resolved.CloudID != "" {
+		esEndpoint, kibanaEndpoint, err := ParseCloudID(resolved.CloudID)
+		if err != nil {
+			return ConfigConnection{}, err
+		}
+		if endpointsEmpty(resolved.ElasticsearchEndpoints) {
+			resolved.ElasticsearchEndpoints = []string{esEndpoint}
+		}
+		if endpointsEmpty(resolved.KibanaEndpoints) {
+			resolved.KibanaEndpoints = []string{kibanaEndpoint}
+		}
+	}
+
+	return resolved, nil
+}
+
+// ParseCloudID returns the Elasticsearch and Kibana endpoints of an Elastic Cloud deployment.
+// A cloud ID is <name>:<base64 of host[:port]$elasticsearch id$kibana id>.
+func ParseCloudID(cloudID string) (string, string, error) {
+	_, encoded, found := strings.Cut(cloudID, ":")
+	if !found {
+		encoded = cloudID
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		log.Debug(err)
+		return "", "", fmt.Errorf("invalid cloud_id, cannot decode it: %w", err)
+	}
+
+	parts := strings.Split(string(decoded), "$")
+	if len(parts) < 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", fmt.Errorf("invalid cloud_id, expected host$elasticsearch id$kibana id")
+	}
+
+	host, port, hasPort := strings.Cut(parts[0], ":")
+	if !hasPort {
+		port = "443"
+	}
+
+	esEndpoint := fmt.Sprintf("https://%s.%s:%s", parts[1], host, port)
+	kibanaEndpoint := fmt.Sprintf("https://%s.%s:%s", parts[2], host, port)
+
+	return esEndpoint, kibanaEndpoint, nil
+}
+
+// expandEnv replaces every ${NAME} reference in value with the environment variable NAME
+func expandEnv(value string) (string, error) {
+	var missing []string
+	expanded := envReference.ReplaceAllStringFunc(value, func(reference string) string {
+		name := envReference.FindStringSubmatch(reference)[1]
+		env, exists := os.LookupEnv(name)
+		if !exists {
+			missing = append(missing, name)
+		}
+		return env
+	})
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+
+	return expanded, nil
+}
+
+// hasEnvReference reports whether value refers to an environment variable
+func hasEnvReference(value string) bool {
+	return envReference.MatchString(value)
+}
+
+func endpointsEmpty(endpoints []string) bool {
+	return len(endpoints) == 0 || (len(endpoints) == 1 && endpoints[0] == "")
+}
@@ -0,0 +1,296 @@
+package config
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func cloudID(name, decoded string) string {
+	encoded := base64.StdEncoding.EncodeToString([]byte(decoded))
+	if name == "" {
+		return encoded
+	}
+	return name + ":" + encoded
+}
+
+func TestParseCloudID(t *testing.T) {
+	tests := []struct {
+		name    string
+		cloudID string
+		es      string
+		kibana  string
+		err     string
+	}{
+		{
+			name:    "default port",
+			cloudID: cloudID("prod", "us-east-1.aws.found.io$es123$kb456"),
+			es:      "https://es123.us-east-1.aws.found.io:443",
+			kibana:  "https://kb456.us-east-1.aws.found.io:443",
+		},
+		{
+			name:    "port",
+			cloudID: cloudID("prod", "us-east-1.aws.found.io:9243$es123$kb456"),
+			es:      "https://es123.us-east-1.aws.found.io:9243",
+			kibana:  "https://kb456.us-east-1.aws.found.io:9243",
+		},
+		{
+			name:    "without a deployment name",
+			cloudID: cloudID("", "eu-west-1.aws.found.io$es123$kb456$apm789"),
+			es:      "https://es123.eu-west-1.aws.found.io:443",
+			kibana:  "https://kb456.eu-west-1.aws.found.io:443",
+		},
+		{name: "not base64", cloudID: "prod:not base64!", err: "cannot decode it"},
+		{name: "missing kibana", cloudID: cloudID("prod", "us-east-1.aws.found.io$es123"), err: "expected host$elasticsearch id$kibana id"},
+		{name: "empty id", cloudID: cloudID("prod", "us-east-1.aws.found.io$$kb456"), err: "expected host$elasticsearch id$kibana id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es, kibana, err := ParseCloudID(tt.cloudID)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("ParseCloudID() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if es != tt.es || kibana != tt.kibana {
+				t.Errorf("ParseCloudID() = %s, %s, want %s, %s", es, kibana, tt.es, tt.kibana)
+			}
+		})
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("ELASTIC_DATA_TEST_HOST", "es.example.com")
+	t.Setenv("ELASTIC_DATA_TEST_EMPTY", "")
+
+	tests := []struct {
+		value string
+		want  string
+		err   string
+	}{
+		{value: "plain", want: "plain"},
+		{value: "${ELASTIC_DATA_TEST_HOST}", want: "es.example.com"},
+		{value: "https://${ELASTIC_DATA_TEST_HOST}:9200", want: "https://es.example.com:9200"},
+		{value: "${ELASTIC_DATA_TEST_EMPTY}", want: ""},
+		{value: "$ELASTIC_DATA_TEST_HOST", want: "$ELASTIC_DATA_TEST_HOST"},
+		{value: "${1INVALID}", want: "${1INVALID}"},
+		{value: "${ELASTIC_DATA_TEST_MISSING}", err: "environment variable ELASTIC_DATA_TEST_MISSING is not set"},
+		{value: "${ELASTIC_DATA_TEST_MISSING}:${ELASTIC_DATA_TEST_OTHER}", err: "ELASTIC_DATA_TEST_MISSING, ELASTIC_DATA_TEST_OTHER is not set"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := expandEnv(tt.value)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expandEnv() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("expandEnv() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	cloud := cloudID("prod", "us-east-1.aws.found.io$es123$kb456")
+
+	tests := []struct {
+		name       string
+		env        map[string]string
+		connection ConfigConnection
+		want       ConfigConnection
+		err        string
+	}{
+		{
+			name:       "references",
+			env:        map[string]string{"ES_HOST": "es.example.com", "ES_KEY": "secret"},
+			connection: ConfigConnection{ElasticsearchEndpoints: []string{"https://${ES_HOST}:9200"}, APIKey: "${ES_KEY}"},
+			want:       ConfigConnection{ElasticsearchEndpoints: []string{"https://es.example.com:9200"}, APIKey: "secret"},
+		},
+		{
+			name:       "missing reference",
+			connection: ConfigConnection{KibanaEndpoints: []string{"${KB_HOST}"}},
+			err:        "kibana_endpoints[0]: environment variable KB_HOST is not set",
+		},
+		{
+			name:       "api key from the environment",
+			env:        map[string]string{EnvAPIKey: "env-key", EnvUsername: "env-user", EnvPassword: "env-pass"},
+			connection: ConfigConnection{},
+			want:       ConfigConnection{APIKey: "env-key"},
+		},
+		{
+			name:       "credentials from the environment",
+			env:        map[string]string{EnvUsername: "env-user", EnvPassword: "env-pass"},
+			connection: ConfigConnection{},
+			want:       ConfigConnection{Username: "env-user", Password: "env-pass"},
+		},
+		{
+			name:       "missing password from the environment",
+			env:        map[string]string{EnvAPIKey: "env-key", EnvPassword: "env-pass"},
+			connection: ConfigConnection{Username: "elastic"},
+			want:       ConfigConnection{Username: "elastic", Password: "env-pass"},
+		},
+		{
+			name:       "configured api key",
+			env:        map[string]string{EnvUsername: "env-user", EnvPassword: "env-pass"},
+			connection: ConfigConnection{APIKey: "key"},
+			want:       ConfigConnection{APIKey: "key"},
+		},
+		{
+			name:       "cloud ID from the environment",
+			env:        map[string]string{EnvCloudID: cloud},
+			connection: ConfigConnection{APIKey: "key"},
+			want: ConfigConnection{
+				CloudID:                cloud,
+				ElasticsearchEndpoints: []string{"https://es123.us-east-1.aws.found.io:443"},
+				KibanaEndpoints:        []string{"https://kb456.us-east-1.aws.found.io:443"},
+				APIKey:                 "key",
+			},
+		},
+		{
+			name:       "endpoints take precedence over the cloud ID",
+			connection: ConfigConnection{CloudID: cloud, KibanaEndpoints: []string{"https://kibana.example.com"}, ElasticsearchEndpoints: []string{""}},
+			want: ConfigConnection{
+				CloudID:                cloud,
+				ElasticsearchEndpoints: []string{"https://es123.us-east-1.aws.found.io:443"},
+				KibanaEndpoints:        []string{"https://kibana.example.com"},
+			},
+		},
+		{
+			name:       "invalid cloud ID",
+			connection: ConfigConnection{CloudID: "prod:nope!"},
+			err:        "invalid cloud_id",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearConnectionEnv(t)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			original := tt.connection
+			original.ElasticsearchEndpoints = slices.Clone(tt.connection.ElasticsearchEndpoints)
+
+			got, err := tt.connection.Resolve()
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("Resolve() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.CloudID != tt.want.CloudID || got.APIKey != tt.want.APIKey || got.Username != tt.want.Username || got.Password != tt.want.Password ||
+				!slices.Equal(got.ElasticsearchEndpoints, tt.want.ElasticsearchEndpoints) || !slices.Equal(got.KibanaEndpoints, tt.want.KibanaEndpoints) {
+				t.Errorf("Resolve() = %+v, want %+v", got, tt.want)
+			}
+
+			// The connection keeps its references so they are saved instead of the secrets
+			if !slices.Equal(tt.connection.ElasticsearchEndpoints, original.ElasticsearchEndpoints) || tt.connection.APIKey != original.APIKey {
+				t.Errorf("Resolve() changed the connection to %+v", tt.connection)
+			}
+		})
+	}
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	cloud := cloudID("prod", "us-east-1.aws.found.io$es123$kb456")
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		// want the connection saved on the first run and resolved
+		saved    ConfigConnection
+		resolved ConfigConnection
+	}{
+		{
+			name: "local cluster",
+			saved: ConfigConnection{
+				ElasticsearchEndpoints: []string{"http://localhost:9200"},
+				KibanaEndpoints:        []string{"http://localhost:5601"},
+				Username:               "elastic",
+				Password:               "changeme",
+			},
+		},
+		{
+			name:  "cloud ID and api key from the environment",
+			env:   map[string]string{EnvCloudID: cloud, EnvAPIKey: "env-key"},
+			saved: ConfigConnection{},
+			resolved: ConfigConnection{
+				CloudID:                cloud,
+				ElasticsearchEndpoints: []string{"https://es123.us-east-1.aws.found.io:443"},
+				KibanaEndpoints:        []string{"https://kb456.us-east-1.aws.found.io:443"},
+				APIKey:                 "env-key",
+			},
+		},
+		{
+			name: "credentials from the environment",
+			env:  map[string]string{EnvUsername: "env-user", EnvPassword: "env-pass"},
+			saved: ConfigConnection{
+				ElasticsearchEndpoints: []string{"http://localhost:9200"},
+				KibanaEndpoints:        []string{"http://localhost:5601"},
+			},
+			resolved: ConfigConnection{
+				ElasticsearchEndpoints: []string{"http://localhost:9200"},
+				KibanaEndpoints:        []string{"http://localhost:5601"},
+				Username:               "env-user",
+				Password:               "env-pass",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearConnectionEnv(t)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			configHome := t.TempDir()
+			t.Setenv("XDG_CONFIG_HOME", configHome)
+
+			cfg, _, err := LoadConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := os.Stat(filepath.Join(configHome, "elastic-data", "config.yaml")); err != nil {
+				t.Fatalf("config was not saved: %v", err)
+			}
+			saved := cfg.Connection
+			if saved.CloudID != tt.saved.CloudID || saved.APIKey != tt.saved.APIKey || saved.Username != tt.saved.Username || saved.Password != tt.saved.Password ||
+				!slices.Equal(saved.ElasticsearchEndpoints, tt.saved.ElasticsearchEndpoints) || !slices.Equal(saved.KibanaEndpoints, tt.saved.KibanaEndpoints) {
+				t.Errorf("saved connection %+v, want %+v", saved, tt.saved)
+			}
+
+			resolved, err := cfg.ActiveConnection()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.resolved.ElasticsearchEndpoints == nil {
+				tt.resolved = tt.saved
+			}
+			if resolved.APIKey != tt.resolved.APIKey || resolved.Username != tt.resolved.Username || resolved.Password != tt.resolved.Password ||
+				!slices.Equal(resolved.ElasticsearchEndpoints, tt.resolved.ElasticsearchEndpoints) || !slices.Equal(resolved.KibanaEndpoints, tt.resolved.KibanaEndpoints) {
+				t.Errorf("resolved connection %+v, want %+v", resolved, tt.resolved)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	// OutputElasticsearch indexes events with bulk requests
+	OutputElasticsearch = "elasticsearch"
+	// OutputFile writes events to a rotating file
+	OutputFile = "file"
+	// OutputStdout writes events to stdout for piping into other tools
+	OutputStdout = "stdout"
+	// OutputSyslog sends events to a syslog receiver such as an Elastic Agent or Logstash input
+	OutputSyslog = "syslog"
+
+	// FormatNDJSON writes every event as a JSON document on its own line
+	FormatNDJSON = "ndjson"
+	// FormatRaw writes the original log line of every event
+	FormatRaw = "raw"
+
+	// SyslogRFC5424 formats syslog messages as described in RFC 5424
+	SyslogRFC5424 = "rfc5424"
+	// SyslogRFC3164 formats syslog messages in the BSD format of RFC 3164
+	SyslogRFC3164 = "rfc3164"
+
+	defaultMaxFileSize = 100 * 1024 * 1024
+	defaultMaxFiles    = 5
+	defaultAppName     = "elastic-data"
+)
+
+var (
+	outputTypes     = []string{OutputElasticsearch, OutputFile, OutputStdout, OutputSyslog}
+	outputFormats   = []string{FormatNDJSON, FormatRaw}
+	syslogNetworks  = []string{"tcp", "udp"}
+	syslogProtocols = []string{SyslogRFC5424, SyslogRFC3164}
+)
+
+// OutputConfig controls where the events of a dataset are written
+type OutputConfig struct {
+	// Type elasticsearch, file, stdout or syslog, defaults to elasticsearch
+	Type string `yaml:"type,omitempty"`
+	// Format ndjson or raw for file and stdout outputs, defaults to ndjson
+	Format string `yaml:"format,omitempty"`
+	// Path file events are written to
+	Path string `yaml:"path,omitempty"`
+	// MaxSize size in bytes a file grows to before it is rotated
+	MaxSize int64 `yaml:"max_size,omitempty"`
+	// MaxFiles number of rotated files kept
+	MaxFiles int `yaml:"max_files,omitempty"`
+	// Network tcp or udp for the syslog output, defaults to tcp
+	Network string `yaml:"network,omitempty"`
+	// Address host:port of the syslog receiver
+	Address string `yaml:"address,omitempty"`
+	// Protocol rfc5424 or rfc3164, defaults to rfc5424
+	Protocol string `yaml:"protocol,omitempty"`
+	// AppName app name or tag of syslog messages
+	AppName string `yaml:"app_name,omitempty"`
+}
+
+// WithDefaults returns the output config with unset values replaced by their defaults
+func (o OutputConfig) WithDefaults() OutputConfig {
+	if o.Type == "" {
+		o.Type = OutputElasticsearch
+	}
+	if o.Format == "" {
+		o.Format = FormatNDJSON
+	}
+	if o.MaxSize == 0 {
+		o.MaxSize = defaultMaxFileSize
+	}
+	if o.MaxFiles == 0 {
+		o.MaxFiles = defaultMaxFiles
+	}
+	if o.Network == "" {
+		o.Network = "tcp"
+	}
+	if o.Protocol == "" {
+		o.Protocol = SyslogRFC5424
+	}
+	if o.AppName == "" {
+		o.AppName = defaultAppName
+	}
+	return o
+}
+
+func validateOutput(output OutputConfig) error {
+	output = output.WithDefaults()
+
+	if !slices.Contains(outputTypes, output.Type) {
+		return fmt.Errorf("invalid output type %s. Valid types are %s", output.Type, strings.Join(outputTypes, ", "))
+	}
+
+	if !slices.Contains(outputFormats, output.Format) {
+		return fmt.Errorf("invalid output format %s. Valid formats are %s", output.Format, strings.Join(outputFormats, ", "))
+	}
+
+	if output.MaxSize < 0 || output.MaxFiles < 0 {
+		return fmt.Errorf("max_size and max_files cannot be negative")
+	}
+
+	switch output.Type {
+	case OutputFile:
+		if output.Path == "" {
+			return fmt.Errorf("path is required for the file output")
+		}
+	case OutputSyslog:
+		if output.Address == "" {
+			return fmt.Errorf("address is required for the syslog output")
+		}
+		if !slices.Contains(syslogNetworks, output.Network) {
+			return fmt.Errorf("invalid syslog network %s. Valid networks are %s", output.Network, strings.Join(syslogNetworks, ", "))
+		}
+		if !slices.Contains(syslogProtocols, output.Protocol) {
+			return fmt.Errorf("invalid syslog protocol %s. Valid protocols are %s", output.Protocol, strings.Join(syslogProtocols, ", "))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// RateConstant keeps the rate at the threshold
+	RateConstant = "constant"
+	// RateRamp raises the rate linearly from min to the threshold, then holds it
+	RateRamp = "ramp"
+	// RateSine swings the rate between min and the threshold every period
+	RateSine = "sine"
+	// RateDiurnal follows the time of day, peaking at the threshold at peak_hour and
+	// dropping to min twelve hours later
+	RateDiurnal = "diurnal"
+	// RateSteps switches between rates at offsets from the start
+	RateSteps = "steps"
+
+	defaultRatePeriod      = time.Hour
+	defaultRatePeakHour    = 14
+	defaultBurstDuration   = 30 * time.Second
+	defaultBurstMultiplier = 5
+)
+
+var rateShapes = []string{RateConstant, RateRamp, RateSine, RateDiurnal, RateSteps}
+
+// RateProfile varies the events per second of a dataset over time. The threshold of
+// the dataset is the highest rate of the ramp, sine and diurnal shapes.
+type RateProfile struct {
+	// Shape constant, ramp, sine, diurnal or steps, defaults to constant
+	Shape string `yaml:"shape,omitempty"`
+	// Min lowest rate of the ramp, sine and diurnal shapes
+	Min int `yaml:"min,omitempty"`
+	// Duration time the ramp takes to reach the threshold
+	Duration time.Duration `yaml:"duration,omitempty"`
+	// Period time of a full sine wave or of the step schedule before it repeats.
+	// Defaults to 1h for the sine shape, steps do not repeat without it.
+	Period time.Duration `yaml:"period,omitempty"`
+	// PeakHour hour of the day between 0 and 23 the diurnal shape peaks at, defaults to 14
+	PeakHour *int `yaml:"peak_hour,omitempty"`
+	// Steps rates of the steps shape, the threshold is used before the first step
+	Steps []RateStep `yaml:"steps,omitempty"`
+	// Bursts multiply the rate for short periods at random times
+	Bursts *RateBursts `yaml:"bursts,omitempty"`
+}
+
+// RateStep is the rate used from an offset since generating started
+type RateStep struct {
+	After time.Duration `yaml:"after"`
+	Rate  int           `yaml:"rate"`
+}
+
+// RateBursts are storms of events on top of the shape of a rate profile
+type RateBursts struct {
+	// Every average time between the start of two bursts
+	Every time.Duration `yaml:"every"`
+	// Duration of a burst, defaults to 30s
+	Duration time.Duration `yaml:"duration,omitempty"`
+	// Multiplier applied to the rate during a burst, defaults to 5
+	Multiplier float64 `yaml:"multiplier,omitempty"`
+}
+
+// WithDefaults returns the rate profile with unset values replaced by their defaults
+func (r RateProfile) WithDefaults() RateProfile {
+	if r.Shape == "" {
+		r.Shape = RateConstant
+	}
+	if r.Period == 0 && r.Shape == RateSine {
+		r.Period = defaultRatePeriod
+	}
+	if r.PeakHour == nil {
+		peakHour := defaultRatePeakHour
+		r.PeakHour = &peakHour
+	}
+	if r.Bursts != nil {
+		bursts := *r.Bursts
+		if bursts.Duration == 0 {
+			bursts.Duration = defaultBurstDuration
+		}
+		if bursts.Multiplier == 0 {
+			bursts.Multiplier = defaultBurstMultiplier
+		}
+		r.Bursts = &bursts
+	}
+	return r
+}
+
+// IsConstant reports whether the profile keeps the rate at the threshold
+func (r RateProfile) IsConstant() bool {
+	return (r.Shape == "" || r.Shape == RateConstant) && r.Bursts == nil
+}
+
+func validateRate(rate RateProfile, threshold int) error {
+	rate = rate.WithDefaults()
+
+	if !slices.Contains(rateShapes, rate.Shape) {
+		return fmt.Errorf("invalid shape %s. Valid shapes are %s", rate.Shape, strings.Join(rateShapes, ", "))
+	}
+
+	if rate.Min < 0 || rate.Min > threshold {
+		return fmt.Errorf("min must be between 0 and the threshold %d", threshold)
+	}
+
+	if rate.Duration < 0 || rate.Period < 0 {
+		return fmt.Errorf("duration and period cannot be negative")
+	}
+
+	if *rate.PeakHour < 0 || *rate.PeakHour > 23 {
+		return fmt.Errorf("peak_hour must be between 0 and 23")
+	}
+
+	switch rate.Shape {
+	case RateRamp:
+		if rate.Duration == 0 {
+			return fmt.Errorf("duration is required for the ramp shape")
+		}
+	case RateSteps:
+		if len(rate.Steps) == 0 {
+			return fmt.Errorf("steps are required for the steps shape")
+		}
+		for i, step := range rate.Steps {
+			if step.Rate < 0 {
+				return fmt.Errorf("rate of step %d cannot be negative", i+1)
+			}
+			if step.After < 0 || (i > 0 && step.After <= rate.Steps[i-1].After) {
+				return fmt.Errorf("after of step %d must be later than the previous step", i+1)
+			}
+		}
+	}
+
+	if rate.Bursts != nil {
+		if rate.Bursts.Every <= 0 {
+			return fmt.Errorf("every is required for bursts")
+		}
+		if rate.Bursts.Duration < 0 || rate.Bursts.Multiplier < 0 {
+			return fmt.Errorf("duration and multiplier of bursts cannot be negative")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateRate(t *testing.T) {
+	hour := func(h int) *int { return &h }
+
+	tests := []struct {
+		name string
+		rate RateProfile
+		err  string
+	}{
+		{name: "defaults", rate: RateProfile{}},
+		{name: "peak at midnight", rate: RateProfile{Shape: RateDiurnal, PeakHour: hour(0)}},
+		{name: "peak at 23", rate: RateProfile{Shape: RateDiurnal, PeakHour: hour(23)}},
+		{name: "peak at 24", rate: RateProfile{Shape: RateDiurnal, PeakHour: hour(24)}, err: "peak_hour must be between 0 and 23"},
+		{name: "negative peak", rate: RateProfile{Shape: RateDiurnal, PeakHour: hour(-1)}, err: "peak_hour must be between 0 and 23"},
+		{name: "unknown shape", rate: RateProfile{Shape: "square"}, err: "invalid shape square"},
+		{name: "min above threshold", rate: RateProfile{Shape: RateSine, Min: 200}, err: "min must be between 0 and the threshold 100"},
+		{name: "ramp without duration", rate: RateProfile{Shape: RateRamp}, err: "duration is required"},
+		{name: "steps out of order", rate: RateProfile{Shape: RateSteps, Steps: []RateStep{{After: time.Minute}, {After: time.Second}}}, err: "after of step 2"},
+		{name: "bursts without every", rate: RateProfile{Bursts: &RateBursts{}}, err: "every is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRate(tt.rate, 100)
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("validateRate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("validateRate() error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestRateProfileDefaultPeakHour(t *testing.T) {
+	if peakHour := (RateProfile{}).WithDefaults().PeakHour; peakHour == nil || *peakHour != defaultRatePeakHour {
+		t.Errorf("default peak hour %v, want %d", peakHour, defaultRatePeakHour)
+	}
+
+	midnight := 0
+	if peakHour := (RateProfile{PeakHour: &midnight}).WithDefaults().PeakHour; *peakHour != 0 {
+		t.Errorf("peak hour 0 replaced by %d", *peakHour)
+	}
+}
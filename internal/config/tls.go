@@ -0,0 +1,43 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/log"
+)
+
+// TLSConfig builds the TLS configuration shared by the Elasticsearch and Kibana clients
+// from the certificate authority, client certificate and unsafe settings
+func (c *ConfigConnection) TLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: c.Unsafe,
+	}
+
+	if c.CACert != "" {
+		caCert, err := os.ReadFile(c.CACert)
+		if err != nil {
+			log.Debug(err)
+			return nil, fmt.Errorf("error reading certificate authority %s: %w", c.CACert, err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no valid certificates found in certificate authority %s", c.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if c.Cert != "" && c.Key != "" {
+		cert, err := tls.LoadX509KeyPair(c.Cert, c.Key)
+		if err != nil {
+			log.Debug(err)
+			return nil, fmt.Errorf("error loading client certificate %s and key %s: %w", c.Cert, c.Key, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,104 @@
+package elasticsearch
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/expandwildcard"
+)
+
+// DataStreamStats holds the number of documents and the size of a data stream
+type DataStreamStats struct {
+	Name      string
+	Documents int64
+	SizeBytes int64
+}
+
+// Size returns the size of the data stream in a human readable unit
+func (s DataStreamStats) Size() string {
+	size := float64(s.SizeBytes)
+
+	for _, unit := range []string{"b", "KB", "MB", "GB", "TB", "PB"} {
+		if size < 1024.0 {
+			return fmt.Sprintf("%.1f%s", size, unit)
+		}
+		size /= 1024.0
+	}
+
+	return fmt.Sprintf("%.1fEB", size)
+}
+
+// DeleteIntegrationDataStreams deletes the data streams of an integration in the given
+// namespaces, of any type, along with the events they hold
+func (c *Config) DeleteIntegrationDataStreams(integration string, namespaces []string) error {
+	if len(namespaces) == 0 {
+		return nil
+	}
+
+	patterns := make([]string, len(namespaces))
+	for i, namespace := range namespaces {
+		patterns[i] = fmt.Sprintf("*-%s.*-%s", integration, namespace)
+	}
+	pattern := strings.Join(patterns, ",")
+
+	_, err := c.Client.Indices.DeleteDataStream(pattern).ExpandWildcards(expandwildcard.All).Do(c.Ctx)
+	if err != nil {
+		log.Debug(err)
+		return fmt.Errorf("failed to delete data streams %s: %w", pattern, err)
+	}
+
+	return nil
+}
+
+// DataStreamStats returns the stats of the named data streams that still exist, in the order given
+func (c *Config) DataStreamStats(names []string) ([]DataStreamStats, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	// Stats of every data stream are requested as naming one that no longer exists fails the request
+	resp, err := c.Client.Indices.DataStreamsStats().ExpandWildcards(expandwildcard.All).Do(c.Ctx)
+	if err != nil {
+		log.Debug(err)
+		return nil, fmt.Errorf("failed to get data stream stats: %w", err)
+	}
+
+	sizes := make(map[string]int64, len(resp.DataStreams))
+	for _, item := range resp.DataStreams {
+		sizes[item.DataStream] = item.StoreSizeBytes
+	}
+
+	var stats []DataStreamStats
+	for _, name := range names {
+		size, exists := sizes[name]
+		if !exists {
+			continue
+		}
+
+		count, err := c.Client.Core.Count().Index(name).Do(c.Ctx)
+		if err != nil {
+			log.Debug(err)
+			return nil, fmt.Errorf("failed to count documents in %s: %w", name, err)
+		}
+
+		stats = append(stats, DataStreamStats{Name: name, Documents: count.Count, SizeBytes: size})
+	}
+
+	return stats, nil
+}
+
+// DeleteDataStreams deletes the named data streams along with the events they hold
+func (c *Config) DeleteDataStreams(names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	_, err := c.Client.Indices.DeleteDataStream(strings.Join(names, ",")).Do(c.Ctx)
+	if err != nil {
+		log.Debug(err)
+		return fmt.Errorf("failed to delete data streams %s: %w", strings.Join(names, ", "), err)
+	}
+
+	return nil
+}
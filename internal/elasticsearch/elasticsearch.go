@@ -1,25 +1,39 @@
 package elasticsearch
 
 import (
+	"bytes"
 	"context"
-	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/bulk"
 	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
 	"github.com/tehbooom/elastic-data/internal/config"
 )
 
+// createAction action line of every document, data streams only accept create
+const createAction = `{"create":{}}` + "\n"
+
+// bulkBuffers bodies of bulk requests reused once a request is done
+var bulkBuffers = sync.Pool{
+	New: func() any {
+		return new(bytes.Buffer)
+	},
+}
+
 type Config struct {
 	Client    *elasticsearch.TypedClient
 	Ctx       context.Context
 	Version   string
 	Connected bool
+	Retry     config.RetryConfig
 }
 
 func (c *Config) TestConnection() error {
@@ -56,20 +70,14 @@ func detectElasticsearchVersion(cfg config.ConfigConnection) (string, error) {
 		tempConfig.Password = cfg.Password
 	}
 
-	if cfg.Unsafe {
-		tempConfig.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
-			},
-		}
+	tlsConfig, err := cfg.TLSConfig()
+	if err != nil {
+		return "", err
 	}
 
-	if cfg.CACert != "" {
-		cert, err := os.ReadFile(cfg.CACert)
-		if err != nil {
-			return "", err
-		}
-		tempConfig.CACert = cert
+	tempConfig.Transport = &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: tlsConfig,
 	}
 
 	tempClient, err := elasticsearch.NewTypedClient(tempConfig)
@@ -88,6 +96,8 @@ func detectElasticsearchVersion(cfg config.ConfigConnection) (string, error) {
 func SetClient(cfg config.ConfigConnection) (*elasticsearch.TypedClient, error) {
 	esConfig := elasticsearch.Config{
 		Addresses: cfg.ElasticsearchEndpoints,
+		// Bulk requests are retried by BulkDocuments with the configured backoff
+		DisableRetry: true,
 	}
 
 	version, err := detectElasticsearchVersion(cfg)
@@ -112,6 +122,11 @@ func SetClient(cfg config.ConfigConnection) (*elasticsearch.TypedClient, error)
 		esConfig.Password = cfg.Password
 	}
 
+	tlsConfig, err := cfg.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
 	// Configure HTTP transport with optimized settings for high throughput
 	transport := &http.Transport{
 		MaxIdleConns:        100,              // Increased connection pool
@@ -119,25 +134,11 @@ func SetClient(cfg config.ConfigConnection) (*elasticsearch.TypedClient, error)
 		IdleConnTimeout:     90 * time.Second, // Keep connections alive longer
 		DisableCompression:  false,            // Enable compression
 		DisableKeepAlives:   false,            // Enable keep-alives
-	}
-
-	if cfg.Unsafe {
-		transport.TLSClientConfig = &tls.Config{
-			InsecureSkipVerify: true,
-		}
+		TLSClientConfig:     tlsConfig,
 	}
 
 	esConfig.Transport = transport
 
-	if cfg.CACert != "" {
-		cert, err := os.ReadFile(cfg.CACert)
-		if err != nil {
-			log.Debug(err)
-			return nil, fmt.Errorf("error reading certificate authority %s: %w", cfg.CACert, err)
-		}
-		esConfig.CACert = cert
-	}
-
 	es, err := elasticsearch.NewTypedClient(esConfig)
 	if err != nil {
 		log.Debug(err)
@@ -147,49 +148,183 @@ func SetClient(cfg config.ConfigConnection) (*elasticsearch.TypedClient, error)
 	return es, nil
 }
 
-func (c *Config) BulkRequest(index string, events []map[string]interface{}) (time.Duration, error) {
-	if len(events) == 0 {
-		return 0, nil
+// BulkResult is the outcome of a bulk request after retries
+type BulkResult struct {
+	// Indexed number of documents indexed
+	Indexed int
+	// IndexedBytes size of the documents indexed
+	IndexedBytes int
+	// Failed number of documents that were not indexed
+	Failed int
+	// Failures failed documents grouped by error type
+	Failures map[string]*BulkFailure
+	// Rejections documents rejected with an error that is not retried
+	Rejections []BulkRejection
+	// Retries number of times documents were resent
+	Retries int
+	// Duration time spent waiting on Elasticsearch, excluding backoff
+	Duration time.Duration
+}
+
+// BulkFailure counts the documents that failed with the same error type
+type BulkFailure struct {
+	Count int
+	// Reason the reason of the first failure of this type
+	Reason string
+}
+
+// BulkRejection is a document Elasticsearch rejected, such as for a mapping conflict
+type BulkRejection struct {
+	// Position index of the document in the events of the request
+	Position int
+	Reason   string
+}
+
+func (r *BulkResult) addFailure(errorType, reason string, count int) {
+	if r.Failures == nil {
+		r.Failures = make(map[string]*BulkFailure)
+	}
+
+	failure, exists := r.Failures[errorType]
+	if !exists {
+		failure = &BulkFailure{Reason: reason}
+		r.Failures[errorType] = failure
 	}
 
-	bulk := c.Client.Bulk().Index(index)
+	failure.Count += count
+	r.Failed += count
+}
 
-	// Batch add operations for better performance
-	for _, event := range events {
-		err := bulk.CreateOp(types.CreateOperation{}, event)
+// isRetryableStatus reports whether a request or document rejected with status should be retried
+func isRetryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= http.StatusInternalServerError
+}
+
+// BulkRequest indexes events into index, see BulkDocuments
+func (c *Config) BulkRequest(index string, events []map[string]interface{}) (BulkResult, error) {
+	documents := make([][]byte, len(events))
+	for i, event := range events {
+		document, err := json.Marshal(event)
 		if err != nil {
 			log.Debug(err)
-			return 0, err
+			return BulkResult{}, fmt.Errorf("failed to marshal event: %w", err)
 		}
+		documents[i] = document
 	}
 
-	start := time.Now()
-	resp, err := bulk.Do(c.Ctx)
-	if err != nil {
-		return 0, err
+	return c.BulkDocuments(index, documents)
+}
+
+// BulkDocuments indexes JSON documents into index. Documents rejected with a retryable
+// status are resent with exponential backoff according to the retry config. An error
+// is only returned when the request itself fails, per document failures are reported
+// in the result.
+func (c *Config) BulkDocuments(index string, documents [][]byte) (BulkResult, error) {
+	var result BulkResult
+
+	if len(documents) == 0 {
+		return result, nil
+	}
+
+	retry := c.Retry.WithDefaults()
+	pending := documents
+	// positions of the pending documents in documents
+	positions := make([]int, len(documents))
+	for i := range positions {
+		positions[i] = i
 	}
-	duration := time.Since(start)
 
-	// Only log first few errors to avoid performance impact
-	if resp.Errors {
-		errorCount := 0
-		for _, item := range resp.Items {
-			if errorCount >= 5 {
-				log.Printf("... and more errors (suppressed for performance)")
-				break
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			result.Retries++
+			select {
+			case <-c.Ctx.Done():
+				result.addFailure("context_canceled", c.Ctx.Err().Error(), len(pending))
+				return result, c.Ctx.Err()
+			case <-time.After(retry.Backoff(attempt)):
 			}
-			for opType, respItem := range item {
-				if respItem.Error != nil {
-					log.Printf("Error in %s operation for document %s: %s",
-						opType, *respItem.Id_, *respItem.Error.Reason)
-					errorCount++
-					if errorCount >= 5 {
-						break
+		}
+
+		start := time.Now()
+		resp, err := c.doBulk(index, pending)
+		result.Duration += time.Since(start)
+
+		if err != nil {
+			var esErr *types.ElasticsearchError
+			if errors.As(err, &esErr) {
+				if isRetryableStatus(esErr.Status) && attempt < retry.MaxRetries {
+					log.Debug(fmt.Sprintf("Bulk request to %s rejected with status %d, retrying", index, esErr.Status))
+					continue
+				}
+				result.addFailure(esErr.ErrorCause.Type, err.Error(), len(pending))
+			} else {
+				result.addFailure("request_failed", err.Error(), len(pending))
+			}
+			log.Debug(err)
+			return result, err
+		}
+
+		var retryDocuments [][]byte
+		var retryPositions []int
+		for i, item := range resp.Items {
+			for _, respItem := range item {
+				if respItem.Error == nil {
+					result.Indexed++
+					if i < len(pending) {
+						result.IndexedBytes += len(pending[i])
 					}
+					continue
+				}
+
+				if isRetryableStatus(respItem.Status) && attempt < retry.MaxRetries && i < len(pending) {
+					retryDocuments = append(retryDocuments, pending[i])
+					retryPositions = append(retryPositions, positions[i])
+					continue
+				}
+
+				reason := ""
+				if respItem.Error.Reason != nil {
+					reason = *respItem.Error.Reason
+				}
+				result.addFailure(respItem.Error.Type, reason, 1)
+
+				if !isRetryableStatus(respItem.Status) && i < len(pending) {
+					result.Rejections = append(result.Rejections, BulkRejection{
+						Position: positions[i],
+						Reason:   fmt.Sprintf("%s: %s", respItem.Error.Type, reason),
+					})
 				}
 			}
 		}
+
+		if len(retryDocuments) == 0 {
+			break
+		}
+
+		log.Debug(fmt.Sprintf("Retrying %d documents rejected by %s", len(retryDocuments), index))
+		pending = retryDocuments
+		positions = retryPositions
+	}
+
+	for errorType, failure := range result.Failures {
+		log.Debug(fmt.Sprintf("%d documents failed for %s with %s: %s", failure.Count, index, errorType, failure.Reason))
+	}
+
+	return result, nil
+}
+
+// doBulk sends documents in a single bulk request, writing the action line and the
+// bytes of every document straight into a pooled buffer
+func (c *Config) doBulk(index string, documents [][]byte) (*bulk.Response, error) {
+	buffer := bulkBuffers.Get().(*bytes.Buffer)
+	defer bulkBuffers.Put(buffer)
+
+	buffer.Reset()
+	for _, document := range documents {
+		buffer.WriteString(createAction)
+		buffer.Write(document)
+		buffer.WriteByte('\n')
 	}
 
-	return duration, nil
+	return c.Client.Bulk().Index(index).Raw(bytes.NewReader(buffer.Bytes())).Do(c.Ctx)
 }
@@ -0,0 +1,366 @@
+package elasticsearch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/tehbooom/elastic-data/internal/config"
+)
+
+// fakeBulk answers bulk requests with the item statuses returned by respond for the
+// documents of each request, recording the documents sent
+type fakeBulk struct {
+	// respond returns the status of every document of a request by attempt
+	respond func(attempt int, documents []string) []int
+	// status of the whole request, used instead of item statuses when set
+	status   func(attempt int) int
+	requests [][]string
+}
+
+func (f *fakeBulk) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var documents []string
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for line := 0; scanner.Scan(); line++ {
+		// Every other line is the action line
+		if line%2 == 1 {
+			documents = append(documents, scanner.Text())
+		}
+	}
+
+	attempt := len(f.requests)
+	f.requests = append(f.requests, documents)
+
+	header := http.Header{}
+	header.Set("X-Elastic-Product", "Elasticsearch")
+	header.Set("Content-Type", "application/json")
+
+	if f.status != nil {
+		if status := f.status(attempt); status != http.StatusOK {
+			response := fmt.Sprintf(`{"error":{"type":"es_rejected_execution_exception","reason":"busy"},"status":%d}`, status)
+			return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader(response))}, nil
+		}
+	}
+
+	var items []string
+	for _, status := range f.respond(attempt, documents) {
+		switch {
+		case status < 300:
+			items = append(items, fmt.Sprintf(`{"create":{"_index":"logs","status":%d}}`, status))
+		case status == http.StatusTooManyRequests:
+			items = append(items, fmt.Sprintf(`{"create":{"_index":"logs","status":%d,"error":{"type":"es_rejected_execution_exception","reason":"busy"}}}`, status))
+		default:
+			items = append(items, fmt.Sprintf(`{"create":{"_index":"logs","status":%d,"error":{"type":"mapper_parsing_exception","reason":"bad"}}}`, status))
+		}
+	}
+
+	response := fmt.Sprintf(`{"took":1,"errors":true,"items":[%s]}`, strings.Join(items, ","))
+	return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(strings.NewReader(response))}, nil
+}
+
+func newFakeConfig(t *testing.T, transport http.RoundTripper, maxRetries int) *Config {
+	t.Helper()
+
+	client, err := elasticsearch.NewTypedClient(elasticsearch.Config{
+		Addresses:    []string{"http://localhost:9200"},
+		Transport:    transport,
+		DisableRetry: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Config{
+		Client: client,
+		Ctx:    context.Background(),
+		Retry: config.RetryConfig{
+			MaxRetries:     maxRetries,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     time.Millisecond,
+		},
+	}
+}
+
+// statuses answers every document with the status set for its name
+func statuses(byAttempt ...map[string]int) func(int, []string) []int {
+	return func(attempt int, documents []string) []int {
+		result := make([]int, len(documents))
+		for i, document := range documents {
+			var event map[string]string
+			json.Unmarshal([]byte(document), &event)
+			result[i] = http.StatusCreated
+			if status, ok := byAttempt[min(attempt, len(byAttempt)-1)][event["name"]]; ok {
+				result[i] = status
+			}
+		}
+		return result
+	}
+}
+
+func documents(names ...string) [][]byte {
+	result := make([][]byte, len(names))
+	for i, name := range names {
+		result[i] = []byte(fmt.Sprintf(`{"name":%q}`, name))
+	}
+	return result
+}
+
+func TestBulkDocuments(t *testing.T) {
+	tests := []struct {
+		name       string
+		documents  [][]byte
+		maxRetries int
+		bulk       *fakeBulk
+		indexed    int
+		failed     int
+		retries    int
+		rejections []int
+		// resent documents of every request after the first
+		resent [][]string
+		err    bool
+	}{
+		{
+			name:      "all indexed",
+			documents: documents("a", "b", "c"),
+			bulk:      &fakeBulk{respond: statuses(map[string]int{})},
+			indexed:   3,
+		},
+		{
+			name:       "only rejected items are resent",
+			documents:  documents("a", "b", "c"),
+			maxRetries: 3,
+			bulk:       &fakeBulk{respond: statuses(map[string]int{"b": 429, "c": 400}, map[string]int{})},
+			indexed:    2,
+			failed:     1,
+			retries:    1,
+			rejections: []int{2},
+			resent:     [][]string{{`{"name":"b"}`}},
+		},
+		{
+			name:       "positions map back to the original documents",
+			documents:  documents("a", "b", "c", "d"),
+			maxRetries: 3,
+			bulk: &fakeBulk{respond: statuses(
+				map[string]int{"a": 429, "c": 429},
+				map[string]int{"c": 400},
+			)},
+			indexed:    3,
+			failed:     1,
+			retries:    1,
+			rejections: []int{2},
+			resent:     [][]string{{`{"name":"a"}`, `{"name":"c"}`}},
+		},
+		{
+			name:       "partial failures are retried until they succeed",
+			documents:  documents("a", "b", "c"),
+			maxRetries: 3,
+			bulk: &fakeBulk{respond: statuses(
+				map[string]int{"a": 429, "b": 429},
+				map[string]int{"b": 429},
+				map[string]int{},
+			)},
+			indexed: 3,
+			retries: 2,
+			resent:  [][]string{{`{"name":"a"}`, `{"name":"b"}`}, {`{"name":"b"}`}},
+		},
+		{
+			name:       "retries run out",
+			documents:  documents("a", "b"),
+			maxRetries: 2,
+			bulk:       &fakeBulk{respond: statuses(map[string]int{"a": 429})},
+			indexed:    1,
+			failed:     1,
+			retries:    2,
+			resent:     [][]string{{`{"name":"a"}`}, {`{"name":"a"}`}},
+		},
+		{
+			name:       "retries disabled",
+			documents:  documents("a", "b"),
+			maxRetries: -1,
+			bulk:       &fakeBulk{respond: statuses(map[string]int{"a": 429})},
+			indexed:    1,
+			failed:     1,
+		},
+		{
+			name:       "rejected request is resent whole",
+			documents:  documents("a", "b"),
+			maxRetries: 3,
+			bulk: &fakeBulk{
+				respond: statuses(map[string]int{}),
+				status: func(attempt int) int {
+					if attempt == 0 {
+						return http.StatusTooManyRequests
+					}
+					return http.StatusOK
+				},
+			},
+			indexed: 2,
+			retries: 1,
+			resent:  [][]string{{`{"name":"a"}`, `{"name":"b"}`}},
+		},
+		{
+			name:       "request failure",
+			documents:  documents("a", "b"),
+			maxRetries: -1,
+			bulk: &fakeBulk{
+				respond: statuses(map[string]int{}),
+				status:  func(int) int { return http.StatusBadRequest },
+			},
+			failed: 2,
+			err:    true,
+		},
+		{
+			name:       "request failure keeps documents indexed before",
+			documents:  documents("a", "b", "c"),
+			maxRetries: 3,
+			bulk: &fakeBulk{
+				respond: statuses(map[string]int{"b": 429}),
+				status: func(attempt int) int {
+					if attempt == 0 {
+						return http.StatusOK
+					}
+					return http.StatusBadRequest
+				},
+			},
+			indexed: 2,
+			failed:  1,
+			retries: 1,
+			resent:  [][]string{{`{"name":"b"}`}},
+			err:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeConfig(t, tt.bulk, tt.maxRetries)
+
+			result, err := c.BulkDocuments("logs", tt.documents)
+			if (err != nil) != tt.err {
+				t.Fatalf("BulkDocuments() error = %v, want error %v", err, tt.err)
+			}
+
+			if result.Indexed != tt.indexed || result.Failed != tt.failed || result.Retries != tt.retries {
+				t.Errorf("indexed %d, failed %d, retries %d, want %d, %d, %d",
+					result.Indexed, result.Failed, result.Retries, tt.indexed, tt.failed, tt.retries)
+			}
+
+			// Every document is 12 bytes long
+			if result.IndexedBytes != 12*tt.indexed {
+				t.Errorf("indexed %d bytes, want %d", result.IndexedBytes, 12*tt.indexed)
+			}
+
+			var positions []int
+			for _, rejection := range result.Rejections {
+				positions = append(positions, rejection.Position)
+			}
+			if fmt.Sprint(positions) != fmt.Sprint(tt.rejections) {
+				t.Errorf("rejected positions %v, want %v", positions, tt.rejections)
+			}
+
+			if fmt.Sprint(tt.bulk.requests[1:]) != fmt.Sprint(tt.resent) {
+				t.Errorf("resent %v, want %v", tt.bulk.requests[1:], tt.resent)
+			}
+		})
+	}
+}
+
+func TestSetClientDisablesRetry(t *testing.T) {
+	var bulkRequests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		if !strings.HasSuffix(r.URL.Path, "/_bulk") {
+			fmt.Fprint(w, `{"version":{"number":"8.17.0"}}`)
+			return
+		}
+		bulkRequests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"error":{"type":"unavailable","reason":"busy"},"status":503}`)
+	}))
+	defer server.Close()
+
+	client, err := SetClient(config.ConfigConnection{ElasticsearchEndpoints: []string{server.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Config{Client: client, Ctx: context.Background(), Retry: config.RetryConfig{MaxRetries: -1}}
+	if _, err := c.BulkDocuments("logs", documents("a")); err == nil {
+		t.Fatal("BulkDocuments() succeeded against an unavailable cluster")
+	}
+
+	// Only BulkDocuments retries, the client sends every request once
+	if bulkRequests != 1 {
+		t.Errorf("sent %d bulk requests, want 1", bulkRequests)
+	}
+}
+
+func TestBulkRequest(t *testing.T) {
+	bulk := &fakeBulk{respond: statuses(map[string]int{})}
+	c := newFakeConfig(t, bulk, 0)
+
+	result, err := c.BulkRequest("logs", []map[string]interface{}{{"name": "a"}, {"name": "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Indexed != 2 {
+		t.Errorf("indexed %d, want 2", result.Indexed)
+	}
+	if fmt.Sprint(bulk.requests) != fmt.Sprint([][]string{{`{"name":"a"}`, `{"name":"b"}`}}) {
+		t.Errorf("sent %v", bulk.requests)
+	}
+}
+
+// recordPath answers every request with an acknowledgement, recording the request paths
+type recordPath struct {
+	paths []string
+}
+
+func (r *recordPath) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.paths = append(r.paths, req.URL.Path)
+
+	header := http.Header{}
+	header.Set("X-Elastic-Product", "Elasticsearch")
+	header.Set("Content-Type", "application/json")
+	return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(strings.NewReader(`{"acknowledged":true}`))}, nil
+}
+
+func TestDeleteIntegrationDataStreams(t *testing.T) {
+	tests := []struct {
+		name       string
+		namespaces []string
+		paths      []string
+	}{
+		{"no namespaces", nil, nil},
+		{"namespaces", []string{"default", "prod"}, []string{"/_data_stream/*-acme.*-default,*-acme.*-prod"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &recordPath{}
+			c := newFakeConfig(t, transport, 0)
+
+			if err := c.DeleteIntegrationDataStreams("acme", tt.namespaces); err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(transport.paths) != fmt.Sprint(tt.paths) {
+				t.Errorf("requested %v, want %v", transport.paths, tt.paths)
+			}
+		})
+	}
+}
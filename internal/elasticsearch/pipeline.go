@@ -0,0 +1,105 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/charmbracelet/log"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+)
+
+// DefaultPipeline returns the default ingest pipeline set by an index template,
+// which for an installed package is the pipeline of its installed version
+func (c *Config) DefaultPipeline(indexTemplate string) (string, error) {
+	resp, err := c.Client.Indices.GetIndexTemplate().Name(indexTemplate).Do(c.Ctx)
+	if err != nil {
+		log.Debug(err)
+		return "", fmt.Errorf("failed to get index template %s: %w", indexTemplate, err)
+	}
+
+	for _, item := range resp.IndexTemplates {
+		if item.IndexTemplate.Template == nil || item.IndexTemplate.Template.Settings == nil {
+			continue
+		}
+
+		settings := item.IndexTemplate.Template.Settings
+		if settings.DefaultPipeline != nil {
+			return *settings.DefaultPipeline, nil
+		}
+		if settings.Index != nil && settings.Index.DefaultPipeline != nil {
+			return *settings.Index.DefaultPipeline, nil
+		}
+	}
+
+	return "", fmt.Errorf("index template %s has no default pipeline", indexTemplate)
+}
+
+// SimulatePipeline runs events through pipeline without indexing them. It returns the
+// error of every event in order, empty when the pipeline processed the event. Events
+// handled by an on_failure processor count as failed with their error.message.
+func (c *Config) SimulatePipeline(pipeline, index string, events []map[string]interface{}) ([]string, error) {
+	docs := make([]types.Document, 0, len(events))
+	for _, event := range events {
+		source, err := json.Marshal(event)
+		if err != nil {
+			log.Debug(err)
+			return nil, fmt.Errorf("failed to marshal event: %w", err)
+		}
+		docs = append(docs, types.Document{Index_: &index, Source_: source})
+	}
+
+	resp, err := c.Client.Ingest.Simulate().Id(pipeline).Docs(docs...).Do(c.Ctx)
+	if err != nil {
+		log.Debug(err)
+		return nil, fmt.Errorf("failed to simulate pipeline %s: %w", pipeline, err)
+	}
+
+	errs := make([]string, len(events))
+	for i, result := range resp.Docs {
+		if i >= len(errs) {
+			break
+		}
+
+		if result.Error != nil {
+			errs[i] = result.Error.Type
+			if result.Error.Reason != nil {
+				errs[i] = fmt.Sprintf("%s: %s", result.Error.Type, *result.Error.Reason)
+			}
+			continue
+		}
+
+		// An error field of the event itself is not a pipeline failure
+		if _, hasError := events[i]["error"]; result.Doc != nil && !hasError {
+			errs[i] = pipelineErrorMessage(result.Doc.Source_)
+		}
+	}
+
+	return errs, nil
+}
+
+// pipelineErrorMessage returns the error.message set by an on_failure processor
+func pipelineErrorMessage(source map[string]json.RawMessage) string {
+	raw, exists := source["error"]
+	if !exists {
+		return ""
+	}
+
+	var pipelineError struct {
+		Message interface{} `json:"message"`
+	}
+	if err := json.Unmarshal(raw, &pipelineError); err != nil || pipelineError.Message == nil {
+		return ""
+	}
+
+	switch message := pipelineError.Message.(type) {
+	case string:
+		return message
+	case []interface{}:
+		if len(message) > 0 {
+			return fmt.Sprint(message[0])
+		}
+		return ""
+	default:
+		return fmt.Sprint(message)
+	}
+}
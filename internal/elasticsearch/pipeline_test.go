@@ -0,0 +1,87 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestPipelineErrorMessage(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{name: "no error", source: `{"message":"ok"}`},
+		{name: "message", source: `{"error":{"message":"field [x] not present"}}`, want: "field [x] not present"},
+		{name: "first of several messages", source: `{"error":{"message":["first","second"]}}`, want: "first"},
+		{name: "empty messages", source: `{"error":{"message":[]}}`},
+		{name: "numeric message", source: `{"error":{"message":42}}`, want: "42"},
+		{name: "error without message", source: `{"error":{"type":"x"}}`},
+		{name: "error is not an object", source: `{"error":"failed"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var source map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(tt.source), &source); err != nil {
+				t.Fatal(err)
+			}
+			if got := pipelineErrorMessage(source); got != tt.want {
+				t.Errorf("pipelineErrorMessage(%s) = %q, want %q", tt.source, got, tt.want)
+			}
+		})
+	}
+}
+
+// simulateResponse answers a simulate request with docs
+type simulateResponse struct {
+	docs []string
+	path string
+}
+
+func (s *simulateResponse) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.path = req.URL.Path
+
+	header := http.Header{}
+	header.Set("X-Elastic-Product", "Elasticsearch")
+	header.Set("Content-Type", "application/json")
+	body := fmt.Sprintf(`{"docs":[%s]}`, strings.Join(s.docs, ","))
+	return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(strings.NewReader(body))}, nil
+}
+
+func TestSimulatePipeline(t *testing.T) {
+	processed := func(source string) string {
+		return fmt.Sprintf(`{"doc":{"_id":"1","_index":"logs-test.logs-default","_source":%s,"_ingest":{"timestamp":"2025-06-01T00:00:00Z"}}}`, source)
+	}
+
+	transport := &simulateResponse{docs: []string{
+		processed(`{"message":"ok"}`),
+		`{"error":{"type":"illegal_argument_exception","reason":"bad value"}}`,
+		processed(`{"message":"failed","error":{"message":"field [x] not present"}}`),
+		processed(`{"message":"own error","error":{"message":"from the event"}}`),
+	}}
+	c := newFakeConfig(t, transport, 0)
+
+	events := []map[string]interface{}{
+		{"message": "ok"},
+		{"message": "bad"},
+		{"message": "failed"},
+		{"message": "own error", "error": map[string]interface{}{"message": "from the event"}},
+	}
+	errs, err := c.SimulatePipeline("logs-test.logs-1.0.0", "logs-test.logs-default", events)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"", "illegal_argument_exception: bad value", "field [x] not present", ""}
+	if fmt.Sprintf("%q", errs) != fmt.Sprintf("%q", want) {
+		t.Errorf("SimulatePipeline() = %q, want %q", errs, want)
+	}
+	if transport.path != "/_ingest/pipeline/logs-test.logs-1.0.0/_simulate" {
+		t.Errorf("simulated with %s", transport.path)
+	}
+}
@@ -0,0 +1,132 @@
+package generator
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/tehbooom/elastic-data/internal/config"
+)
+
+// entitySeed keeps the entities the same for every dataset and run with the same replacements
+const entitySeed = 1
+
+// Entity is a simulated user whose IP, host, email and domain stay the same in every event
+type Entity struct {
+	User   string
+	Host   string
+	IP     string
+	Email  string
+	Domain string
+}
+
+// EntityPool picks the entity of an event from the sessions active at its timestamp.
+// Sessions only depend on the timestamp, so pools built from the same config agree on
+// the active entities without sharing state, letting datasets of different integrations
+// tell the same story.
+type EntityPool struct {
+	entities           []Entity
+	sessionDuration    time.Duration
+	concurrentSessions int
+}
+
+// NewEntityPool builds the entities from the replacements or returns nil when entities are disabled
+func NewEntityPool(cfg config.EntitiesConfig, replacements *config.Replacements) *EntityPool {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	cfg = cfg.WithDefaults()
+	random := rand.New(rand.NewSource(entitySeed))
+
+	// Shuffle each pool on its own so entities do not all combine the same positions
+	pick := func(values []string) func(int) string {
+		order := random.Perm(len(values))
+		return func(i int) string {
+			if len(values) == 0 {
+				return ""
+			}
+			return values[order[i%len(values)]]
+		}
+	}
+
+	users := pick(replacements.Users)
+	hosts := pick(replacements.Hosts)
+	ips := pick(replacements.IPs)
+	emails := pick(replacements.Emails)
+	domains := pick(replacements.Domains)
+
+	entities := make([]Entity, cfg.Count)
+	for i := range entities {
+		entities[i] = Entity{
+			User:   users(i),
+			Host:   hosts(i),
+			IP:     ips(i),
+			Email:  emails(i),
+			Domain: domains(i),
+		}
+	}
+
+	return &EntityPool{
+		entities:           entities,
+		sessionDuration:    cfg.SessionDuration,
+		concurrentSessions: cfg.ConcurrentSessions,
+	}
+}
+
+// RandomEntity returns an entity with each value picked at random from the replacements
+func RandomEntity(replacements *config.Replacements) Entity {
+	pick := func(values []string) string {
+		if len(values) == 0 {
+			return ""
+		}
+		return values[rand.Intn(len(values))]
+	}
+
+	return Entity{
+		User:   pick(replacements.Users),
+		Host:   pick(replacements.Hosts),
+		IP:     pick(replacements.IPs),
+		Email:  pick(replacements.Emails),
+		Domain: pick(replacements.Domains),
+	}
+}
+
+// At returns one of the entities with a session active at timestamp, picking the session with random
+func (p *EntityPool) At(timestamp time.Time, random *rand.Rand) Entity {
+	return p.inSlot(timestamp, random.Intn(p.concurrentSessions))
+}
+
+// inSlot returns the entity of a session slot active at timestamp
+func (p *EntityPool) inSlot(timestamp time.Time, slot int) Entity {
+	// Stagger the sessions of each slot so they do not all end at once
+	offset := time.Duration(slot) * p.sessionDuration / time.Duration(p.concurrentSessions)
+	session := timestamp.Add(offset).UnixNano() / int64(p.sessionDuration)
+
+	return p.entities[mix(uint64(session), uint64(slot))%uint64(len(p.entities))]
+}
+
+// value returns the entity value for a data pool, empty when the pool has no entity value
+func (e Entity) value(pool string) string {
+	switch pool {
+	case "IPs":
+		return e.IP
+	case "Domains":
+		return e.Domain
+	case "Emails":
+		return e.Email
+	case "Users":
+		return e.User
+	case "Hosts":
+		return e.Host
+	}
+
+	return ""
+}
+
+// mix hashes a session and slot into a well distributed number (splitmix64)
+func mix(session, slot uint64) uint64 {
+	z := session*0x9e3779b97f4a7c15 + slot + 0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
@@ -0,0 +1,224 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// preserveOriginalTags tags member added to the documents of events that keep their original
+	preserveOriginalTags = `"tags":["preserve_original_event"]`
+	hexDigits            = "0123456789abcdef"
+)
+
+// Event is a rendered event ready to be written to any output
+type Event struct {
+	// Document JSON document of the event on a single line, with @timestamp set
+	Document []byte
+	// Timestamp the @timestamp of the document
+	Timestamp time.Time
+	// message log line of plain text events
+	message string
+	// fields offset of the members of JSON events in the document, after the fields
+	// added by the generator
+	fields int
+	isJSON bool
+}
+
+// Raw returns the log line the event was rendered from. Plain text events return their
+// message and JSON events the document without the fields added by the generator.
+func (e Event) Raw() string {
+	if !e.isJSON {
+		return e.message
+	}
+
+	members := e.Document[e.fields:]
+	if len(members) > 0 && members[0] == ',' {
+		members = members[1:]
+	}
+	return "{" + string(members)
+}
+
+// buildEvent builds the event of a rendered message. JSON messages are compacted and
+// @timestamp and tags are spliced into their top level object instead of decoding and
+// encoding the document again. scratch is reused between calls.
+func (l *LogTemplate) buildEvent(message []byte, timestamp time.Time, preserveOriginal bool, scratch *bytes.Buffer) (Event, error) {
+	event := Event{Timestamp: timestamp, isJSON: l.IsJSON}
+
+	var source []byte
+	size := len(message) + timestampOverhead
+	if l.IsJSON {
+		// Like decoding, only the first value counts
+		message = bytes.TrimLeft(message, " \t\r\n")
+		message = message[:skipValue(message, 0)]
+
+		scratch.Reset()
+		if err := json.Compact(scratch, message); err != nil {
+			return Event{}, fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+		}
+		source = scratch.Bytes()
+		if len(source) == 0 || source[0] != '{' {
+			return Event{}, fmt.Errorf("%w: document is not an object", ErrInvalidJSON)
+		}
+		// Compacting keeps invalid UTF-8 in strings, which decoding replaces
+		if !utf8.Valid(source) {
+			source = replaceInvalidUTF8(source)
+		}
+		size = len(source) + timestampOverhead
+	}
+
+	document := make([]byte, 0, size+len(preserveOriginalTags))
+	document = append(document, `{"@timestamp":"`...)
+	document = timestamp.UTC().AppendFormat(document, time.RFC3339Nano)
+	document = append(document, '"')
+
+	if !l.IsJSON {
+		event.message = string(message)
+		document = append(document, `,"message":`...)
+		document = appendJSONString(document, event.message)
+	}
+
+	if preserveOriginal {
+		document = append(document, ',')
+		document = append(document, preserveOriginalTags...)
+	}
+
+	if l.IsJSON {
+		event.fields = len(document)
+		document = appendMembers(document, source, preserveOriginal)
+	}
+
+	event.Document = append(document, '}')
+	return event, nil
+}
+
+// appendMembers appends the members of the compacted object source, each preceded by a
+// comma, skipping @timestamp and, when replaced, tags
+func appendMembers(dst, source []byte, skipTags bool) []byte {
+	// Skip the opening brace
+	i := 1
+	for i < len(source) && source[i] != '}' {
+		start := i
+		keyEnd := skipString(source, i)
+		// Skip the colon after the key
+		end := skipValue(source, keyEnd+1)
+
+		key := source[start:keyEnd]
+		if string(key) != `"@timestamp"` && !(skipTags && string(key) == `"tags"`) {
+			dst = append(dst, ',')
+			dst = append(dst, source[start:end]...)
+		}
+
+		i = end
+		if i < len(source) && source[i] == ',' {
+			i++
+		}
+	}
+	return dst
+}
+
+// skipString returns the offset after the string starting at i
+func skipString(source []byte, i int) int {
+	for i++; i < len(source); i++ {
+		switch source[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return i
+}
+
+// skipValue returns the offset after the value starting at i
+func skipValue(source []byte, i int) int {
+	depth := 0
+	for i < len(source) {
+		switch source[i] {
+		case '"':
+			i = skipString(source, i)
+			if depth == 0 {
+				return i
+			}
+			continue
+		case '{', '[':
+			depth++
+		case '}', ']':
+			if depth == 0 {
+				return i
+			}
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		case ',':
+			if depth == 0 {
+				return i
+			}
+		}
+		i++
+	}
+	return i
+}
+
+// replaceInvalidUTF8 returns source with every byte that is not valid UTF-8 replaced by
+// U+FFFD, like encoding/json does when decoding strings
+func replaceInvalidUTF8(source []byte) []byte {
+	valid := make([]byte, 0, len(source)+8)
+	for i := 0; i < len(source); {
+		r, width := utf8.DecodeRune(source[i:])
+		if r == utf8.RuneError && width == 1 {
+			valid = append(valid, "\ufffd"...)
+		} else {
+			valid = append(valid, source[i:i+width]...)
+		}
+		i += width
+	}
+	return valid
+}
+
+// appendJSONString appends s as a JSON string, replacing invalid UTF-8 like encoding/json
+func appendJSONString(dst []byte, s string) []byte {
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' {
+				i++
+				continue
+			}
+			dst = append(dst, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				dst = append(dst, '\\', c)
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+
+		r, width := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && width == 1 {
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, "\ufffd"...)
+			i++
+			start = i
+			continue
+		}
+		i += width
+	}
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
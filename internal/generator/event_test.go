@@ -0,0 +1,327 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/tehbooom/elastic-data/internal/config"
+)
+
+// benchmarkTemplates plain text and JSON templates the bulk body benchmarks render
+var benchmarkTemplates = []struct {
+	name        string
+	integration string
+	dataset     string
+}{
+	{name: "text", integration: "nginx", dataset: "access"},
+	{name: "json", integration: "o365", dataset: "audit"},
+}
+
+// benchmarkMessage renders the first template of a dataset once
+func benchmarkMessage(b *testing.B, integration, dataset string) (*LogTemplate, string) {
+	b.Helper()
+
+	cfg := &config.Config{Integrations: map[string]config.Integration{}}
+	templates, _, err := LoadTemplatesForDataset(integration, dataset, cfg)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	template := templates[0]
+	template.UpdateValuesAt(time.Now())
+	message, err := template.ExecuteTemplate()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	return template, message
+}
+
+// BenchmarkBulkBody compares building the bulk body of a rendered message by decoding it
+// into a map and encoding it again, as the typed client does, with splicing its bytes
+func BenchmarkBulkBody(b *testing.B) {
+	timestamp := time.Now()
+
+	for _, bt := range benchmarkTemplates {
+		template, message := benchmarkMessage(b, bt.integration, bt.dataset)
+
+		b.Run(bt.name+"/decode", func(b *testing.B) {
+			var body bytes.Buffer
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				body.Reset()
+				document, _, err := template.document(message, timestamp, true)
+				if err != nil {
+					b.Fatal(err)
+				}
+				line, err := json.Marshal(document)
+				if err != nil {
+					b.Fatal(err)
+				}
+				body.WriteString(`{"create":{}}` + "\n")
+				body.Write(line)
+				body.WriteByte('\n')
+			}
+			b.SetBytes(int64(body.Len()))
+		})
+
+		b.Run(bt.name+"/splice", func(b *testing.B) {
+			var body, scratch bytes.Buffer
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				body.Reset()
+				event, err := template.buildEvent([]byte(message), timestamp, true, &scratch)
+				if err != nil {
+					b.Fatal(err)
+				}
+				body.WriteString(`{"create":{}}` + "\n")
+				body.Write(event.Document)
+				body.WriteByte('\n')
+			}
+			b.SetBytes(int64(body.Len()))
+		})
+	}
+}
+
+// BenchmarkRender measures rendering an event end to end with a renderer
+func BenchmarkRender(b *testing.B) {
+	timestamp := time.Now()
+
+	for _, bt := range benchmarkTemplates {
+		template, _ := benchmarkMessage(b, bt.integration, bt.dataset)
+		renderer := NewRenderer()
+
+		b.Run(bt.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := renderer.Render(template, timestamp, true); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkRenderParallel compares executing templates concurrently with the template
+// functions picking values from the locked shared source, as a baseline, and from the
+// source of each renderer
+func BenchmarkRenderParallel(b *testing.B) {
+	timestamp := time.Now()
+
+	for _, bt := range benchmarkTemplates {
+		template, _ := benchmarkMessage(b, bt.integration, bt.dataset)
+
+		b.Run(bt.name+"/shared", func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				renderer := NewRenderer()
+				for pb.Next() {
+					clear(renderer.data)
+					renderer.fill(template, timestamp)
+					renderer.message.Reset()
+					if err := template.Template.Execute(&renderer.message, renderer.data); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+
+		b.Run(bt.name+"/renderer", func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				renderer := NewRenderer()
+				tmpl, err := renderer.template(template)
+				if err != nil {
+					b.Fatal(err)
+				}
+				for pb.Next() {
+					clear(renderer.data)
+					renderer.fill(template, timestamp)
+					renderer.message.Reset()
+					if err := tmpl.Execute(&renderer.message, renderer.data); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+	}
+}
+
+// decodedEvent builds the document of message the way events were built before splicing,
+// by decoding the message and encoding it again
+func decodedEvent(t *testing.T, template *LogTemplate, message string, timestamp time.Time, preserveOriginal bool) ([]byte, error) {
+	t.Helper()
+
+	document, _, err := template.document(message, timestamp, preserveOriginal)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encoded, nil
+}
+
+func decode(t *testing.T, document []byte) interface{} {
+	t.Helper()
+
+	var value interface{}
+	if err := json.Unmarshal(document, &value); err != nil {
+		t.Fatalf("document %q is not valid JSON: %v", document, err)
+	}
+	return value
+}
+
+func TestBuildEvent(t *testing.T) {
+	timestamp := time.Date(2025, 6, 1, 12, 30, 45, 123000000, time.FixedZone("CEST", 2*3600))
+
+	tests := []struct {
+		name    string
+		isJSON  bool
+		message string
+		err     bool
+	}{
+		{name: "text", message: "GET /index.html 200"},
+		{name: "text escapes", message: "say \"hi\" \\ to\tthe\nworld\r\x01\x1f"},
+		{name: "text html and unicode", message: "<a href=\"x\">&amp;</a> héllo 日本 \u2028 \U0001F600"},
+		{name: "text invalid UTF-8", message: "bad \xff\xfe byte \xe2\x82 end"},
+		{name: "text JSON looking", message: `{"not":"parsed"}`},
+		{name: "object", isJSON: true, message: `{"event":{"action":"login"},"user":"alice"}`},
+		{name: "empty object", isJSON: true, message: `{}`},
+		{name: "existing timestamp", isJSON: true, message: `{"a":1,"@timestamp":"2020-01-01T00:00:00Z","b":2}`},
+		{name: "only timestamp", isJSON: true, message: `{"@timestamp":"2020-01-01T00:00:00Z"}`},
+		{name: "existing tags", isJSON: true, message: `{"tags":["forwarded"],"message":"x"}`},
+		{name: "nested timestamp and tags", isJSON: true, message: `{"event":{"@timestamp":"2020","tags":["kept"]},"list":[{"@timestamp":1}]}`},
+		{name: "nested arrays", isJSON: true, message: `{"a":[[1,2],[3,[4,{"b":[5]}]]],"c":{"d":{"e":{}}},"f":[]}`},
+		{name: "scalars", isJSON: true, message: `{"n":-1.50e3,"t":true,"f":false,"z":null,"i":12345}`},
+		{name: "escaped strings", isJSON: true, message: `{"q":"a \"}\" b","s":"\\","u":"\u00e9\u2028","c":"a,b:c{d}[e]","k\"ey":"v"}`},
+		{name: "escaped backslash before quote", isJSON: true, message: `{"a":"ends with \\","b":"x"}`},
+		{name: "whitespace", isJSON: true, message: "\n\t {\n  \"a\" : [ 1 , 2 ] ,\r\n  \"b\" :\t{ \"c\" : \"d e\" }\n}\n"},
+		{name: "trailing document", isJSON: true, message: "{\"a\":1}\n{\"b\":2}"},
+		{name: "trailing text", isJSON: true, message: `{"a":{"b":1}} trailing`},
+		{name: "trailing brace", isJSON: true, message: `{"a":1}}`},
+		{name: "invalid UTF-8 value", isJSON: true, message: "{\"a\":\"x\xffy\xe2\x82z\",\"b\":\"ok\"}"},
+		{name: "invalid UTF-8 key", isJSON: true, message: "{\"k\xfe\":1}"},
+		{name: "array", isJSON: true, message: `[{"a":1}]`, err: true},
+		{name: "string", isJSON: true, message: `"text"`, err: true},
+		{name: "truncated", isJSON: true, message: `{"a":`, err: true},
+		{name: "unquoted key", isJSON: true, message: `{a:1}`, err: true},
+		{name: "empty", isJSON: true, message: ``, err: true},
+	}
+
+	for _, tt := range tests {
+		for _, preserveOriginal := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/preserve=%t", tt.name, preserveOriginal), func(t *testing.T) {
+				template := &LogTemplate{IsJSON: tt.isJSON}
+
+				var scratch bytes.Buffer
+				event, err := template.buildEvent([]byte(tt.message), timestamp, preserveOriginal, &scratch)
+				want, decodeErr := decodedEvent(t, template, tt.message, timestamp, preserveOriginal)
+
+				if tt.err {
+					if !errors.Is(err, ErrInvalidJSON) || !errors.Is(decodeErr, ErrInvalidJSON) {
+						t.Fatalf("buildEvent() error = %v and decoding error = %v, want ErrInvalidJSON", err, decodeErr)
+					}
+					return
+				}
+				if err != nil || decodeErr != nil {
+					t.Fatalf("buildEvent() error = %v, decoding error = %v", err, decodeErr)
+				}
+
+				if !json.Valid(event.Document) || !utf8.Valid(event.Document) {
+					t.Fatalf("document %q is not valid JSON", event.Document)
+				}
+				if !bytes.HasPrefix(event.Document, []byte(`{"@timestamp":"2025-06-01T10:30:45.123Z"`)) {
+					t.Errorf("document %s does not start with @timestamp", event.Document)
+				}
+				if bytes.ContainsAny(event.Document, "\n\r") {
+					t.Errorf("document %q spans several lines", event.Document)
+				}
+				if got, want := decode(t, event.Document), decode(t, want); !reflect.DeepEqual(got, want) {
+					t.Errorf("buildEvent() = %s\nwant %s", event.Document, want)
+				}
+				if !event.Timestamp.Equal(timestamp) {
+					t.Errorf("timestamp %s, want %s", event.Timestamp, timestamp)
+				}
+			})
+		}
+	}
+}
+
+func TestEventRaw(t *testing.T) {
+	timestamp := time.Now()
+
+	tests := []struct {
+		name    string
+		isJSON  bool
+		message string
+		want    string
+	}{
+		{name: "text", message: "plain \"line\"", want: "plain \"line\""},
+		{name: "object", isJSON: true, message: `{"@timestamp":"x", "a" : 1, "tags":["t"]}`, want: `{"a":1,"tags":["t"]}`},
+		{name: "empty object", isJSON: true, message: `{"@timestamp":"x"}`, want: `{}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var scratch bytes.Buffer
+			event, err := (&LogTemplate{IsJSON: tt.isJSON}).buildEvent([]byte(tt.message), timestamp, false, &scratch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := event.Raw(); got != tt.want {
+				t.Errorf("Raw() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSkipValue(t *testing.T) {
+	tests := []struct {
+		source string
+		start  int
+		want   int
+	}{
+		{source: `"a\"b",1`, want: 6},
+		{source: `{"a":{"b":"}"}},"c":1`, want: 15},
+		{source: `[1,[2,"]"],3]x`, want: 13},
+		{source: `123,4`, want: 3},
+		{source: `true}`, want: 4},
+		{source: `{"k":"v"}`, start: 5, want: 8},
+		{source: `{"unterminated":`, want: 16},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			if got := skipValue([]byte(tt.source), tt.start); got != tt.want {
+				t.Errorf("skipValue(%q, %d) = %d, want %d", tt.source, tt.start, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppendJSONString(t *testing.T) {
+	for _, s := range []string{"", "plain", "\"\\/", "\x00\x07\b\f\n\r\t\x1f\x7f", "<>&", "é日\U0001F600\u2028\u2029", "\xff", "a\xe2\x82b", "\xed\xa0\x80"} {
+		encoded := appendJSONString(nil, s)
+
+		var got string
+		if err := json.Unmarshal(encoded, &got); err != nil {
+			t.Fatalf("appendJSONString(%q) = %s is not a JSON string: %v", s, encoded, err)
+		}
+
+		// Decoding the string encoded by encoding/json gives the same value
+		marshaled, _ := json.Marshal(s)
+		var want string
+		json.Unmarshal(marshaled, &want)
+		if got != want || !utf8.Valid(encoded) {
+			t.Errorf("appendJSONString(%q) = %s decodes to %q, want %q", s, encoded, got, want)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package generator
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Generator produces a value each time its template function is called,
+// for example {{uuid}} or {{int_range 1 100}}
+type Generator func(args ...any) (string, error)
+
+// randomGenerator is a built-in generator picking its values with the rand of the renderer
+type randomGenerator func(random *rand.Rand, args ...any) (string, error)
+
+var (
+	builtinGenerators = map[string]randomGenerator{
+		"uuid":            generateUUID,
+		"int_range":       generateIntRange,
+		"ipv6":            generateIPv6,
+		"mac":             generateMAC,
+		"port":            generatePort,
+		"http_status":     generateHTTPStatus,
+		"user_agent":      generateUserAgent,
+		"weighted_choice": generateWeightedChoice,
+	}
+	// generators registered with RegisterGenerator, they take precedence over the built-in ones
+	generators   = map[string]Generator{}
+	generatorsMu sync.RWMutex
+
+	// sharedRandom is used by templates executed without a renderer
+	sharedRandom = rand.New(&lockedSource{source: rand.NewSource(time.Now().UnixNano()).(rand.Source64)})
+)
+
+// lockedSource is a rand source that is safe for concurrent use
+type lockedSource struct {
+	mu     sync.Mutex
+	source rand.Source64
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.source.Int63()
+}
+
+func (s *lockedSource) Uint64() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.source.Uint64()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.source.Seed(seed)
+}
+
+// httpStatuses status codes returned by http_status with their weights
+var httpStatuses = []weightedValue{
+	{"200", 80}, {"201", 2}, {"204", 2}, {"301", 2}, {"302", 3}, {"304", 3},
+	{"400", 2}, {"401", 1}, {"403", 1}, {"404", 2}, {"500", 1}, {"502", 0.5}, {"503", 0.5},
+}
+
+// userAgents user agents returned by user_agent
+var userAgents = []string{
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Safari/605.1.15",
+	"Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:125.0) Gecko/20100101 Firefox/125.0",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36 Edg/124.0.2478.80",
+	"Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1",
+	"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36",
+	"curl/8.5.0",
+	"python-requests/2.31.0",
+	"Go-http-client/1.1",
+}
+
+type weightedValue struct {
+	value  string
+	weight float64
+}
+
+// RegisterGenerator adds a generator that templates can call by name.
+// Templates must be loaded after registering for the function to be available.
+func RegisterGenerator(name string, generator Generator) {
+	generatorsMu.Lock()
+	defer generatorsMu.Unlock()
+	generators[name] = generator
+}
+
+// Funcs returns the registered generators as template functions
+func Funcs() template.FuncMap {
+	return funcs(sharedRandom)
+}
+
+// funcs returns the registered generators as template functions, the built-in ones
+// picking their values with random
+func funcs(random *rand.Rand) template.FuncMap {
+	generatorsMu.RLock()
+	defer generatorsMu.RUnlock()
+
+	funcs := make(template.FuncMap, len(builtinGenerators)+len(generators))
+	for name, generator := range builtinGenerators {
+		funcs[name] = func(args ...any) (string, error) {
+			return generator(random, args...)
+		}
+	}
+	for name, generator := range generators {
+		funcs[name] = generator
+	}
+
+	return funcs
+}
+
+func generateUUID(random *rand.Rand, _ ...any) (string, error) {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], random.Uint64())
+	binary.BigEndian.PutUint64(b[8:], random.Uint64())
+	// Version 4, RFC 4122 variant
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// generateIntRange returns an integer between the first and second argument inclusive
+func generateIntRange(random *rand.Rand, args ...any) (string, error) {
+	if len(args) != 2 {
+		return "", fmt.Errorf("int_range expects a minimum and maximum, got %d arguments", len(args))
+	}
+
+	low, err := intArg(args[0])
+	if err != nil {
+		return "", fmt.Errorf("int_range minimum: %w", err)
+	}
+
+	high, err := intArg(args[1])
+	if err != nil {
+		return "", fmt.Errorf("int_range maximum: %w", err)
+	}
+
+	if high < low {
+		return "", fmt.Errorf("int_range maximum %d is less than minimum %d", high, low)
+	}
+
+	return strconv.FormatInt(low+random.Int63n(high-low+1), 10), nil
+}
+
+func generateIPv6(random *rand.Rand, _ ...any) (string, error) {
+	// 2001:db8::/32 is reserved for documentation
+	return fmt.Sprintf("2001:db8:%x:%x:%x:%x:%x:%x",
+		random.Intn(0x10000), random.Intn(0x10000), random.Intn(0x10000),
+		random.Intn(0x10000), random.Intn(0x10000), random.Intn(0x10000)), nil
+}
+
+// generateMAC returns a MAC address in the ECS format, an optional argument sets the separator
+func generateMAC(random *rand.Rand, args ...any) (string, error) {
+	separator := "-"
+	if len(args) > 0 {
+		separator = fmt.Sprint(args[0])
+	}
+
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], random.Uint64())
+	// Locally administered unicast address
+	b[0] = (b[0] | 0x02) & 0xfe
+
+	octets := make([]string, 6)
+	for i, octet := range b[:6] {
+		octets[i] = fmt.Sprintf("%02X", octet)
+	}
+
+	return strings.Join(octets, separator), nil
+}
+
+// generatePort returns a port from the registered and dynamic ranges
+func generatePort(random *rand.Rand, _ ...any) (string, error) {
+	return strconv.Itoa(1024 + random.Intn(65535-1024+1)), nil
+}
+
+func generateHTTPStatus(random *rand.Rand, _ ...any) (string, error) {
+	return pickWeighted(random, httpStatuses), nil
+}
+
+func generateUserAgent(random *rand.Rand, _ ...any) (string, error) {
+	return userAgents[random.Intn(len(userAgents))], nil
+}
+
+// generateWeightedChoice picks one of its arguments. Each argument is a value
+// optionally followed by a colon and its weight, for example "GET:70" "POST:30".
+func generateWeightedChoice(random *rand.Rand, args ...any) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("weighted_choice expects at least one choice")
+	}
+
+	choices := make([]weightedValue, 0, len(args))
+	for _, arg := range args {
+		choice := weightedValue{value: fmt.Sprint(arg), weight: 1}
+		if idx := strings.LastIndex(choice.value, ":"); idx != -1 {
+			if weight, err := strconv.ParseFloat(choice.value[idx+1:], 64); err == nil {
+				if weight < 0 {
+					return "", fmt.Errorf("weighted_choice weight for %s cannot be negative", choice.value[:idx])
+				}
+				choice.value = choice.value[:idx]
+				choice.weight = weight
+			}
+		}
+		choices = append(choices, choice)
+	}
+
+	return pickWeighted(random, choices), nil
+}
+
+func pickWeighted(random *rand.Rand, choices []weightedValue) string {
+	var total float64
+	for _, choice := range choices {
+		total += choice.weight
+	}
+
+	if total <= 0 {
+		return choices[random.Intn(len(choices))].value
+	}
+
+	target := random.Float64() * total
+	for _, choice := range choices {
+		target -= choice.weight
+		if target < 0 {
+			return choice.value
+		}
+	}
+
+	return choices[len(choices)-1].value
+}
+
+// intArg converts a template function argument to an integer
+func intArg(arg any) (int64, error) {
+	switch v := arg.(type) {
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case float64:
+		return int64(v), nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	default:
+		return 0, fmt.Errorf("%v is not an integer", arg)
+	}
+}
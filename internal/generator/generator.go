@@ -2,11 +2,12 @@ package generator
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math/rand"
-	"os"
-	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"text/template"
@@ -15,6 +16,17 @@ import (
 	"github.com/charmbracelet/log"
 	"github.com/tehbooom/elastic-data/internal/common"
 	"github.com/tehbooom/elastic-data/internal/config"
+	"github.com/tehbooom/elastic-data/internal/integrations"
+)
+
+// timestampOverhead approximate overhead for @timestamp and other metadata
+const timestampOverhead = 50
+
+var (
+	// ErrExecute is returned when a template fails to execute
+	ErrExecute = errors.New("failed to execute template")
+	// ErrInvalidJSON is returned when a JSON template renders an invalid document
+	ErrInvalidJSON = errors.New("invalid JSON")
 )
 
 type LogTemplate struct {
@@ -26,8 +38,41 @@ type LogTemplate struct {
 	Data         map[string]string
 	DataPools    map[string][]string
 	UserProvided bool
+	// Entities when set provides the IPs, Users, Hosts, Emails and Domains variables
+	Entities *EntityPool
+	// variables of the template, resolved once when it is loaded
+	variables []variable
+	// random picks the values stored in Data, created with the template
+	random *rand.Rand
+}
+
+// variable is a template variable with the function generating its values
+type variable struct {
+	name string
+	// entityPool data pool the entity of the event fills the variable from, empty
+	// for numbered variables, which describe other parties
+	entityPool string
+	// value generates a value for the variable, nil for unknown variables
+	value valueFunc
+}
+
+// valueFunc generates the value of a variable from the data pools of its template
+type valueFunc func(dataPools map[string][]string, random *rand.Rand, now time.Time) string
+
+// timestampLayouts layouts of the timestamp variables
+var timestampLayouts = map[string]string{
+	"timestamp_iso":           "2006-01-02T15:04:05.000Z",
+	"timestamp_common":        "02/Jan/2006:15:04:05",
+	"timestamp_clf_timezone":  "[02/Jan/2006:15:04:05 -0700]",
+	"timestamp_clf":           "[02/Jan/2006:15:04:05]",
+	"timestamp_syslog":        "Jan _2 15:04:05",
+	"timestamp_snort":         "01/02/06-15:04:05.000000",
+	"timestamp_snort_no_year": "01/02-15:04:05.000000",
 }
 
+// templateVariable matches the {{.VariableName}} actions of a template
+var templateVariable = regexp.MustCompile(`\{\{\.([^}]+)\}\}`)
+
 type PatternRule struct {
 	Name    string
 	Regex   *regexp.Regexp
@@ -68,6 +113,12 @@ func (l *LogTemplate) AddCommonPatterns() {
 		{"timestamp_snort_no_year", common.SnortNoYearRegex},
 		{"timestamp_unix_s", common.UnixSecRegex},
 		{"timestamp_unix_ms", common.UnixMsRegex},
+		{"Users", common.UserFieldRegex},
+		{"Users", common.NestedUserFieldRegex},
+		{"Hosts", common.HostFieldRegex},
+		{"Hosts", common.NestedHostFieldRegex},
+		{"Hosts", common.SyslogHostRegex},
+		{"Hosts", common.Syslog5424HostRegex},
 		{"IPs", regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)},
 		{"Emails", regexp.MustCompile(`[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`)},
 		{"Domains", regexp.MustCompile(`[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`)},
@@ -86,57 +137,180 @@ func (l *LogTemplate) AddPattern(name string, pattern *regexp.Regexp) {
 	})
 }
 
+// ApplyPatterns replaces every value in event matching one of the template's
+// patterns with its template variable. Repeated values share a variable while
+// distinct values of the same pattern get numbered variables (e.g. IPs_1).
+// Patterns with a group named value only replace the text of that group.
+func (l *LogTemplate) ApplyPatterns(event string) string {
+	valueTracker := make(map[string]map[string]int)
+
+	for _, p := range l.Patterns {
+		event = common.ReplaceValues(p.Regex, event, func(value string) string {
+			if valueTracker[p.Name] == nil {
+				valueTracker[p.Name] = make(map[string]int)
+			}
+
+			index, exists := valueTracker[p.Name][value]
+			if !exists {
+				index = len(valueTracker[p.Name])
+				valueTracker[p.Name][value] = index
+			}
+
+			if index == 0 {
+				return p.Replace
+			}
+			return fmt.Sprintf("{{.%s_%d}}", p.Name, index)
+		})
+	}
+
+	return event
+}
+
+// UpdateValues generates new values for the template variables using the current time
 func (l *LogTemplate) UpdateValues() {
+	l.UpdateValuesAt(time.Now())
+}
+
+// UpdateValuesAt generates new values for the template variables with every
+// timestamp variable set to timestamp
+func (l *LogTemplate) UpdateValuesAt(timestamp time.Time) {
+	var entity Entity
+	if l.Entities != nil {
+		entity = l.Entities.At(timestamp, l.random)
+	}
+
+	l.UpdateValuesWithEntity(timestamp, entity)
+}
+
+// UpdateValuesWithEntity generates new values for the template variables with
+// every timestamp variable set to timestamp and the event describing entity.
+// Numbered variables such as IPs_1 are other parties and come from the data pools.
+func (l *LogTemplate) UpdateValuesWithEntity(timestamp time.Time, entity Entity) {
 	if l.Data == nil {
 		l.Data = make(map[string]string)
 	}
 
-	// Extract all template variables from the template string
-	variableNames := extractTemplateVariables(l.Original)
+	l.fillValues(l.Data, timestamp, entity, l.random)
+}
+
+// fillValues generates values for the template variables into data
+func (l *LogTemplate) fillValues(data map[string]string, timestamp time.Time, entity Entity, random *rand.Rand) {
+	now := timestamp.UTC()
+
+	for _, v := range l.variables {
+		if v.entityPool != "" {
+			if value := entity.value(v.entityPool); value != "" {
+				data[v.name] = value
+				continue
+			}
+		}
+
+		if v.value == nil {
+			continue
+		}
 
-	// Generate values for all found variables
-	for _, varName := range variableNames {
-		value := generateValueForVariable(varName, l.DataPools)
-		if value != "" {
-			l.Data[varName] = value
+		if value := v.value(l.DataPools, random, now); value != "" {
+			data[v.name] = value
 		}
 	}
 }
 
 func (l *LogTemplate) ExecuteTemplate() (string, error) {
+	return l.execute(l.Data)
+}
+
+// execute renders the template with the values in data
+func (l *LogTemplate) execute(data map[string]string) (string, error) {
 	if l.Template == nil {
 		log.Debug(fmt.Errorf("template not parsed yet, call Parse() first"))
 		return "", fmt.Errorf("template not parsed yet, call Parse() first")
 	}
 
 	var buf bytes.Buffer
-	err := l.Template.Execute(&buf, l.Data)
+	err := l.Template.Execute(&buf, data)
 	if err != nil {
 		log.Debug(err)
-		return "", fmt.Errorf("failed to execute template: %v", err)
+		return "", fmt.Errorf("%w: %v", ErrExecute, err)
 	}
 
 	return buf.String(), nil
 }
 
-// LoadPreGeneratedTemplatesForDataset loads templates from pre-generated .tmpl files
-func LoadPreGeneratedTemplatesForDataset(integration, dataset string, cfg *config.Config) ([]*LogTemplate, error) {
-	templateFilePath := filepath.Join("internal", "integrations", "templates", integration, dataset+".tmpl")
+// Document executes the template with its current values and returns the document
+// for a bulk request with @timestamp set, along with its approximate size in bytes
+func (l *LogTemplate) Document(timestamp time.Time, preserveOriginal bool) (map[string]interface{}, int, error) {
+	message, err := l.ExecuteTemplate()
+	if err != nil {
+		log.Debug(err)
+		return nil, 0, err
+	}
+
+	return l.document(message, timestamp, preserveOriginal)
+}
+
+// document turns a rendered message into the document for a bulk request
+func (l *LogTemplate) document(message string, timestamp time.Time, preserveOriginal bool) (map[string]interface{}, int, error) {
+	var event map[string]interface{}
+	formattedTimestamp := timestamp.UTC().Format(time.RFC3339Nano)
 
-	// Check if template file exists
-	if _, err := os.Stat(templateFilePath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("template file not found: %s", templateFilePath)
+	if l.IsJSON {
+		decoder := json.NewDecoder(strings.NewReader(message))
+		if err := decoder.Decode(&event); err != nil {
+			log.Debug("Failed to parse JSON message:", err)
+			return nil, 0, fmt.Errorf("%w: %v", ErrInvalidJSON, err)
+		}
+		event["@timestamp"] = formattedTimestamp
+	} else {
+		event = map[string]interface{}{
+			"message":    message,
+			"@timestamp": formattedTimestamp,
+		}
+	}
+
+	if preserveOriginal {
+		event["tags"] = []string{"preserve_original_event"}
 	}
 
-	// Read the template file
-	templateFile, err := os.ReadFile(templateFilePath)
+	// Approximate size without re-marshaling
+	return event, len(message) + timestampOverhead, nil
+}
+
+// TemplateError is a template of a dataset that could not be parsed
+type TemplateError struct {
+	// Name name of the template, the dataset followed by its position
+	Name string
+	// Event the template text
+	Event string
+	Err   error
+}
+
+func (e TemplateError) Error() string {
+	return fmt.Sprintf("template %s: %v", e.Name, e.Err)
+}
+
+func (e TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// LoadPreGeneratedTemplatesForDataset loads templates from pre-generated .tmpl files
+func LoadPreGeneratedTemplatesForDataset(integration, dataset string, cfg *config.Config) ([]*LogTemplate, error) {
+	templates, _, err := LoadTemplatesForDataset(integration, dataset, cfg)
+	return templates, err
+}
+
+// LoadTemplatesForDataset loads templates from pre-generated .tmpl files and the events
+// configured for the dataset, also returning the templates that failed to parse
+func LoadTemplatesForDataset(integration, dataset string, cfg *config.Config) ([]*LogTemplate, []TemplateError, error) {
+	templateFile, err := integrations.ReadTemplate(integration, dataset)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read template file %s: %w", templateFilePath, err)
+		return nil, nil, err
 	}
 
 	// Split into individual templates using delimiter
 	templateEvents := strings.Split(string(templateFile), "\n---EVENT_DELIMITER---\n")
 	var templates []*LogTemplate
+	var failed []TemplateError
+	excluded := excludedTemplates(integration, dataset, cfg)
 
 	for i, event := range templateEvents {
 		event = strings.TrimSpace(event)
@@ -144,10 +318,16 @@ func LoadPreGeneratedTemplatesForDataset(integration, dataset string, cfg *confi
 			continue // Skip empty events
 		}
 
+		name := fmt.Sprintf("%s_%s_%d", integration, dataset, i)
+		if slices.Contains(excluded, name) {
+			continue
+		}
+
 		// Create LogTemplate from the event
-		logTemplate, err := createLogTemplateFromString(event, fmt.Sprintf("%s_%s_%d", integration, dataset, i))
+		logTemplate, err := createLogTemplateFromString(event, name)
 		if err != nil {
 			log.Debug(fmt.Sprintf("Warning: failed to create template from line %d: %v", i, err))
+			failed = append(failed, TemplateError{Name: name, Event: event, Err: err})
 			continue
 		}
 
@@ -156,21 +336,95 @@ func LoadPreGeneratedTemplatesForDataset(integration, dataset string, cfg *confi
 		templates = append(templates, logTemplate)
 	}
 
+	userTemplates, userFailed := loadUserTemplatesForDataset(integration, dataset, cfg)
+	templates = append(templates, userTemplates...)
+	failed = append(failed, userFailed...)
+
+	entities := NewEntityPool(cfg.Entities, &cfg.Replacements)
+	for _, logTemplate := range templates {
+		logTemplate.Entities = entities
+	}
+
 	if len(templates) == 0 {
-		return nil, fmt.Errorf("no valid templates found in file %s", templateFilePath)
+		return nil, failed, fmt.Errorf("no valid templates found for %s:%s", integration, dataset)
 	}
 
 	log.Debug(fmt.Sprintf("Loaded %d pre-generated templates for %s:%s", len(templates), integration, dataset))
-	return templates, nil
+	return templates, failed, nil
+}
+
+// excludedTemplates returns the names of the templates the dataset is configured to skip
+func excludedTemplates(integration, dataset string, cfg *config.Config) []string {
+	return cfg.Integrations[integration].Datasets[dataset].ExcludeTemplates
+}
+
+// loadUserTemplatesForDataset creates templates from the events configured for the dataset
+func loadUserTemplatesForDataset(integration, dataset string, cfg *config.Config) ([]*LogTemplate, []TemplateError) {
+	var templates []*LogTemplate
+	var failed []TemplateError
+
+	integrationConfig, exists := cfg.Integrations[integration]
+	if !exists {
+		return templates, failed
+	}
+
+	datasetConfig, exists := integrationConfig.Datasets[dataset]
+	if !exists {
+		return templates, failed
+	}
+
+	for i, event := range datasetConfig.Events {
+		event = strings.TrimSpace(event)
+		if event == "" {
+			continue
+		}
+
+		name := fmt.Sprintf("%s_%s_user_%d", integration, dataset, i)
+		if slices.Contains(datasetConfig.ExcludeTemplates, name) {
+			continue
+		}
+
+		logTemplate, err := createLogTemplateFromEvent(event, name)
+		if err != nil {
+			log.Debug(fmt.Sprintf("Warning: failed to create template from user event %d: %v", i, err))
+			failed = append(failed, TemplateError{Name: name, Event: event, Err: err})
+			continue
+		}
+
+		logTemplate.initializeDataPools(&cfg.Replacements)
+		templates = append(templates, logTemplate)
+	}
+
+	log.Debug(fmt.Sprintf("Loaded %d user provided templates for %s:%s", len(templates), integration, dataset))
+	return templates, failed
+}
+
+// createLogTemplateFromEvent creates a LogTemplate from a raw event by replacing
+// values matching the common patterns with template variables
+func createLogTemplateFromEvent(event, name string) (*LogTemplate, error) {
+	patterns := &LogTemplate{}
+	patterns.AddCommonPatterns()
+
+	logTemplate, err := createLogTemplateFromString(patterns.ApplyPatterns(event), name)
+	if err != nil {
+		return nil, err
+	}
+
+	logTemplate.Patterns = patterns.Patterns
+	logTemplate.UserProvided = true
+
+	return logTemplate, nil
 }
 
 // createLogTemplateFromString creates a LogTemplate from a template string
 func createLogTemplateFromString(templateStr, name string) (*LogTemplate, error) {
-	// Determine if this is a JSON template
-	isJSON := strings.HasPrefix(strings.TrimSpace(templateStr), "{")
+	// Determine if this is a JSON template. Plain text events can start with a
+	// template action such as {{.IPs}}, which is not a JSON object.
+	trimmed := strings.TrimSpace(templateStr)
+	isJSON := strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "{{")
 
 	// Parse the template string
-	tmpl, err := template.New(name).Parse(templateStr)
+	tmpl, err := template.New(name).Funcs(Funcs()).Parse(templateStr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse template: %w", err)
 	}
@@ -184,6 +438,11 @@ func createLogTemplateFromString(templateStr, name string) (*LogTemplate, error)
 		Data:         make(map[string]string),
 		DataPools:    make(map[string][]string),
 		UserProvided: false,
+		random:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+
+	for _, name := range extractTemplateVariables(templateStr) {
+		logTemplate.variables = append(logTemplate.variables, newVariable(name))
 	}
 
 	return logTemplate, nil
@@ -191,8 +450,7 @@ func createLogTemplateFromString(templateStr, name string) (*LogTemplate, error)
 
 // extractTemplateVariables finds all {{.VariableName}} patterns in a template string
 func extractTemplateVariables(templateStr string) []string {
-	re := regexp.MustCompile(`\{\{\.([^}]+)\}\}`)
-	matches := re.FindAllStringSubmatch(templateStr, -1)
+	matches := templateVariable.FindAllStringSubmatch(templateStr, -1)
 
 	var variables []string
 	seen := make(map[string]bool)
@@ -210,58 +468,44 @@ func extractTemplateVariables(templateStr string) []string {
 	return variables
 }
 
-// generateValueForVariable generates appropriate values for template variables including numbered ones
-func generateValueForVariable(varName string, dataPools map[string][]string) string {
-	now := time.Now().UTC()
-
-	// Handle numbered variables (e.g., IPs_1, Emails_0)
-	baseVar := varName
-	if idx := strings.LastIndex(varName, "_"); idx != -1 {
-		if _, err := strconv.Atoi(varName[idx+1:]); err == nil {
-			baseVar = varName[:idx] // Extract base variable name
+// newVariable resolves the function generating the values of a variable, including
+// numbered ones such as IPs_1 that take their value from the same data pool
+func newVariable(name string) variable {
+	baseVar := name
+	if idx := strings.LastIndex(name, "_"); idx != -1 {
+		if _, err := strconv.Atoi(name[idx+1:]); err == nil {
+			baseVar = name[:idx] // Extract base variable name
 		}
 	}
 
+	v := variable{name: name}
+
 	switch baseVar {
-	case "IPs":
-		if array, exists := dataPools["IPs"]; exists && len(array) > 0 {
-			return array[rand.Intn(len(array))]
+	case "IPs", "Domains", "Emails", "Users", "Hosts":
+		if baseVar == name {
+			v.entityPool = name
 		}
-	case "Domains":
-		if array, exists := dataPools["Domains"]; exists && len(array) > 0 {
-			return array[rand.Intn(len(array))]
+		v.value = func(dataPools map[string][]string, random *rand.Rand, _ time.Time) string {
+			if array := dataPools[baseVar]; len(array) > 0 {
+				return array[random.Intn(len(array))]
+			}
+			return ""
 		}
-	case "Emails":
-		if array, exists := dataPools["Emails"]; exists && len(array) > 0 {
-			return array[rand.Intn(len(array))]
+	case "timestamp_unix_s":
+		v.value = func(_ map[string][]string, _ *rand.Rand, now time.Time) string {
+			return strconv.FormatInt(now.Unix(), 10)
 		}
-	case "Users":
-		if array, exists := dataPools["Users"]; exists && len(array) > 0 {
-			return array[rand.Intn(len(array))]
+	case "timestamp_unix_ms":
+		v.value = func(_ map[string][]string, _ *rand.Rand, now time.Time) string {
+			return strconv.FormatInt(now.UnixMilli(), 10)
 		}
-	case "Hosts":
-		if array, exists := dataPools["Hosts"]; exists && len(array) > 0 {
-			return array[rand.Intn(len(array))]
+	default:
+		if layout, ok := timestampLayouts[baseVar]; ok {
+			v.value = func(_ map[string][]string, _ *rand.Rand, now time.Time) string {
+				return now.Format(layout)
+			}
 		}
-	case "timestamp_iso":
-		return now.Format("2006-01-02T15:04:05.000Z")
-	case "timestamp_common":
-		return now.Format("02/Jan/2006:15:04:05")
-	case "timestamp_clf_timezone":
-		return now.Format("[02/Jan/2006:15:04:05 -0700]")
-	case "timestamp_clf":
-		return now.Format("[02/Jan/2006:15:04:05]")
-	case "timestamp_syslog":
-		return now.Format("Jan _2 15:04:05")
-	case "timestamp_snort":
-		return now.Format("01/02/06-15:04:05.000000")
-	case "timestamp_snort_no_year":
-		return now.Format("01/02-15:04:05.000000")
-	case "timestamp_unix_s":
-		return strconv.FormatInt(now.Unix(), 10)
-	case "timestamp_unix_ms":
-		return strconv.FormatInt(now.UnixMilli(), 10)
 	}
 
-	return ""
+	return v
 }
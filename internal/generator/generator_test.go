@@ -0,0 +1,215 @@
+package generator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tehbooom/elastic-data/internal/config"
+	"github.com/tehbooom/elastic-data/internal/integrations"
+)
+
+// writeTemplates writes events as the templates of the test:logs dataset
+func writeTemplates(t *testing.T, events ...string) {
+	t.Helper()
+	writeDatasets(t, map[string][]string{"test/logs": events})
+}
+
+// writeDatasets writes the events of each <integration>/<dataset> as its templates
+func writeDatasets(t *testing.T, datasets map[string][]string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, events := range datasets {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		content := strings.Join(events, "\n---EVENT_DELIMITER---\n")
+		if err := os.WriteFile(filepath.Join(dir, name+".tmpl"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	integrations.SetTemplatesDir(dir)
+	t.Cleanup(func() { integrations.SetTemplatesDir("") })
+}
+
+func TestLoadUserTemplates(t *testing.T) {
+	writeTemplates(t, "shipped {{.Users}}", "other shipped event")
+
+	tests := []struct {
+		name    string
+		events  []string
+		exclude []string
+		// want names of the templates loaded, user templates only
+		want     []string
+		failed   []string
+		original map[string]string
+	}{
+		{
+			name:   "user events",
+			events: []string{"login from 10.1.2.3 to 10.1.2.4", "  ", "mail to alice@example.com"},
+			want:   []string{"test_logs_user_0", "test_logs_user_2"},
+			original: map[string]string{
+				"test_logs_user_0": "login from {{.IPs}} to {{.IPs_1}}",
+				"test_logs_user_2": "mail to {{.Emails}}",
+			},
+		},
+		{
+			name:    "excluded user event",
+			events:  []string{"first", "second", "third"},
+			exclude: []string{"test_logs_user_1", "test_logs_0"},
+			want:    []string{"test_logs_user_0", "test_logs_user_2"},
+		},
+		{
+			name:   "invalid user event",
+			events: []string{"broken {{ event", "fine"},
+			want:   []string{"test_logs_user_1"},
+			failed: []string{"test_logs_user_0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Integrations: map[string]config.Integration{
+					"test": {Datasets: map[string]config.Dataset{
+						"logs": {Events: tt.events, ExcludeTemplates: tt.exclude},
+					}},
+				},
+			}
+
+			templates, failed, err := LoadTemplatesForDataset("test", "logs", cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var shipped, user []string
+			for _, template := range templates {
+				name := template.Template.Name()
+				if !template.UserProvided {
+					shipped = append(shipped, name)
+					continue
+				}
+				user = append(user, name)
+				if want, ok := tt.original[name]; ok && template.Original != want {
+					t.Errorf("template %s = %q, want %q", name, template.Original, want)
+				}
+			}
+
+			if strings.Join(user, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("user templates %v, want %v", user, tt.want)
+			}
+
+			// Shipped templates keep their own names and exclusions
+			wantShipped := "test_logs_0,test_logs_1"
+			for _, excluded := range tt.exclude {
+				if excluded == "test_logs_0" {
+					wantShipped = "test_logs_1"
+				}
+			}
+			if strings.Join(shipped, ",") != wantShipped {
+				t.Errorf("shipped templates %v, want %s", shipped, wantShipped)
+			}
+
+			var failedNames []string
+			for _, templateErr := range failed {
+				failedNames = append(failedNames, templateErr.Name)
+			}
+			if strings.Join(failedNames, ",") != strings.Join(tt.failed, ",") {
+				t.Errorf("failed templates %v, want %v", failedNames, tt.failed)
+			}
+		})
+	}
+}
+
+func TestLoadTemplatesNone(t *testing.T) {
+	writeTemplates(t, "only event")
+
+	cfg := &config.Config{
+		Integrations: map[string]config.Integration{
+			"test": {Datasets: map[string]config.Dataset{"logs": {ExcludeTemplates: []string{"test_logs_0"}}}},
+		},
+	}
+
+	_, _, err := LoadTemplatesForDataset("test", "logs", cfg)
+	if err == nil || !strings.Contains(err.Error(), "no valid templates found for test:logs") {
+		t.Errorf("LoadTemplatesForDataset() error = %v", err)
+	}
+}
+
+// Datasets of different integrations loaded with entities enabled agree on the entity
+// active at a timestamp, so their events tell the same story
+func TestLoadTemplatesSharedEntities(t *testing.T) {
+	writeDatasets(t, map[string][]string{
+		"auth/login": {"login {{.Users}} from {{.IPs}}"},
+		"web/access": {"{{.IPs}} GET / user={{.Users}}"},
+	})
+
+	cfg := &config.Config{
+		// Fewer entities than users and IPs so every entity has its own user and IP
+		Entities: config.EntitiesConfig{Enabled: true, Count: 5, SessionDuration: time.Hour, ConcurrentSessions: 4},
+		Replacements: config.Replacements{
+			Users: []string{"u0", "u1", "u2", "u3", "u4", "u5", "u6", "u7", "u8", "u9"},
+			IPs:   []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5", "10.0.0.6", "10.0.0.7"},
+		},
+	}
+
+	load := func(integration, dataset string) *LogTemplate {
+		templates, _, err := LoadTemplatesForDataset(integration, dataset, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if templates[0].Entities == nil {
+			t.Fatalf("%s:%s loaded without entities", integration, dataset)
+		}
+		return templates[0]
+	}
+	login := load("auth", "login")
+	access := load("web", "access")
+
+	fields := func(template *LogTemplate, timestamp time.Time, seed int64) (string, string) {
+		event, err := seededRenderer(seed).Render(template, timestamp, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		words := strings.Fields(event.Raw())
+		if words[0] == "login" {
+			return words[1], words[3]
+		}
+		return strings.TrimPrefix(words[3], "user="), words[0]
+	}
+
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	// ips of the entity of each user across both datasets
+	ips := make(map[string]string)
+	for i := 0; i < 200; i++ {
+		timestamp := start.Add(time.Duration(i) * 7 * time.Minute)
+		seed := int64(i)
+
+		// The same session slot gives the same entity in every dataset
+		loginUser, loginIP := fields(login, timestamp, seed)
+		accessUser, accessIP := fields(access, timestamp, seed)
+		if loginUser != accessUser || loginIP != accessIP {
+			t.Fatalf("at %s login has %s from %s and access %s from %s, want the same entity",
+				timestamp, loginUser, loginIP, accessUser, accessIP)
+		}
+
+		// Every user keeps the IP of their entity
+		if ip, ok := ips[loginUser]; ok && ip != loginIP {
+			t.Errorf("user %s from %s and %s", loginUser, ip, loginIP)
+		}
+		ips[loginUser] = loginIP
+	}
+
+	// Loading a dataset again gives the same entities
+	again := load("auth", "login")
+	for i := 0; i < 20; i++ {
+		timestamp := start.Add(time.Duration(i) * time.Hour)
+		user, ip := fields(login, timestamp, int64(i))
+		if againUser, againIP := fields(again, timestamp, int64(i)); againUser != user || againIP != ip {
+			t.Errorf("at %s reloaded template has %s from %s, want %s from %s", timestamp, againUser, againIP, user, ip)
+		}
+	}
+}
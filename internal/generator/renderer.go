@@ -0,0 +1,87 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+// rendererSeeds keeps renderers created at the same time from picking the same values
+var rendererSeeds atomic.Int64
+
+// Renderer renders events of templates with values of its own instead of the values
+// stored on the templates, so that several renderers can share templates concurrently
+type Renderer struct {
+	data map[string]string
+	// random picks values without the lock of the global math/rand source
+	random *rand.Rand
+	// templates clones of the templates rendered so far with the template functions
+	// bound to random
+	templates map[*LogTemplate]*template.Template
+	// message and scratch are reused between events
+	message bytes.Buffer
+	scratch bytes.Buffer
+}
+
+// NewRenderer returns a renderer, each goroutine rendering events needs its own
+func NewRenderer() *Renderer {
+	seed := time.Now().UnixNano() + rendererSeeds.Add(1)
+	return &Renderer{
+		data:      make(map[string]string),
+		random:    rand.New(rand.NewSource(seed)),
+		templates: make(map[*LogTemplate]*template.Template),
+	}
+}
+
+// Render generates new values for the template at timestamp and returns the event
+// with its document ready for a bulk request
+func (r *Renderer) Render(l *LogTemplate, timestamp time.Time, preserveOriginal bool) (Event, error) {
+	tmpl, err := r.template(l)
+	if err != nil {
+		return Event{}, err
+	}
+
+	clear(r.data)
+	r.fill(l, timestamp)
+
+	r.message.Reset()
+	if err := tmpl.Execute(&r.message, r.data); err != nil {
+		log.Debug(err)
+		return Event{}, fmt.Errorf("%w: %v", ErrExecute, err)
+	}
+
+	return l.buildEvent(r.message.Bytes(), timestamp, preserveOriginal, &r.scratch)
+}
+
+// template returns the template of l with its functions picking values with the rand
+// of the renderer, cloning it the first time l is rendered
+func (r *Renderer) template(l *LogTemplate) (*template.Template, error) {
+	if tmpl, ok := r.templates[l]; ok {
+		return tmpl, nil
+	}
+
+	tmpl, err := l.Template.Clone()
+	if err != nil {
+		log.Debug(err)
+		return nil, fmt.Errorf("%w: %v", ErrExecute, err)
+	}
+	tmpl.Funcs(funcs(r.random))
+
+	r.templates[l] = tmpl
+	return tmpl, nil
+}
+
+// fill generates the values of the template variables at timestamp
+func (r *Renderer) fill(l *LogTemplate, timestamp time.Time) {
+	var entity Entity
+	if l.Entities != nil {
+		entity = l.Entities.At(timestamp, r.random)
+	}
+
+	l.fillValues(r.data, timestamp, entity, r.random)
+}
@@ -0,0 +1,320 @@
+package generator
+
+import (
+	"fmt"
+	"math/rand"
+	"regexp"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/tehbooom/elastic-data/internal/config"
+	"github.com/tehbooom/elastic-data/internal/integrations"
+)
+
+// integrationTemplates loads the templates of every dataset of every integration
+func integrationTemplates(b *testing.B) ([]string, map[string][]*LogTemplate) {
+	b.Helper()
+
+	names, err := integrations.GetIntegrationsFromTemplates()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Integrations: map[string]config.Integration{},
+		Replacements: config.Replacements{
+			IPs:     []string{"192.168.1.100", "10.0.0.50", "172.16.0.25"},
+			Domains: []string{"example.com", "test.local", "company.internal"},
+			Emails:  []string{"user@example.com", "admin@company.com", "noreply@test.local"},
+			Users:   []string{"john.doe", "admin", "service_account", "test_user", "root"},
+			Hosts:   []string{"web-server-01", "db-server", "app-host", "workstation-123"},
+		},
+	}
+
+	templates := make(map[string][]*LogTemplate, len(names))
+	for _, name := range names {
+		datasets, err := integrations.GetDatasetsFromTemplates(name)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for _, dataset := range datasets {
+			loaded, _, err := LoadTemplatesForDataset(name, dataset, cfg)
+			if err != nil {
+				continue
+			}
+			templates[name] = append(templates[name], loaded...)
+		}
+	}
+
+	return names, templates
+}
+
+// BenchmarkRenderIntegrations renders the templates of each integration in turn
+func BenchmarkRenderIntegrations(b *testing.B) {
+	names, templates := integrationTemplates(b)
+	timestamp := time.Now()
+
+	for _, name := range names {
+		b.Run(name, func(b *testing.B) {
+			renderer := NewRenderer()
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				template := templates[name][i%len(templates[name])]
+				// Templates that do not execute are quarantined while generating
+				renderer.Render(template, timestamp, false)
+			}
+		})
+	}
+}
+
+// fillPerEvent generates the values of a template the way they were generated before the
+// variables were resolved at load time, as a baseline: the variables are found in the
+// template text for every event and their values picked with the locked shared source
+func fillPerEvent(l *LogTemplate, data map[string]string, timestamp time.Time) {
+	now := timestamp.UTC()
+	variables := regexp.MustCompile(`\{\{\.([^}]+)\}\}`)
+
+	seen := make(map[string]bool)
+	for _, match := range variables.FindAllStringSubmatch(l.Original, -1) {
+		if seen[match[1]] {
+			continue
+		}
+		seen[match[1]] = true
+
+		if v := newVariable(match[1]); v.value != nil {
+			if value := v.value(l.DataPools, sharedRandom, now); value != "" {
+				data[match[1]] = value
+			}
+		}
+	}
+}
+
+// BenchmarkFillValues generates the values of every template of every integration, with
+// the variables found for every event as a baseline and resolved at load time
+func BenchmarkFillValues(b *testing.B) {
+	names, templates := integrationTemplates(b)
+	timestamp := time.Now()
+
+	var all []*LogTemplate
+	for _, name := range names {
+		all = append(all, templates[name]...)
+	}
+
+	b.Run("per_event", func(b *testing.B) {
+		data := make(map[string]string)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			clear(data)
+			fillPerEvent(all[i%len(all)], data, timestamp)
+		}
+	})
+
+	b.Run("resolved", func(b *testing.B) {
+		renderer := NewRenderer()
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			clear(renderer.data)
+			renderer.fill(all[i%len(all)], timestamp)
+		}
+	})
+
+	// Render workers generate values concurrently, sharing a source makes them wait on its lock
+	b.Run("per_event/parallel", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			data := make(map[string]string)
+			for i := 0; pb.Next(); i++ {
+				clear(data)
+				fillPerEvent(all[i%len(all)], data, timestamp)
+			}
+		})
+	})
+
+	b.Run("resolved/parallel", func(b *testing.B) {
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			renderer := NewRenderer()
+			for i := 0; pb.Next(); i++ {
+				clear(renderer.data)
+				renderer.fill(all[i%len(all)], timestamp)
+			}
+		})
+	})
+}
+
+// seededRenderer returns a renderer picking values from a source seeded with seed
+func seededRenderer(seed int64) *Renderer {
+	renderer := NewRenderer()
+	renderer.random = rand.New(rand.NewSource(seed))
+	return renderer
+}
+
+func TestNewVariable(t *testing.T) {
+	dataPools := map[string][]string{"IPs": {"10.0.0.1"}, "Users": {"alice"}}
+	now := time.Date(2025, 6, 1, 12, 30, 45, 0, time.UTC)
+	random := rand.New(rand.NewSource(1))
+
+	tests := []struct {
+		name       string
+		entityPool string
+		// value generated, empty for variables without a function
+		value string
+	}{
+		{name: "IPs", entityPool: "IPs", value: "10.0.0.1"},
+		{name: "IPs_1", value: "10.0.0.1"},
+		{name: "Users_12", value: "alice"},
+		{name: "Hosts_1", value: ""},
+		{name: "timestamp_iso", value: "2025-06-01T12:30:45.000Z"},
+		{name: "timestamp_clf_2", value: "[01/Jun/2025:12:30:45]"},
+		{name: "timestamp_unix_s_1", value: "1748781045"},
+		{name: "timestamp_unix_ms", value: "1748781045000"},
+		{name: "IPs_source", value: ""},
+		{name: "Custom_1", value: ""},
+		{name: "unknown", value: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newVariable(tt.name)
+			if v.name != tt.name || v.entityPool != tt.entityPool {
+				t.Errorf("newVariable() = %+v, want entity pool %q", v, tt.entityPool)
+			}
+
+			var value string
+			if v.value != nil {
+				value = v.value(dataPools, random, now)
+			}
+			if value != tt.value {
+				t.Errorf("value %q, want %q", value, tt.value)
+			}
+		})
+	}
+}
+
+func TestFillNumberedVariables(t *testing.T) {
+	template, err := createLogTemplateFromString("{{.IPs}} {{.IPs_1}} {{.Users}} {{.Users_2}} {{.Hosts}} {{.IPs_1}}", "test_logs_0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.initializeDataPools(&config.Replacements{
+		IPs:   []string{"10.0.0.1", "10.0.0.2"},
+		Users: []string{"bob", "carol"},
+		Hosts: []string{"web-1"},
+	})
+
+	// Variables are resolved once, in the order they first appear
+	var names []string
+	for _, v := range template.variables {
+		names = append(names, v.name)
+	}
+	if fmt.Sprint(names) != "[IPs IPs_1 Users Users_2 Hosts]" {
+		t.Errorf("variables %v", names)
+	}
+
+	// The entity is the actor of the event, numbered variables are other parties
+	entity := Entity{IP: "192.168.1.1", User: "alice"}
+	for i := 0; i < 100; i++ {
+		data := make(map[string]string)
+		template.fillValues(data, time.Now(), entity, rand.New(rand.NewSource(int64(i))))
+
+		if data["IPs"] != "192.168.1.1" || data["Users"] != "alice" {
+			t.Fatalf("entity values %v", data)
+		}
+		if !slices.Contains(template.DataPools["IPs"], data["IPs_1"]) || !slices.Contains(template.DataPools["Users"], data["Users_2"]) {
+			t.Fatalf("numbered values %v", data)
+		}
+		// Without an entity host the pool is used
+		if data["Hosts"] != "web-1" {
+			t.Fatalf("host %q", data["Hosts"])
+		}
+	}
+}
+
+func TestEntityPoolSlots(t *testing.T) {
+	cfg := config.EntitiesConfig{Enabled: true, Count: 20, SessionDuration: time.Hour, ConcurrentSessions: 4}
+	replacements := &config.Replacements{
+		Users: []string{"u0", "u1", "u2", "u3", "u4", "u5", "u6", "u7", "u8", "u9"},
+		IPs:   []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"},
+	}
+	pool := NewEntityPool(cfg, replacements)
+	other := NewEntityPool(cfg, replacements)
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	if NewEntityPool(config.EntitiesConfig{}, replacements) != nil {
+		t.Error("pool built with entities disabled")
+	}
+
+	for slot := 0; slot < cfg.ConcurrentSessions; slot++ {
+		// Sessions of a slot start staggered by a quarter of the session duration
+		offset := time.Duration(slot) * cfg.SessionDuration / time.Duration(cfg.ConcurrentSessions)
+		sessionStart := start.Add(cfg.SessionDuration - offset)
+
+		entity := pool.inSlot(sessionStart, slot)
+		for _, at := range []time.Duration{time.Second, 30 * time.Minute, cfg.SessionDuration - time.Nanosecond} {
+			if got := pool.inSlot(sessionStart.Add(at), slot); got != entity {
+				t.Errorf("slot %d changed entity %s into its session", slot, at)
+			}
+		}
+
+		// Pools built from the same config agree on the entities without sharing state
+		if got := other.inSlot(sessionStart, slot); got != entity {
+			t.Errorf("slot %d has %+v in another pool, want %+v", slot, got, entity)
+		}
+	}
+
+	// At picks the slot with the rand it is given
+	for seed := int64(0); seed < 10; seed++ {
+		slot := rand.New(rand.NewSource(seed)).Intn(cfg.ConcurrentSessions)
+		if got, want := pool.At(start, rand.New(rand.NewSource(seed))), pool.inSlot(start, slot); got != want {
+			t.Errorf("At() with seed %d = %+v, want slot %d %+v", seed, got, slot, want)
+		}
+	}
+
+	// Over many sessions every entity gets a turn
+	seen := make(map[Entity]bool)
+	for session := 0; session < 500; session++ {
+		seen[pool.inSlot(start.Add(time.Duration(session)*cfg.SessionDuration), session%cfg.ConcurrentSessions)] = true
+	}
+	if len(seen) < 10 {
+		t.Errorf("only %d distinct entities over 500 sessions", len(seen))
+	}
+}
+
+func TestRendererRandom(t *testing.T) {
+	template, err := createLogTemplateFromString(`{{uuid}} {{int_range 1 1000000}} {{ipv6}} {{mac}} {{port}} {{http_status}} {{user_agent}} {{weighted_choice "a" "b" "c"}} {{.IPs_1}}`, "test_logs_0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.initializeDataPools(&config.Replacements{IPs: []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}})
+	timestamp := time.Now()
+
+	render := func(renderer *Renderer) []string {
+		var events []string
+		for i := 0; i < 5; i++ {
+			event, err := renderer.Render(template, timestamp, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			events = append(events, event.Raw())
+		}
+		return events
+	}
+
+	// The template functions pick values with the rand of the renderer only
+	first := render(seededRenderer(42))
+	rand.Int()
+	second := render(seededRenderer(42))
+	if !slices.Equal(first, second) {
+		t.Errorf("renderers with the same seed rendered\n%v\n%v", first, second)
+	}
+	if third := render(seededRenderer(7)); slices.Equal(first, third) {
+		t.Errorf("renderers with different seeds rendered the same events %v", third)
+	}
+
+	// The template itself keeps the shared functions for rendering without a renderer
+	template.UpdateValues()
+	if _, err := template.ExecuteTemplate(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,59 @@
+package generator
+
+import (
+	"math/rand"
+	"slices"
+	"time"
+
+	"github.com/tehbooom/elastic-data/internal/config"
+)
+
+// SpreadTimestamps returns count timestamps within [start, start+window) placed according
+// to distribution. Each timestamp is then moved by a random offset of up to jitter in
+// either direction, which may move it outside the window. The timestamps are returned
+// in ascending order.
+func SpreadTimestamps(start time.Time, window time.Duration, count int, distribution string, jitter time.Duration, random *rand.Rand) []time.Time {
+	timestamps := make([]time.Time, count)
+	if count == 0 {
+		return timestamps
+	}
+
+	offsets := make([]float64, count)
+
+	switch distribution {
+	case config.DistributionRandom:
+		for i := range offsets {
+			offsets[i] = random.Float64()
+		}
+		slices.Sort(offsets)
+	case config.DistributionPoisson:
+		// Normalize exponential gaps so the last arrival still falls inside the window
+		var total float64
+		for i := range offsets {
+			total += random.ExpFloat64()
+			offsets[i] = total
+		}
+		total += random.ExpFloat64()
+		for i := range offsets {
+			offsets[i] /= total
+		}
+	default:
+		for i := range offsets {
+			offsets[i] = float64(i) / float64(count)
+		}
+	}
+
+	for i, offset := range offsets {
+		timestamp := start.Add(time.Duration(offset * float64(window)))
+		if jitter > 0 {
+			timestamp = timestamp.Add(time.Duration(random.Int63n(int64(2*jitter))) - jitter)
+		}
+		timestamps[i] = timestamp.UTC()
+	}
+
+	if jitter > 0 {
+		slices.SortFunc(timestamps, time.Time.Compare)
+	}
+
+	return timestamps
+}
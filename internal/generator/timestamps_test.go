@@ -0,0 +1,99 @@
+package generator
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/tehbooom/elastic-data/internal/config"
+)
+
+func TestSpreadTimestamps(t *testing.T) {
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	window := time.Minute
+
+	tests := []struct {
+		name         string
+		distribution string
+		count        int
+		jitter       time.Duration
+	}{
+		{name: "even", distribution: config.DistributionEven, count: 60},
+		{name: "default", count: 10},
+		{name: "random", distribution: config.DistributionRandom, count: 500},
+		{name: "poisson", distribution: config.DistributionPoisson, count: 500},
+		{name: "single", distribution: config.DistributionPoisson, count: 1},
+		{name: "none", distribution: config.DistributionRandom},
+		{name: "even with jitter", distribution: config.DistributionEven, count: 60, jitter: 5 * time.Second},
+		{name: "random with jitter", distribution: config.DistributionRandom, count: 500, jitter: time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timestamps := SpreadTimestamps(start, window, tt.count, tt.distribution, tt.jitter, rand.New(rand.NewSource(1)))
+			if len(timestamps) != tt.count {
+				t.Fatalf("got %d timestamps, want %d", len(timestamps), tt.count)
+			}
+
+			if !slices.IsSortedFunc(timestamps, time.Time.Compare) {
+				t.Errorf("timestamps are not ascending")
+			}
+
+			// Jitter moves timestamps at most jitter outside the window
+			from, to := start.Add(-tt.jitter), start.Add(window+tt.jitter)
+			for _, timestamp := range timestamps {
+				if timestamp.Before(from) || !timestamp.Before(to) {
+					t.Fatalf("timestamp %s outside [%s, %s)", timestamp, from, to)
+				}
+				if timestamp.Location() != time.UTC {
+					t.Fatalf("timestamp %s is not UTC", timestamp)
+				}
+			}
+		})
+	}
+}
+
+func TestSpreadTimestampsEven(t *testing.T) {
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	timestamps := SpreadTimestamps(start, time.Second, 4, config.DistributionEven, 0, rand.New(rand.NewSource(1)))
+	for i, timestamp := range timestamps {
+		if want := start.Add(time.Duration(i) * 250 * time.Millisecond); !timestamp.Equal(want) {
+			t.Errorf("timestamp %d = %s, want %s", i, timestamp, want)
+		}
+	}
+}
+
+func TestSpreadTimestampsSeeded(t *testing.T) {
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, distribution := range []string{config.DistributionRandom, config.DistributionPoisson} {
+		first := SpreadTimestamps(start, time.Hour, 100, distribution, time.Second, rand.New(rand.NewSource(7)))
+		second := SpreadTimestamps(start, time.Hour, 100, distribution, time.Second, rand.New(rand.NewSource(7)))
+		if !slices.Equal(first, second) {
+			t.Errorf("%s timestamps differ for the same seed", distribution)
+		}
+	}
+}
+
+// The random and poisson distributions cover the whole window instead of bunching up
+func TestSpreadTimestampsCoverWindow(t *testing.T) {
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	window := 10 * time.Second
+
+	for _, distribution := range []string{config.DistributionRandom, config.DistributionPoisson} {
+		timestamps := SpreadTimestamps(start, window, 1000, distribution, 0, rand.New(rand.NewSource(3)))
+
+		var buckets [10]int
+		for _, timestamp := range timestamps {
+			buckets[timestamp.Sub(start)/time.Second]++
+		}
+		for i, count := range buckets {
+			// 100 expected per second
+			if count < 50 || count > 150 {
+				t.Errorf("%s distribution has %d timestamps in second %d, want about 100", distribution, count, i)
+			}
+		}
+	}
+}
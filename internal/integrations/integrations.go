@@ -2,9 +2,15 @@ package integrations
 
 import (
 	"embed"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 
 	"github.com/charmbracelet/log"
 )
@@ -12,7 +18,52 @@ import (
 //go:embed templates
 var templatesFS embed.FS
 
+var (
+	// templatesDir is an optional directory whose templates take precedence over the embedded templates
+	templatesDir string
+	mu           sync.RWMutex
+)
+
+// SetTemplatesDir sets the directory searched for templates before the embedded templates.
+// The directory uses the same layout as the embedded templates: <integration>/<dataset>.tmpl
+func SetTemplatesDir(dir string) {
+	mu.Lock()
+	defer mu.Unlock()
+	templatesDir = dir
+}
+
+func getTemplatesDir() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return templatesDir
+}
+
+// ReadTemplate returns the contents of the template file for a dataset.
+// A template in the templates directory overrides the embedded template.
+func ReadTemplate(integration, dataset string) ([]byte, error) {
+	if dir := getTemplatesDir(); dir != "" {
+		content, err := os.ReadFile(filepath.Join(dir, integration, dataset+".tmpl"))
+		if err == nil {
+			log.Debug(fmt.Sprintf("Using template override for %s:%s from %s", integration, dataset, dir))
+			return content, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Debug(err)
+			return nil, fmt.Errorf("failed to read template for %s:%s: %w", integration, dataset, err)
+		}
+	}
+
+	content, err := templatesFS.ReadFile(path.Join("templates", integration, dataset+".tmpl"))
+	if err != nil {
+		log.Debug(err)
+		return nil, fmt.Errorf("template not found for %s:%s", integration, dataset)
+	}
+
+	return content, nil
+}
+
 // GetIntegrationsFromTemplates discovers integrations from embedded template files
+// and the templates directory
 func GetIntegrationsFromTemplates() ([]string, error) {
 	entries, err := templatesFS.ReadDir("templates")
 	if err != nil {
@@ -20,9 +71,11 @@ func GetIntegrationsFromTemplates() ([]string, error) {
 		return nil, fmt.Errorf("failed to read embedded templates directory: %w", err)
 	}
 
+	entries = append(entries, readTemplatesDir("")...)
+
 	var integrations []string
 	for _, entry := range entries {
-		if entry.IsDir() {
+		if entry.IsDir() && !slices.Contains(integrations, entry.Name()) {
 			integrations = append(integrations, entry.Name())
 		}
 	}
@@ -31,16 +84,24 @@ func GetIntegrationsFromTemplates() ([]string, error) {
 		return nil, fmt.Errorf("no integrations found in embedded templates")
 	}
 
+	slices.Sort(integrations)
+
 	return integrations, nil
 }
 
 // GetDatasetsFromTemplates gets datasets for an integration from embedded template files
+// and the templates directory
 func GetDatasetsFromTemplates(integration string) ([]string, error) {
-	integrationDir := filepath.Join("templates", integration)
-	
+	integrationDir := path.Join("templates", integration)
+
 	entries, err := templatesFS.ReadDir(integrationDir)
 	if err != nil {
 		log.Debug(err)
+	}
+
+	entries = append(entries, readTemplatesDir(integration)...)
+
+	if len(entries) == 0 {
 		return nil, fmt.Errorf("integration directory not found: %s", integrationDir)
 	}
 
@@ -49,7 +110,9 @@ func GetDatasetsFromTemplates(integration string) ([]string, error) {
 		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".tmpl") {
 			// Remove .tmpl extension to get dataset name
 			dataset := strings.TrimSuffix(entry.Name(), ".tmpl")
-			datasets = append(datasets, dataset)
+			if !slices.Contains(datasets, dataset) {
+				datasets = append(datasets, dataset)
+			}
 		}
 	}
 
@@ -57,5 +120,26 @@ func GetDatasetsFromTemplates(integration string) ([]string, error) {
 		return nil, fmt.Errorf("no datasets found for integration %s", integration)
 	}
 
+	slices.Sort(datasets)
+
 	return datasets, nil
 }
+
+// readTemplatesDir lists a directory relative to the templates directory.
+// A missing templates directory is not an error.
+func readTemplatesDir(subdir string) []fs.DirEntry {
+	dir := getTemplatesDir()
+	if dir == "" {
+		return nil
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, subdir))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Debug(err)
+		}
+		return nil
+	}
+
+	return entries
+}
@@ -2,10 +2,8 @@ package kibana
 
 import (
 	"context"
-	"crypto/tls"
 	"fmt"
 	"net/http"
-	"os"
 
 	"github.com/charmbracelet/log"
 	"github.com/tehbooom/elastic-data/internal/config"
@@ -13,6 +11,9 @@ import (
 	"github.com/tehbooom/go-kibana/kbapi"
 )
 
+// installedPackagesPerPage number of installed packages requested per page
+const installedPackagesPerPage = 100
+
 type Config struct {
 	Client    *kibana.Client
 	Ctx       context.Context
@@ -48,21 +49,14 @@ func SetClient(cfg config.ConfigConnection) (*kibana.Client, error) {
 		kbConfig.Password = cfg.Password
 	}
 
-	if cfg.Unsafe {
-		kbConfig.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
-			},
-		}
+	tlsConfig, err := cfg.TLSConfig()
+	if err != nil {
+		return nil, err
 	}
 
-	if cfg.CACert != "" {
-		cert, err := os.ReadFile(cfg.CACert)
-		if err != nil {
-			log.Debug(err)
-			return nil, fmt.Errorf("error reading certificate authority %s: %w", cfg.CACert, err)
-		}
-		kbConfig.CACert = cert
+	kbConfig.Transport = &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: tlsConfig,
 	}
 
 	client, err := kibana.NewClient(kbConfig)
@@ -74,8 +68,10 @@ func SetClient(cfg config.ConfigConnection) (*kibana.Client, error) {
 	return client, nil
 }
 
-func (c *Config) InstallPackage(pkgName string) error {
-	_, err := c.Client.EPM.InstallPackageRegistry(c.Ctx, &kbapi.FleetEPMInstallPackageRegistryRequest{
+// InstallPackage installs the latest version of a package. When version is set exactly
+// that version is installed, upgrading or downgrading an installed package.
+func (c *Config) InstallPackage(pkgName, version string) error {
+	req := &kbapi.FleetEPMInstallPackageRegistryRequest{
 		PackageName: pkgName,
 		Params: kbapi.FleetEPMInstallPackageRegistryRequestParams{
 			Prerelease: kbapi.BoolPtr(true),
@@ -83,28 +79,154 @@ func (c *Config) InstallPackage(pkgName string) error {
 		Body: kbapi.FleetEPMInstallPackageRegistryRequestBody{
 			Force: kbapi.BoolPtr(false),
 		},
-	})
+	}
+
+	if version != "" {
+		req.PackageVersion = &version
+		// Fleet refuses to replace an installed package with an older version without force
+		req.Body.Force = kbapi.BoolPtr(true)
+	}
 
+	_, err := c.Client.EPM.InstallPackageRegistry(c.Ctx, req)
 	if err != nil {
 		log.Debug(err)
-		return fmt.Errorf("failed to install package %s: %w", pkgName, err)
+		return fmt.Errorf("failed to install package %s %s: %w", pkgName, version, err)
 	}
 
 	return nil
 }
 
-func (c *Config) GetInstalledPackages() ([]string, error) {
-	resp, err := c.Client.EPM.GetPackagesInstalled(c.Ctx, &kbapi.FleetEPMGetInstalledPackagesRequest{})
+// UninstallPackage removes an installed package and the assets it installed, such as
+// its index templates and ingest pipelines. Fleet refuses to uninstall a package while
+// its data streams exist, delete them first.
+func (c *Config) UninstallPackage(pkgName string) error {
+	version, err := c.GetInstalledVersion(pkgName, "")
+	if err != nil {
+		return err
+	}
+
+	if version == "" {
+		return fmt.Errorf("package %s is not installed", pkgName)
+	}
+
+	_, err = c.Client.EPM.DeletePackage(c.Ctx, &kbapi.FleetEPMDeletePackageRequest{
+		PackageName:    pkgName,
+		PackageVersion: &version,
+		Params: kbapi.FleetEPMDeletePackageRequestParams{
+			Force: kbapi.BoolPtr(true),
+		},
+	})
 	if err != nil {
 		log.Debug(err)
-		return nil, fmt.Errorf("error getting install packages: %w", err)
+		return fmt.Errorf("failed to uninstall package %s: %w", pkgName, err)
+	}
+
+	return nil
+}
+
+// GetInstalledPackages returns the installed version of every installed package by name
+func (c *Config) GetInstalledPackages() (map[string]string, error) {
+	installed := make(map[string]string)
+	perPage := float64(installedPackagesPerPage)
+	var searchAfter []interface{}
+
+	for {
+		resp, err := c.Client.EPM.GetPackagesInstalled(c.Ctx, &kbapi.FleetEPMGetInstalledPackagesRequest{
+			Params: kbapi.FleetEPMGetInstalledPackagesRequestParams{
+				PerPage:     &perPage,
+				SearchAfter: searchAfter,
+			},
+		})
+		if err != nil {
+			log.Debug(err)
+			return nil, fmt.Errorf("error getting install packages: %w", err)
+		}
+
+		if resp.Body == nil {
+			return installed, nil
+		}
+
+		for _, integration := range resp.Body.Items {
+			installed[integration.Name] = integration.Version
+		}
+
+		if len(resp.Body.Items) < installedPackagesPerPage || len(resp.Body.SearchAfter) == 0 {
+			return installed, nil
+		}
+		searchAfter = resp.Body.SearchAfter
+	}
+}
+
+// GetInstalledVersion returns the installed version of a package, empty when it is not installed.
+// The package is looked up at version, the pinned version, or the latest version when it is empty.
+func (c *Config) GetInstalledVersion(pkgName, version string) (string, error) {
+	resp, err := c.getPackage(pkgName, version)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.Body == nil || resp.Body.Item.InstallationInfo == nil || resp.Body.Item.InstallationInfo.InstallStatus != "installed" {
+		return "", nil
+	}
+
+	return resp.Body.Item.InstallationInfo.Version, nil
+}
+
+// NeedsInstall reports whether a package is missing from installed or, when version
+// is set, installed at another version
+func NeedsInstall(installed map[string]string, pkgName, version string) bool {
+	installedVersion, exists := installed[pkgName]
+	if !exists {
+		return true
+	}
+
+	return version != "" && installedVersion != version
+}
+
+// GetDataStreamType returns the data stream type (logs, metrics, ...) of a dataset as
+// defined in the manifest of the package at version, the latest version when it is empty
+func (c *Config) GetDataStreamType(pkgName, version, dataset string) (string, error) {
+	resp, err := c.getPackage(pkgName, version)
+	if err != nil {
+		return "", err
 	}
 
-	var integrations []string
+	if resp.Body == nil || resp.Body.Item.DataStreams == nil {
+		return "", fmt.Errorf("package %s has no data streams", pkgName)
+	}
+
+	for _, dataStream := range *resp.Body.Item.DataStreams {
+		path, _ := dataStream["path"].(string)
+		name, _ := dataStream["dataset"].(string)
+		if path != dataset && name != pkgName+"."+dataset {
+			continue
+		}
+
+		if dataStreamType, ok := dataStream["type"].(string); ok && dataStreamType != "" {
+			return dataStreamType, nil
+		}
+	}
+
+	return "", fmt.Errorf("data stream %s not found in package %s", dataset, pkgName)
+}
 
-	for _, integration := range resp.Body.Items {
-		integrations = append(integrations, integration.Name)
+// getPackage returns a package at version, the latest version when it is empty
+func (c *Config) getPackage(pkgName, version string) (*kbapi.FleetEPMGetPackageResponse, error) {
+	req := &kbapi.FleetEPMGetPackageRequest{
+		PackageName: pkgName,
+		Params: kbapi.FleetEPMGetPackageRequestParams{
+			Prerelease: kbapi.BoolPtr(true),
+		},
+	}
+	if version != "" {
+		req.PackageVersion = &version
+	}
+
+	resp, err := c.Client.EPM.GetPackage(c.Ctx, req)
+	if err != nil {
+		log.Debug(err)
+		return nil, fmt.Errorf("failed to get package %s %s: %w", pkgName, version, err)
 	}
 
-	return integrations, nil
+	return resp, nil
 }
@@ -0,0 +1,162 @@
+package output
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/tehbooom/elastic-data/internal/elasticsearch"
+	"github.com/tehbooom/elastic-data/internal/generator"
+)
+
+// File writes events to a file, one per line. Once the file reaches maxSize it is
+// renamed with a numeric suffix and a new file is started, keeping maxFiles rotated files.
+type File struct {
+	path     string
+	format   string
+	maxSize  int64
+	maxFiles int
+
+	mu     sync.Mutex
+	file   *os.File
+	writer *bufio.Writer
+	size   int64
+}
+
+// NewFile opens path for appending, creating it and its directory when missing
+func NewFile(path, format string, maxSize int64, maxFiles int) (*File, error) {
+	f := &File{
+		path:     path,
+		format:   format,
+		maxSize:  maxSize,
+		maxFiles: maxFiles,
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		log.Debug(err)
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *File) Write(_ string, events []generator.Event) (result elasticsearch.BulkResult, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	start := time.Now()
+	defer func() {
+		result.Duration = time.Since(start)
+	}()
+
+	// Events are only written once the buffer holding them is flushed,
+	// written counts the events and bytes of the batch flushed so far
+	var written, writtenBytes int
+	fail := func(err error) {
+		addFailure(&result, "write_failed", err, len(events)-written)
+		result.Indexed, result.IndexedBytes = written, writtenBytes
+	}
+
+	for i, event := range events {
+		line := encode(event, f.format)
+
+		if f.size > 0 && f.size+int64(len(line))+1 > f.maxSize {
+			if err := f.rotate(); err != nil {
+				fail(err)
+				return result, err
+			}
+			written, writtenBytes = i, result.IndexedBytes
+		}
+
+		// The line is the document of the event, write the newline on its own
+		n, err := f.writer.Write(line)
+		f.size += int64(n)
+		if err == nil {
+			err = f.writer.WriteByte('\n')
+			f.size++
+		}
+		if err != nil {
+			log.Debug(err)
+			fail(err)
+			return result, fmt.Errorf("failed to write to %s: %w", f.path, err)
+		}
+		result.Indexed++
+		result.IndexedBytes += len(event.Document)
+	}
+
+	if err := f.writer.Flush(); err != nil {
+		log.Debug(err)
+		fail(err)
+		return result, fmt.Errorf("failed to write to %s: %w", f.path, err)
+	}
+
+	return result, nil
+}
+
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.writer.Flush(); err != nil {
+		log.Debug(err)
+	}
+	return f.file.Close()
+}
+
+func (f *File) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		log.Debug(err)
+		return fmt.Errorf("failed to open output file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		log.Debug(err)
+		file.Close()
+		return fmt.Errorf("failed to stat output file: %w", err)
+	}
+
+	f.file = file
+	f.writer = bufio.NewWriter(file)
+	f.size = info.Size()
+
+	return nil
+}
+
+// rotate shifts path.1 to path.2 and so on, dropping the oldest file,
+// moves the current file to path.1 and opens a new one
+func (f *File) rotate() error {
+	if err := f.writer.Flush(); err != nil {
+		log.Debug(err)
+		return fmt.Errorf("failed to write to %s: %w", f.path, err)
+	}
+	if err := f.file.Close(); err != nil {
+		log.Debug(err)
+	}
+
+	for i := f.maxFiles - 1; i >= 1; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", f.path, i), fmt.Sprintf("%s.%d", f.path, i+1))
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Debug(err)
+			return fmt.Errorf("failed to rotate %s: %w", f.path, err)
+		}
+	}
+
+	if err := os.Rename(f.path, f.path+".1"); err != nil {
+		log.Debug(err)
+		return fmt.Errorf("failed to rotate %s: %w", f.path, err)
+	}
+
+	return f.open()
+}
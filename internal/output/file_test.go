@@ -0,0 +1,167 @@
+package output
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tehbooom/elastic-data/internal/config"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestFileFormats(t *testing.T) {
+	events := renderEvents(t, "user logged in", `{"user":"alice"}`)
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{config.FormatNDJSON, `{"@timestamp":"2024-05-01T12:30:00Z","message":"user logged in"}` + "\n" +
+			`{"@timestamp":"2024-05-01T12:30:00Z","user":"alice"}` + "\n"},
+		{config.FormatRaw, "user logged in\n" + `{"user":"alice"}` + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "nested", "events.log")
+			file, err := NewFile(path, tt.format, 1<<20, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := file.Write("logs-test.logs-default", events)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := file.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if result.Indexed != 2 {
+				t.Errorf("indexed %d, want 2", result.Indexed)
+			}
+			// Indexed bytes are the size of the documents whatever the format
+			if want := len(events[0].Document) + len(events[1].Document); result.IndexedBytes != want {
+				t.Errorf("indexed %d bytes, want %d", result.IndexedBytes, want)
+			}
+			if got := readFile(t, path); got != tt.want {
+				t.Errorf("wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileAppends(t *testing.T) {
+	events := renderEvents(t, "first")
+	path := filepath.Join(t.TempDir(), "events.log")
+	if err := os.WriteFile(path, []byte("existing\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := NewFile(path, config.FormatRaw, 1<<20, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.Write("", events); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	if got := readFile(t, path); got != "existing\nfirst\n" {
+		t.Errorf("wrote %q", got)
+	}
+}
+
+func TestFileRotation(t *testing.T) {
+	// Every line is 6 bytes, so each file holds two lines
+	events := renderEvents(t, "line1", "line2", "line3", "line4", "line5", "line6", "line7", "line8")
+	path := filepath.Join(t.TempDir(), "events.log")
+
+	file, err := NewFile(path, config.FormatRaw, 12, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotation happens within and across batches
+	for _, batch := range [][2]int{{0, 3}, {3, 4}, {4, 8}} {
+		if _, err := file.Write("", events[batch[0]:batch[1]]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		path:        "line7\nline8\n",
+		path + ".1": "line5\nline6\n",
+		path + ".2": "line3\nline4\n",
+	}
+	for name, content := range want {
+		if got := readFile(t, name); got != content {
+			t.Errorf("%s = %q, want %q", filepath.Base(name), got, content)
+		}
+	}
+
+	// Only maxFiles rotated files are kept
+	if _, err := os.Stat(path + ".3"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("oldest file was kept: %v", err)
+	}
+}
+
+func TestFileRotationInBatch(t *testing.T) {
+	events := renderEvents(t, "line1", "line2", "line3")
+	path := filepath.Join(t.TempDir(), "events.log")
+
+	file, err := NewFile(path, config.FormatRaw, 12, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := file.Write("", events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	if result.Indexed != 3 {
+		t.Errorf("indexed %d, want 3", result.Indexed)
+	}
+	if got := readFile(t, path+".1"); got != "line1\nline2\n" {
+		t.Errorf("rotated file = %q", got)
+	}
+	if got := readFile(t, path); got != "line3\n" {
+		t.Errorf("current file = %q", got)
+	}
+}
+
+func TestFileFlushFailure(t *testing.T) {
+	events := renderEvents(t, "first", "second")
+	path := filepath.Join(t.TempDir(), "events.log")
+
+	file, err := NewFile(path, config.FormatRaw, 1<<20, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The events fit in the buffer, writing them fails when it is flushed
+	file.file.Close()
+
+	result, err := file.Write("", events)
+	if err == nil {
+		t.Fatal("Write() to a closed file succeeded")
+	}
+	if result.Indexed != 0 || result.IndexedBytes != 0 || result.Failed != 2 || result.Failures["write_failed"].Count != 2 {
+		t.Errorf("indexed %d events and %d bytes, failed %d, failures %v",
+			result.Indexed, result.IndexedBytes, result.Failed, result.Failures)
+	}
+}
@@ -0,0 +1,78 @@
+package output
+
+import (
+	"fmt"
+
+	"github.com/tehbooom/elastic-data/internal/config"
+	"github.com/tehbooom/elastic-data/internal/elasticsearch"
+	"github.com/tehbooom/elastic-data/internal/generator"
+)
+
+// Sink is where a generator writes its events
+type Sink interface {
+	// Write sends the events of a batch for index. Events that were not written
+	// are reported as failures in the result.
+	Write(index string, events []generator.Event) (elasticsearch.BulkResult, error)
+	// Close flushes and releases the sink
+	Close() error
+}
+
+// New returns the sink configured by cfg. Events are indexed with client
+// unless another output type is set.
+func New(cfg config.OutputConfig, client *elasticsearch.Config) (Sink, error) {
+	cfg = cfg.WithDefaults()
+
+	switch cfg.Type {
+	case config.OutputElasticsearch:
+		return &Elasticsearch{client: client}, nil
+	case config.OutputFile:
+		return NewFile(cfg.Path, cfg.Format, cfg.MaxSize, cfg.MaxFiles)
+	case config.OutputStdout:
+		return NewStdout(cfg.Format), nil
+	case config.OutputSyslog:
+		return NewSyslog(cfg.Network, cfg.Address, cfg.Protocol, cfg.AppName)
+	}
+
+	return nil, fmt.Errorf("unknown output type %s", cfg.Type)
+}
+
+// Elasticsearch indexes events with bulk requests
+type Elasticsearch struct {
+	client *elasticsearch.Config
+}
+
+func (e *Elasticsearch) Write(index string, events []generator.Event) (elasticsearch.BulkResult, error) {
+	documents := make([][]byte, len(events))
+	for i, event := range events {
+		documents[i] = event.Document
+	}
+	return e.client.BulkDocuments(index, documents)
+}
+
+func (e *Elasticsearch) Close() error {
+	return nil
+}
+
+// encode formats an event as an NDJSON document or its raw log line without a newline
+func encode(event generator.Event, format string) []byte {
+	if format == config.FormatRaw {
+		return []byte(event.Raw())
+	}
+	return event.Document
+}
+
+// addFailure records count events that were not written because of err
+func addFailure(result *elasticsearch.BulkResult, errorType string, err error, count int) {
+	if result.Failures == nil {
+		result.Failures = make(map[string]*elasticsearch.BulkFailure)
+	}
+
+	failure, exists := result.Failures[errorType]
+	if !exists {
+		failure = &elasticsearch.BulkFailure{Reason: err.Error()}
+		result.Failures[errorType] = failure
+	}
+
+	failure.Count += count
+	result.Failed += count
+}
@@ -0,0 +1,126 @@
+package output
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tehbooom/elastic-data/internal/config"
+	"github.com/tehbooom/elastic-data/internal/generator"
+	"github.com/tehbooom/elastic-data/internal/integrations"
+)
+
+var eventTime = time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+
+// renderEvents renders an event of every template at eventTime
+func renderEvents(t *testing.T, templates ...string) []generator.Event {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "test"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := strings.Join(templates, "\n---EVENT_DELIMITER---\n")
+	if err := os.WriteFile(filepath.Join(dir, "test", "logs.tmpl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	integrations.SetTemplatesDir(dir)
+	defer integrations.SetTemplatesDir("")
+
+	logTemplates, _, err := generator.LoadTemplatesForDataset("test", "logs", &config.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	renderer := generator.NewRenderer()
+	events := make([]generator.Event, len(logTemplates))
+	for i, logTemplate := range logTemplates {
+		events[i], err = renderer.Render(logTemplate, eventTime, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return events
+}
+
+func TestEncode(t *testing.T) {
+	events := renderEvents(t, "user logged in", `{"user":"alice","@timestamp":"old"}`)
+
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{config.FormatNDJSON, []string{
+			`{"@timestamp":"2024-05-01T12:30:00Z","message":"user logged in"}`,
+			`{"@timestamp":"2024-05-01T12:30:00Z","user":"alice"}`,
+		}},
+		{config.FormatRaw, []string{
+			"user logged in",
+			`{"user":"alice"}`,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			for i, event := range events {
+				if got := string(encode(event, tt.format)); got != tt.want[i] {
+					t.Errorf("encode() = %s, want %s", got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestStdout(t *testing.T) {
+	events := renderEvents(t, "first", "second")
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+
+	result, err := NewStdout(config.FormatRaw).Write("logs-test.logs-default", events)
+	writer.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := make([]byte, 64)
+	n, _ := reader.Read(output)
+	if string(output[:n]) != "first\nsecond\n" {
+		t.Errorf("wrote %q", output[:n])
+	}
+	if result.Indexed != 2 || result.Failed != 0 {
+		t.Errorf("indexed %d, failed %d", result.Indexed, result.Failed)
+	}
+	if want := len(events[0].Document) + len(events[1].Document); result.IndexedBytes != want {
+		t.Errorf("indexed %d bytes, want %d", result.IndexedBytes, want)
+	}
+}
+
+func TestStdoutFailure(t *testing.T) {
+	events := renderEvents(t, "first", "second")
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader.Close()
+	writer.Close()
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() { os.Stdout = stdout }()
+
+	result, err := NewStdout(config.FormatNDJSON).Write("logs-test.logs-default", events)
+	if err == nil {
+		t.Fatal("Write() to a closed stdout succeeded")
+	}
+	if result.Indexed != 0 || result.Failed != 2 || result.Failures["write_failed"].Count != 2 {
+		t.Errorf("indexed %d, failed %d, failures %v", result.Indexed, result.Failed, result.Failures)
+	}
+}
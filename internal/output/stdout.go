@@ -0,0 +1,57 @@
+package output
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/tehbooom/elastic-data/internal/elasticsearch"
+	"github.com/tehbooom/elastic-data/internal/generator"
+)
+
+// stdoutMu keeps the batches of generators sharing stdout from interleaving
+var stdoutMu sync.Mutex
+
+// Stdout writes events to stdout, one per line
+type Stdout struct {
+	format string
+}
+
+func NewStdout(format string) *Stdout {
+	return &Stdout{format: format}
+}
+
+func (s *Stdout) Write(_ string, events []generator.Event) (elasticsearch.BulkResult, error) {
+	var result elasticsearch.BulkResult
+	start := time.Now()
+
+	var buffer bytes.Buffer
+	var documentBytes int
+	for _, event := range events {
+		buffer.Write(encode(event, s.format))
+		buffer.WriteByte('\n')
+		documentBytes += len(event.Document)
+	}
+
+	stdoutMu.Lock()
+	_, err := os.Stdout.Write(buffer.Bytes())
+	stdoutMu.Unlock()
+
+	result.Duration = time.Since(start)
+	if err != nil {
+		log.Debug(err)
+		addFailure(&result, "write_failed", err, len(events))
+		return result, fmt.Errorf("failed to write to stdout: %w", err)
+	}
+	result.Indexed = len(events)
+	result.IndexedBytes = documentBytes
+
+	return result, nil
+}
+
+func (s *Stdout) Close() error {
+	return nil
+}
@@ -0,0 +1,151 @@
+package output
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/tehbooom/elastic-data/internal/config"
+	"github.com/tehbooom/elastic-data/internal/elasticsearch"
+	"github.com/tehbooom/elastic-data/internal/generator"
+)
+
+const (
+	// syslogPriority user-level facility with informational severity
+	syslogPriority = 14
+	syslogTimeout  = 10 * time.Second
+)
+
+// syslogPriorityRegex matches the <PRI> header starting a syslog message
+var syslogPriorityRegex = regexp.MustCompile(`^<\d{1,3}>`)
+
+// Syslog sends the raw line of every event to a syslog receiver over TCP or UDP.
+// TCP messages are separated by newlines and every UDP datagram holds one message.
+type Syslog struct {
+	network  string
+	address  string
+	protocol string
+	appName  string
+	hostname string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslog connects to the syslog receiver at address
+func NewSyslog(network, address, protocol, appName string) (*Syslog, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.Debug(err)
+		hostname = "localhost"
+	}
+
+	s := &Syslog{
+		network:  network,
+		address:  address,
+		protocol: protocol,
+		appName:  appName,
+		hostname: hostname,
+	}
+
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Syslog) Write(_ string, events []generator.Event) (result elasticsearch.BulkResult, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	start := time.Now()
+	defer func() {
+		result.Duration = time.Since(start)
+	}()
+
+	for i, event := range events {
+		message := s.format(event)
+		if s.network == "tcp" {
+			message += "\n"
+		}
+
+		if err := s.send(message); err != nil {
+			addFailure(&result, "write_failed", err, len(events)-i)
+			return result, err
+		}
+		result.Indexed++
+		result.IndexedBytes += len(event.Document)
+	}
+
+	return result, nil
+}
+
+func (s *Syslog) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+// send writes a message, reconnecting once when the connection was lost
+func (s *Syslog) send(message string) error {
+	if s.conn != nil {
+		s.conn.SetWriteDeadline(time.Now().Add(syslogTimeout))
+		_, err := s.conn.Write([]byte(message))
+		if err == nil {
+			return nil
+		}
+		log.Debug(err)
+		s.conn.Close()
+		s.conn = nil
+	}
+
+	if err := s.connect(); err != nil {
+		return err
+	}
+
+	s.conn.SetWriteDeadline(time.Now().Add(syslogTimeout))
+	if _, err := s.conn.Write([]byte(message)); err != nil {
+		log.Debug(err)
+		return fmt.Errorf("failed to send syslog message to %s: %w", s.address, err)
+	}
+
+	return nil
+}
+
+func (s *Syslog) connect() error {
+	conn, err := net.DialTimeout(s.network, s.address, syslogTimeout)
+	if err != nil {
+		log.Debug(err)
+		return fmt.Errorf("failed to connect to syslog receiver %s: %w", s.address, err)
+	}
+
+	s.conn = conn
+	return nil
+}
+
+// format builds the syslog message of an event with its @timestamp as the message time.
+// Lines that already are syslog messages are sent unchanged.
+func (s *Syslog) format(event generator.Event) string {
+	timestamp := event.Timestamp
+	// A message is a single line, embedded newlines would split it into several events
+	message := strings.ReplaceAll(event.Raw(), "\n", " ")
+	if syslogPriorityRegex.MatchString(message) {
+		return message
+	}
+
+	if s.protocol == config.SyslogRFC3164 {
+		return fmt.Sprintf("<%d>%s %s %s: %s", syslogPriority, timestamp.Local().Format(time.Stamp), s.hostname, s.appName, message)
+	}
+
+	return fmt.Sprintf("<%d>1 %s %s %s - - - %s", syslogPriority, timestamp.UTC().Format("2006-01-02T15:04:05.000000Z07:00"), s.hostname, s.appName, message)
+}
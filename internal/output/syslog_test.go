@@ -0,0 +1,125 @@
+package output
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tehbooom/elastic-data/internal/config"
+)
+
+func TestSyslogFormat(t *testing.T) {
+	events := renderEvents(t,
+		"user logged in",
+		"first line\nsecond line",
+		"<34>Oct 11 22:14:15 mymachine su: 'su root' failed",
+		"<165>1 2003-10-11T22:14:15.003Z mymachine evntslog - ID47 - An application event",
+		"<not a priority> message",
+		`{"user":"alice"}`,
+	)
+
+	rfc5424 := &Syslog{protocol: config.SyslogRFC5424, hostname: "generator", appName: "elastic-data"}
+	rfc3164 := &Syslog{protocol: config.SyslogRFC3164, hostname: "generator", appName: "elastic-data"}
+	stamp := eventTime.Local().Format(time.Stamp)
+
+	tests := []struct {
+		name   string
+		syslog *Syslog
+		event  int
+		want   string
+	}{
+		{"rfc5424", rfc5424, 0, "<14>1 2024-05-01T12:30:00.000000Z generator elastic-data - - - user logged in"},
+		{"rfc3164", rfc3164, 0, "<14>" + stamp + " generator elastic-data: user logged in"},
+		{"multiline", rfc5424, 1, "<14>1 2024-05-01T12:30:00.000000Z generator elastic-data - - - first line second line"},
+		{"rfc3164 message", rfc5424, 2, "<34>Oct 11 22:14:15 mymachine su: 'su root' failed"},
+		{"rfc5424 message", rfc3164, 3, "<165>1 2003-10-11T22:14:15.003Z mymachine evntslog - ID47 - An application event"},
+		{"not a priority", rfc3164, 4, "<14>" + stamp + " generator elastic-data: <not a priority> message"},
+		{"json", rfc5424, 5, `<14>1 2024-05-01T12:30:00.000000Z generator elastic-data - - - {"user":"alice"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.syslog.format(events[tt.event]); got != tt.want {
+				t.Errorf("format() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyslogTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	received := make(chan []string)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			close(received)
+			return
+		}
+		defer conn.Close()
+
+		var lines []string
+		scanner := bufio.NewScanner(conn)
+		for len(lines) < 2 && scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		received <- lines
+	}()
+
+	syslog, err := NewSyslog("tcp", listener.Addr().String(), config.SyslogRFC5424, "elastic-data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer syslog.Close()
+
+	events := renderEvents(t, "<13>1 - host app - - - already formatted", "plain")
+	result, err := syslog.Write("", events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Indexed != 2 {
+		t.Errorf("indexed %d, want 2", result.Indexed)
+	}
+
+	lines := <-received
+	if len(lines) != 2 || lines[0] != "<13>1 - host app - - - already formatted" || !strings.HasSuffix(lines[1], " - - - plain") {
+		t.Errorf("received %q", lines)
+	}
+}
+
+func TestSyslogUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	syslog, err := NewSyslog("udp", conn.LocalAddr().String(), config.SyslogRFC3164, "elastic-data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer syslog.Close()
+
+	if _, err := syslog.Write("", renderEvents(t, "first", "second")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every datagram holds a single message without a trailing newline
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buffer := make([]byte, 1024)
+	for _, want := range []string{"first", "second"} {
+		n, _, err := conn.ReadFrom(buffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if message := string(buffer[:n]); !strings.HasSuffix(message, "elastic-data: "+want) {
+			t.Errorf("received %q, want message %q", message, want)
+		}
+	}
+}
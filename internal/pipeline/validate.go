@@ -0,0 +1,150 @@
+package pipeline
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/tehbooom/elastic-data/internal/config"
+	"github.com/tehbooom/elastic-data/internal/elasticsearch"
+	"github.com/tehbooom/elastic-data/internal/generator"
+	"github.com/tehbooom/elastic-data/internal/kibana"
+)
+
+// simulateBatchSize maximum number of events sent in a single simulate request
+const simulateBatchSize = 500
+
+// Report is the outcome of running the templates of a dataset through its ingest pipeline
+type Report struct {
+	Integration string
+	Dataset     string
+	Pipeline    string
+	Results     []TemplateResult
+}
+
+// TemplateResult is the outcome of a template, which fails when any of its events
+// fails to render or is rejected by the pipeline
+type TemplateResult struct {
+	Template string
+	// Error the first error of the template, empty when it passed
+	Error string
+}
+
+// Failed returns the number of templates that failed
+func (r *Report) Failed() int {
+	var failed int
+	for _, result := range r.Results {
+		if result.Error != "" {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Validator renders events of a dataset and simulates them in the ingest pipeline
+// of the installed package, installing the package when it is missing
+type Validator struct {
+	Config *config.Config
+	ES     *elasticsearch.Config
+	KB     *kibana.Config
+	// Samples number of events rendered from every template, defaults to 1
+	Samples int
+}
+
+// Validate reports for every template of the dataset whether its events pass the pipeline.
+// Nothing is indexed.
+func (v *Validator) Validate(integration, dataset string) (*Report, error) {
+	report := &Report{Integration: integration, Dataset: dataset}
+
+	templates, failed, err := generator.LoadTemplatesForDataset(integration, dataset, v.Config)
+	if err != nil && len(failed) == 0 {
+		return nil, err
+	}
+
+	for _, templateErr := range failed {
+		report.Results = append(report.Results, TemplateResult{Template: templateErr.Name, Error: templateErr.Err.Error()})
+	}
+
+	if len(templates) == 0 {
+		return report, nil
+	}
+
+	if err := v.installPackage(integration); err != nil {
+		return nil, err
+	}
+
+	datasetConfig := v.Config.Integrations[integration].Datasets[dataset]
+
+	dataStreamType := datasetConfig.Type
+	if dataStreamType == "" {
+		dataStreamType, err = v.KB.GetDataStreamType(integration, v.Config.Integrations[integration].Version, dataset)
+		if err != nil {
+			log.Debug(err)
+			dataStreamType = config.DefaultDataStreamType
+		}
+	}
+
+	report.Pipeline, err = v.ES.DefaultPipeline(fmt.Sprintf("%s-%s.%s", dataStreamType, integration, dataset))
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := v.Config.GetNamespace(integration, datasetConfig.Namespace)
+	index := config.DataStreamName(dataStreamType, integration, dataset, namespace)
+
+	// Render every template, keeping track of the template each event came from
+	var events []map[string]interface{}
+	var owners []int
+	results := make([]TemplateResult, len(templates))
+	samples := max(v.Samples, 1)
+
+	for i, template := range templates {
+		results[i].Template = template.Template.Name()
+
+		for range samples {
+			now := time.Now()
+			template.UpdateValuesAt(now)
+			event, _, err := template.Document(now, datasetConfig.PreserveEventOriginal)
+			if err != nil {
+				results[i].Error = err.Error()
+				break
+			}
+			events = append(events, event)
+			owners = append(owners, i)
+		}
+	}
+
+	for start := 0; start < len(events); start += simulateBatchSize {
+		end := min(start+simulateBatchSize, len(events))
+
+		errs, err := v.ES.SimulatePipeline(report.Pipeline, index, events[start:end])
+		if err != nil {
+			return nil, err
+		}
+
+		for j, eventErr := range errs {
+			owner := owners[start+j]
+			if eventErr != "" && results[owner].Error == "" {
+				results[owner].Error = eventErr
+			}
+		}
+	}
+
+	report.Results = append(report.Results, results...)
+	return report, nil
+}
+
+func (v *Validator) installPackage(integration string) error {
+	installed, err := v.KB.GetInstalledPackages()
+	if err != nil {
+		return err
+	}
+
+	version := v.Config.Integrations[integration].Version
+	if !kibana.NeedsInstall(installed, integration, version) {
+		return nil
+	}
+
+	log.Debug(fmt.Sprintf("Installing Package %s %s", integration, version))
+	return v.KB.InstallPackage(integration, version)
+}
@@ -0,0 +1,168 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	es "github.com/elastic/go-elasticsearch/v8"
+	"github.com/tehbooom/elastic-data/internal/config"
+	"github.com/tehbooom/elastic-data/internal/elasticsearch"
+	"github.com/tehbooom/elastic-data/internal/integrations"
+	"github.com/tehbooom/elastic-data/internal/kibana"
+	gokibana "github.com/tehbooom/go-kibana"
+)
+
+// cluster fakes the index template and simulate APIs of Elasticsearch, rejecting the
+// events whose message contains "bad", and the Fleet packages API
+type cluster struct {
+	// simulated number of events of every simulate request
+	simulated []int
+}
+
+func (c *cluster) handleElasticsearch(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	w.Header().Set("Content-Type", "application/json")
+
+	switch {
+	case r.URL.Path == "/_index_template/metrics-acme.auth":
+		fmt.Fprint(w, `{"index_templates":[{"name":"metrics-acme.auth","index_template":{"index_patterns":["metrics-acme.auth-*"],"composed_of":[],`+
+			`"template":{"settings":{"index":{"default_pipeline":"metrics-acme.auth-1.0.0"}}}}}]}`)
+	case r.URL.Path == "/_ingest/pipeline/metrics-acme.auth-1.0.0/_simulate":
+		var request struct {
+			Docs []struct {
+				Source map[string]interface{} `json:"_source"`
+			} `json:"docs"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		c.simulated = append(c.simulated, len(request.Docs))
+
+		docs := make([]string, len(request.Docs))
+		for i, doc := range request.Docs {
+			message, _ := doc.Source["message"].(string)
+			if strings.Contains(message, "bad") {
+				docs[i] = fmt.Sprintf(`{"error":{"type":"illegal_argument_exception","reason":%q}}`, message)
+				continue
+			}
+			source, _ := json.Marshal(doc.Source)
+			docs[i] = fmt.Sprintf(`{"doc":{"_id":"%d","_index":"metrics-acme.auth-default","_source":%s,"_ingest":{"timestamp":"2025-06-01T00:00:00Z"}}}`, i, source)
+		}
+		fmt.Fprintf(w, `{"docs":[%s]}`, strings.Join(docs, ","))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (c *cluster) handleFleet(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.URL.Path {
+	case "/api/fleet/epm/packages/installed":
+		fmt.Fprint(w, `{"items":[{"name":"acme","version":"1.0.0"}],"total":1}`)
+	case "/api/fleet/epm/packages/acme":
+		fmt.Fprint(w, `{"item":{"name":"acme","version":"1.0.0","data_streams":[{"type":"metrics","dataset":"acme.auth","path":"auth"}]}}`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newValidator(t *testing.T, events ...string) (*Validator, *cluster) {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "acme"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := strings.Join(events, "\n---EVENT_DELIMITER---\n")
+	if err := os.WriteFile(filepath.Join(dir, "acme", "auth.tmpl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	integrations.SetTemplatesDir(dir)
+	t.Cleanup(func() { integrations.SetTemplatesDir("") })
+
+	c := &cluster{}
+	esServer := httptest.NewServer(http.HandlerFunc(c.handleElasticsearch))
+	t.Cleanup(esServer.Close)
+	kbServer := httptest.NewServer(http.HandlerFunc(c.handleFleet))
+	t.Cleanup(kbServer.Close)
+
+	esClient, err := es.NewTypedClient(es.Config{Addresses: []string{esServer.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kbClient, err := gokibana.NewClient(gokibana.Config{Addresses: []string{kbServer.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Validator{
+		Config: &config.Config{Replacements: config.Replacements{Users: []string{"alice"}}},
+		ES:     &elasticsearch.Config{Client: esClient, Ctx: context.Background()},
+		KB:     &kibana.Config{Client: kbClient, Ctx: context.Background()},
+	}, c
+}
+
+func TestValidate(t *testing.T) {
+	validator, cluster := newValidator(t, "login ok for {{.Users}}", "login bad for {{.Users}}", "broken {{ template")
+
+	report, err := validator.Validate("acme", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Pipeline != "metrics-acme.auth-1.0.0" {
+		t.Errorf("pipeline %s, want the pipeline of the metrics data stream", report.Pipeline)
+	}
+
+	want := []TemplateResult{
+		{Template: "acme_auth_2", Error: report.Results[0].Error},
+		{Template: "acme_auth_0"},
+		{Template: "acme_auth_1", Error: "illegal_argument_exception: login bad for alice"},
+	}
+	if fmt.Sprint(report.Results) != fmt.Sprint(want) {
+		t.Errorf("results %+v, want %+v", report.Results, want)
+	}
+	if report.Results[0].Error == "" {
+		t.Error("template that does not parse passed")
+	}
+	if report.Failed() != 2 {
+		t.Errorf("%d failed templates, want 2", report.Failed())
+	}
+	if fmt.Sprint(cluster.simulated) != "[2]" {
+		t.Errorf("simulated %v events", cluster.simulated)
+	}
+}
+
+// Failures of events simulated in a later batch are reported for their own template
+func TestValidateBatches(t *testing.T) {
+	validator, cluster := newValidator(t, "first ok", "second ok", "third bad")
+	validator.Samples = 300
+
+	report, err := validator.Validate("acme", "auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The events of the second template are split across both batches
+	if fmt.Sprint(cluster.simulated) != fmt.Sprintf("[%d 400]", simulateBatchSize) {
+		t.Errorf("simulated %v events", cluster.simulated)
+	}
+
+	want := []TemplateResult{
+		{Template: "acme_auth_0"},
+		{Template: "acme_auth_1"},
+		{Template: "acme_auth_2", Error: "illegal_argument_exception: third bad"},
+	}
+	if fmt.Sprint(report.Results) != fmt.Sprint(want) {
+		t.Errorf("results %+v, want %+v", report.Results, want)
+	}
+}
@@ -0,0 +1,228 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/tehbooom/elastic-data/internal/config"
+	"github.com/tehbooom/elastic-data/internal/elasticsearch"
+	"github.com/tehbooom/elastic-data/internal/generator"
+	"github.com/tehbooom/elastic-data/internal/kibana"
+	"github.com/tehbooom/elastic-data/internal/session"
+)
+
+// Runner plays scenarios against Elasticsearch
+type Runner struct {
+	Config *config.Config
+	ES     *elasticsearch.Config
+	KB     *kibana.Config
+	// OnStep is called before each step starts
+	OnStep func(index int, step Step)
+	// Session records the data streams written to, it may be nil
+	Session *session.Recorder
+}
+
+// StepResult counts the events of a step
+type StepResult struct {
+	Step    string
+	Sent    int
+	Indexed int
+	Failed  int
+}
+
+// Run plays every step of the scenario in order until it is done or ctx is cancelled
+func (r *Runner) Run(ctx context.Context, scenario *Scenario) ([]StepResult, error) {
+	if err := r.installPackages(scenario); err != nil {
+		return nil, err
+	}
+
+	entity := r.entity(scenario)
+	log.Debug(fmt.Sprintf("Running scenario %s as %+v", scenario.Name, entity))
+
+	results := make([]StepResult, 0, len(scenario.Steps))
+	for i, step := range scenario.Steps {
+		if r.OnStep != nil {
+			r.OnStep(i, step)
+		}
+
+		if err := wait(ctx, step.Delay); err != nil {
+			return results, err
+		}
+
+		result, err := r.runStep(ctx, step, entity)
+		results = append(results, result)
+		if err != nil {
+			return results, fmt.Errorf("step %s of scenario %s failed: %w", result.Step, scenario.Name, err)
+		}
+	}
+
+	return results, nil
+}
+
+func (r *Runner) runStep(ctx context.Context, step Step, entity generator.Entity) (StepResult, error) {
+	result := StepResult{Step: step.Name}
+	if result.Step == "" {
+		result.Step = fmt.Sprintf("%s:%s", step.Integration, step.Dataset)
+	}
+
+	templates, err := r.templates(step)
+	if err != nil {
+		return result, err
+	}
+
+	index := r.index(step)
+	count := max(step.Count, 1)
+	r.Session.Add(index)
+
+	// Without an interval every event of the step is sent in a single request
+	batchSize := 1
+	if step.Interval == 0 {
+		batchSize = count
+	}
+
+	events := make([]map[string]interface{}, 0, batchSize)
+	for i := 0; i < count; i++ {
+		template := templates[rand.Intn(len(templates))]
+		timestamp := time.Now()
+
+		template.UpdateValuesWithEntity(timestamp, entity)
+		event, _, err := template.Document(timestamp, step.PreserveEventOriginal)
+		if err != nil {
+			log.Debug(err)
+			return result, err
+		}
+		events = append(events, event)
+
+		if len(events) < batchSize {
+			continue
+		}
+
+		bulkResult, err := r.ES.BulkRequest(index, events)
+		result.Sent += len(events)
+		result.Indexed += bulkResult.Indexed
+		result.Failed += bulkResult.Failed
+		if err != nil {
+			log.Debug(err)
+			return result, err
+		}
+		events = events[:0]
+
+		if i < count-1 {
+			if err := wait(ctx, step.Interval); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// templates returns the templates of the step dataset containing the match text
+func (r *Runner) templates(step Step) ([]*generator.LogTemplate, error) {
+	templates, err := generator.LoadPreGeneratedTemplatesForDataset(step.Integration, step.Dataset, r.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	if step.Match == "" {
+		return templates, nil
+	}
+
+	var matched []*generator.LogTemplate
+	for _, template := range templates {
+		if strings.Contains(template.Original, step.Match) {
+			matched = append(matched, template)
+		}
+	}
+
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("no templates for %s:%s contain %q", step.Integration, step.Dataset, step.Match)
+	}
+
+	return matched, nil
+}
+
+// index returns the data stream of the step, reading the type from the package
+// unless the step sets it
+func (r *Runner) index(step Step) string {
+	dataStreamType := step.Type
+	if dataStreamType == "" {
+		resolved, err := r.KB.GetDataStreamType(step.Integration, r.Config.Integrations[step.Integration].Version, step.Dataset)
+		if err != nil {
+			log.Debug(err)
+			resolved = config.DefaultDataStreamType
+		}
+		dataStreamType = resolved
+	}
+
+	namespace := r.Config.GetNamespace(step.Integration, step.Namespace)
+
+	return config.DataStreamName(dataStreamType, step.Integration, step.Dataset, namespace)
+}
+
+// entity returns the actor of the scenario with the values it sets taking precedence
+func (r *Runner) entity(scenario *Scenario) generator.Entity {
+	entity := generator.RandomEntity(&r.Config.Replacements)
+	if pool := generator.NewEntityPool(r.Config.Entities, &r.Config.Replacements); pool != nil {
+		entity = pool.At(time.Now(), rand.New(rand.NewSource(time.Now().UnixNano())))
+	}
+
+	override := func(value *string, configured string) {
+		if configured != "" {
+			*value = configured
+		}
+	}
+
+	override(&entity.User, scenario.Entity.User)
+	override(&entity.Host, scenario.Entity.Host)
+	override(&entity.IP, scenario.Entity.IP)
+	override(&entity.Email, scenario.Entity.Email)
+	override(&entity.Domain, scenario.Entity.Domain)
+
+	return entity
+}
+
+// installPackages installs the package of every integration used by the scenario
+func (r *Runner) installPackages(scenario *Scenario) error {
+	installed, err := r.KB.GetInstalledPackages()
+	if err != nil {
+		return err
+	}
+
+	for _, step := range scenario.Steps {
+		version := r.Config.Integrations[step.Integration].Version
+		if !kibana.NeedsInstall(installed, step.Integration, version) {
+			continue
+		}
+
+		log.Debug(fmt.Sprintf("Installing Package %s %s", step.Integration, version))
+		if err := r.KB.InstallPackage(step.Integration, version); err != nil {
+			log.Debug(err)
+			return err
+		}
+		installed[step.Integration] = version
+	}
+
+	return nil
+}
+
+// wait blocks for duration or until ctx is cancelled
+func wait(ctx context.Context, duration time.Duration) error {
+	if duration <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,282 @@
+package scenario
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	es "github.com/elastic/go-elasticsearch/v8"
+	"github.com/tehbooom/elastic-data/internal/config"
+	"github.com/tehbooom/elastic-data/internal/elasticsearch"
+	"github.com/tehbooom/elastic-data/internal/integrations"
+	"github.com/tehbooom/elastic-data/internal/kibana"
+	"github.com/tehbooom/elastic-data/internal/session"
+	gokibana "github.com/tehbooom/go-kibana"
+)
+
+const authTemplate = "login failed for {{.Users}} from {{.IPs}}\n---EVENT_DELIMITER---\nlogin succeeded for {{.Users}} on {{.Hosts}}"
+
+// cluster fakes the Elasticsearch bulk API and the Fleet packages API
+type cluster struct {
+	mu sync.Mutex
+	// requests documents of every bulk request by index
+	requests map[string][][]map[string]interface{}
+	// installed packages and packages installed by the runner
+	installed map[string]string
+	installs  []string
+}
+
+func (c *cluster) handleBulk(w http.ResponseWriter, r *http.Request) {
+	index := strings.TrimPrefix(strings.TrimSuffix(r.URL.Path, "/_bulk"), "/")
+
+	var documents []map[string]interface{}
+	var items []string
+	scanner := bufio.NewScanner(r.Body)
+	for line := 0; scanner.Scan(); line++ {
+		// Every other line is the action line
+		if line%2 == 0 {
+			continue
+		}
+
+		var document map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &document); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		documents = append(documents, document)
+		items = append(items, fmt.Sprintf(`{"create":{"_index":%q,"status":201}}`, index))
+	}
+
+	c.mu.Lock()
+	c.requests[index] = append(c.requests[index], documents)
+	c.mu.Unlock()
+
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"took":1,"errors":false,"items":[%s]}`, strings.Join(items, ","))
+}
+
+func (c *cluster) handleFleet(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/fleet/epm/packages/installed":
+		var items []string
+		for name, version := range c.installed {
+			items = append(items, fmt.Sprintf(`{"name":%q,"version":%q}`, name, version))
+		}
+		fmt.Fprintf(w, `{"items":[%s],"total":%d}`, strings.Join(items, ","), len(items))
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/api/fleet/epm/packages/"):
+		name := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/fleet/epm/packages/"), "/")[0]
+		c.installs = append(c.installs, name)
+		fmt.Fprint(w, `{"items":[]}`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newRunner(t *testing.T, installed map[string]string) (*Runner, *cluster) {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "acme"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "acme", "auth.tmpl"), []byte(authTemplate), 0644); err != nil {
+		t.Fatal(err)
+	}
+	integrations.SetTemplatesDir(dir)
+	t.Cleanup(func() { integrations.SetTemplatesDir("") })
+
+	c := &cluster{requests: make(map[string][][]map[string]interface{}), installed: installed}
+	esServer := httptest.NewServer(http.HandlerFunc(c.handleBulk))
+	t.Cleanup(esServer.Close)
+	kbServer := httptest.NewServer(http.HandlerFunc(c.handleFleet))
+	t.Cleanup(kbServer.Close)
+
+	esClient, err := es.NewTypedClient(es.Config{Addresses: []string{esServer.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	kbClient, err := gokibana.NewClient(gokibana.Config{Addresses: []string{kbServer.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		Replacements: config.Replacements{
+			IPs:   []string{"10.0.0.1"},
+			Users: []string{"alice", "bob"},
+			Hosts: []string{"web-1"},
+		},
+	}
+
+	return &Runner{
+		Config: cfg,
+		ES:     &elasticsearch.Config{Client: esClient, Ctx: context.Background()},
+		KB:     &kibana.Config{Client: kbClient, Ctx: context.Background()},
+	}, c
+}
+
+func TestRun(t *testing.T) {
+	runner, cluster := newRunner(t, map[string]string{"acme": "1.0.0"})
+	sessionsDir := t.TempDir()
+	runner.Session = session.NewRecorder(sessionsDir, "default")
+
+	var started []string
+	runner.OnStep = func(index int, step Step) {
+		started = append(started, fmt.Sprintf("%d:%s", index, step.Name))
+	}
+
+	scenario := &Scenario{
+		Name:   "brute_force",
+		Entity: Entity{User: "mallory"},
+		Steps: []Step{
+			{Name: "brute force", Integration: "acme", Dataset: "auth", Type: "logs", Count: 3, Match: "failed"},
+			{Name: "login", Integration: "acme", Dataset: "auth", Type: "logs", Namespace: "prod", Count: 2, Interval: time.Millisecond, Match: "succeeded"},
+		},
+	}
+
+	results, err := runner.Run(context.Background(), scenario)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []StepResult{
+		{Step: "brute force", Sent: 3, Indexed: 3},
+		{Step: "login", Sent: 2, Indexed: 2},
+	}
+	if fmt.Sprint(results) != fmt.Sprint(want) {
+		t.Errorf("Run() = %+v, want %+v", results, want)
+	}
+
+	if fmt.Sprint(started) != "[0:brute force 1:login]" {
+		t.Errorf("started steps %v", started)
+	}
+
+	// Without an interval the events of a step are sent in a single request
+	bruteForce := cluster.requests["logs-acme.auth-default"]
+	if len(bruteForce) != 1 || len(bruteForce[0]) != 3 {
+		t.Fatalf("brute force requests %v", bruteForce)
+	}
+	for _, document := range bruteForce[0] {
+		if document["message"] != "login failed for mallory from 10.0.0.1" {
+			t.Errorf("brute force event %v", document["message"])
+		}
+	}
+
+	login := cluster.requests["logs-acme.auth-prod"]
+	if len(login) != 2 {
+		t.Fatalf("login requests %v", login)
+	}
+	for _, request := range login {
+		if len(request) != 1 || request[0]["message"] != "login succeeded for mallory on web-1" {
+			t.Errorf("login request %v", request)
+		}
+	}
+
+	if len(cluster.installs) != 0 {
+		t.Errorf("installed %v although the package is installed", cluster.installs)
+	}
+
+	sessions, err := session.Load(sessionsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dataStreams := session.DataStreams(sessions); fmt.Sprint(dataStreams) != "[logs-acme.auth-default logs-acme.auth-prod]" {
+		t.Errorf("recorded data streams %v", dataStreams)
+	}
+}
+
+func TestRunInstallsPackages(t *testing.T) {
+	runner, cluster := newRunner(t, map[string]string{})
+
+	scenario := &Scenario{
+		Name:  "login",
+		Steps: []Step{{Integration: "acme", Dataset: "auth", Type: "logs"}},
+	}
+
+	results, err := runner.Run(context.Background(), scenario)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cluster.installs) != 1 || cluster.installs[0] != "acme" {
+		t.Errorf("installed %v, want [acme]", cluster.installs)
+	}
+
+	// A step without a name or count sends a single event named after its dataset
+	if len(results) != 1 || results[0].Step != "acme:auth" || results[0].Sent != 1 {
+		t.Errorf("Run() = %+v", results)
+	}
+}
+
+func TestRunStepFailures(t *testing.T) {
+	tests := []struct {
+		name string
+		step Step
+		err  string
+	}{
+		{"unknown dataset", Step{Name: "a", Integration: "acme", Dataset: "missing", Type: "logs"}, "step a of scenario failing failed"},
+		{"no matching template", Step{Name: "b", Integration: "acme", Dataset: "auth", Type: "logs", Match: "logout"}, `contain "logout"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner, _ := newRunner(t, map[string]string{"acme": "1.0.0"})
+
+			scenario := &Scenario{Name: "failing", Steps: []Step{tt.step, {Integration: "acme", Dataset: "auth", Type: "logs"}}}
+			results, err := runner.Run(context.Background(), scenario)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("Run() error = %v, want %q", err, tt.err)
+			}
+
+			// Steps after the failing one are not run
+			if len(results) != 1 {
+				t.Errorf("Run() = %+v", results)
+			}
+		})
+	}
+}
+
+func TestRunCancelled(t *testing.T) {
+	runner, cluster := newRunner(t, map[string]string{"acme": "1.0.0"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runner.OnStep = func(index int, step Step) {
+		if index == 1 {
+			cancel()
+		}
+	}
+
+	scenario := &Scenario{
+		Name: "cancelled",
+		Steps: []Step{
+			{Integration: "acme", Dataset: "auth", Type: "logs"},
+			{Integration: "acme", Dataset: "auth", Type: "logs", Namespace: "later", Delay: time.Hour},
+		},
+	}
+
+	results, err := runner.Run(ctx, scenario)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run() error = %v, want context.Canceled", err)
+	}
+	if len(results) != 1 {
+		t.Errorf("Run() = %+v", results)
+	}
+	if _, ok := cluster.requests["logs-acme.auth-later"]; ok {
+		t.Error("events sent after the scenario was cancelled")
+	}
+}
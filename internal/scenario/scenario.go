@@ -0,0 +1,153 @@
+package scenario
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"gopkg.in/yaml.v3"
+)
+
+// Scenario is a scripted sequence of events, such as a brute force followed by
+// a successful login and lateral movement, played by the same entity
+type Scenario struct {
+	Name        string `yaml:"-"`
+	Description string `yaml:"description,omitempty"`
+	Entity      Entity `yaml:"entity,omitempty"`
+	Steps       []Step `yaml:"steps"`
+}
+
+// Entity values of the actor shared by every step. Unset values are picked from
+// the entities or the replacements.
+type Entity struct {
+	User   string `yaml:"user,omitempty"`
+	Host   string `yaml:"host,omitempty"`
+	IP     string `yaml:"ip,omitempty"`
+	Email  string `yaml:"email,omitempty"`
+	Domain string `yaml:"domain,omitempty"`
+}
+
+// Step emits events from the templates of a dataset
+type Step struct {
+	Name        string `yaml:"name"`
+	Integration string `yaml:"integration"`
+	Dataset     string `yaml:"dataset"`
+	// Count number of events, defaults to 1
+	Count int `yaml:"count,omitempty"`
+	// Interval time between events, events are sent in a single request when unset
+	Interval time.Duration `yaml:"interval,omitempty"`
+	// Delay time to wait before the step starts
+	Delay time.Duration `yaml:"delay,omitempty"`
+	// Match only uses templates containing this text
+	Match                 string `yaml:"match,omitempty"`
+	Type                  string `yaml:"type,omitempty"`
+	Namespace             string `yaml:"namespace,omitempty"`
+	PreserveEventOriginal bool   `yaml:"preserve_original_event,omitempty"`
+}
+
+// Dir returns the directory holding scenario files next to the config file
+func Dir(configDir string) string {
+	return filepath.Join(configDir, "scenarios")
+}
+
+// List returns the names of the scenarios in the scenarios directory.
+// A missing directory is not an error.
+func List(configDir string) ([]string, error) {
+	entries, err := os.ReadDir(Dir(configDir))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		log.Debug(err)
+		return nil, fmt.Errorf("failed to read scenarios directory: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if name, ok := trimExtension(entry.Name()); ok {
+			names = append(names, name)
+		}
+	}
+
+	slices.Sort(names)
+
+	return names, nil
+}
+
+// Load reads a scenario by name from the scenarios directory or from a path to a YAML file
+func Load(configDir, name string) (*Scenario, error) {
+	path := name
+	if _, isFile := trimExtension(name); !isFile {
+		path = filepath.Join(Dir(configDir), name+".yaml")
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			path = filepath.Join(Dir(configDir), name+".yml")
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Debug(err)
+		return nil, fmt.Errorf("failed to read scenario %s: %w", name, err)
+	}
+
+	scenario := &Scenario{}
+	if err := yaml.Unmarshal(data, scenario); err != nil {
+		log.Debug(err)
+		return nil, fmt.Errorf("failed to unmarshal scenario %s: %w", name, err)
+	}
+
+	scenario.Name, _ = trimExtension(filepath.Base(path))
+
+	if err := scenario.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid scenario %s: %w", name, err)
+	}
+
+	return scenario, nil
+}
+
+// Validate checks that every step targets a dataset and has no negative values
+func (s *Scenario) Validate() error {
+	if len(s.Steps) == 0 {
+		return fmt.Errorf("scenario has no steps")
+	}
+
+	for i, step := range s.Steps {
+		name := step.Name
+		if name == "" {
+			name = fmt.Sprintf("%d", i+1)
+		}
+
+		if step.Integration == "" || step.Dataset == "" {
+			return fmt.Errorf("step %s must set integration and dataset", name)
+		}
+
+		if step.Count < 0 {
+			return fmt.Errorf("count cannot be negative for step %s", name)
+		}
+
+		if step.Interval < 0 || step.Delay < 0 {
+			return fmt.Errorf("interval and delay cannot be negative for step %s", name)
+		}
+	}
+
+	return nil
+}
+
+// trimExtension returns the file name without its YAML extension and whether it had one
+func trimExtension(name string) (string, bool) {
+	for _, extension := range []string{".yaml", ".yml"} {
+		if strings.HasSuffix(name, extension) {
+			return strings.TrimSuffix(name, extension), true
+		}
+	}
+	return name, false
+}
@@ -0,0 +1,160 @@
+package scenario
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const bruteForce = `description: brute force followed by a login
+entity:
+  user: mallory
+steps:
+  - name: brute force
+    integration: acme
+    dataset: auth
+    count: 5
+    interval: 1s
+    match: failed
+  - name: login
+    integration: acme
+    dataset: auth
+    delay: 2m
+    type: logs
+    namespace: prod
+    preserve_original_event: true
+`
+
+func writeScenario(t *testing.T, dir, file, content string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, file)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	configDir := t.TempDir()
+	writeScenario(t, Dir(configDir), "brute_force.yaml", bruteForce)
+
+	scenario, err := Load(configDir, "brute_force")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if scenario.Name != "brute_force" || scenario.Entity.User != "mallory" || len(scenario.Steps) != 2 {
+		t.Fatalf("Load() = %+v", scenario)
+	}
+
+	want := Step{
+		Name:        "brute force",
+		Integration: "acme",
+		Dataset:     "auth",
+		Count:       5,
+		Interval:    time.Second,
+		Match:       "failed",
+	}
+	if scenario.Steps[0] != want {
+		t.Errorf("first step = %+v, want %+v", scenario.Steps[0], want)
+	}
+
+	want = Step{
+		Name:                  "login",
+		Integration:           "acme",
+		Dataset:               "auth",
+		Delay:                 2 * time.Minute,
+		Type:                  "logs",
+		Namespace:             "prod",
+		PreserveEventOriginal: true,
+	}
+	if scenario.Steps[1] != want {
+		t.Errorf("second step = %+v, want %+v", scenario.Steps[1], want)
+	}
+}
+
+func TestLoadLocations(t *testing.T) {
+	configDir := t.TempDir()
+	writeScenario(t, Dir(configDir), "short.yml", bruteForce)
+	path := writeScenario(t, t.TempDir(), "elsewhere.yaml", bruteForce)
+
+	tests := []struct {
+		name     string
+		scenario string
+		want     string
+	}{
+		{"yml extension", "short", "short"},
+		{"path to a file", path, "elsewhere"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scenario, err := Load(configDir, tt.scenario)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if scenario.Name != tt.want {
+				t.Errorf("Name = %q, want %q", scenario.Name, tt.want)
+			}
+		})
+	}
+
+	if _, err := Load(configDir, "missing"); err == nil {
+		t.Error("Load() of a missing scenario succeeded")
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"not yaml", "steps: [", "failed to unmarshal"},
+		{"no steps", "description: nothing\n", "has no steps"},
+		{"no dataset", "steps:\n  - integration: acme\n", "step 1 must set integration and dataset"},
+		{"negative count", "steps:\n  - name: a\n    integration: acme\n    dataset: auth\n    count: -1\n", "count cannot be negative for step a"},
+		{"negative delay", "steps:\n  - integration: acme\n    dataset: auth\n    delay: -1s\n", "cannot be negative for step 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configDir := t.TempDir()
+			writeScenario(t, Dir(configDir), "invalid.yaml", tt.content)
+
+			_, err := Load(configDir, "invalid")
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Load() error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestList(t *testing.T) {
+	configDir := t.TempDir()
+
+	names, err := List(configDir)
+	if err != nil || names != nil {
+		t.Fatalf("List() of a missing directory = %v, %v", names, err)
+	}
+
+	writeScenario(t, Dir(configDir), "lateral.yml", bruteForce)
+	writeScenario(t, Dir(configDir), "brute_force.yaml", bruteForce)
+	writeScenario(t, Dir(configDir), "notes.txt", "")
+	writeScenario(t, Dir(configDir), "nested/ignored.yaml", bruteForce)
+
+	names, err = List(configDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(names, []string{"brute_force", "lateral"}) {
+		t.Errorf("List() = %v", names)
+	}
+}
@@ -0,0 +1,252 @@
+package session
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"gopkg.in/yaml.v3"
+)
+
+// maxSessions number of sessions kept in the sessions file, older sessions are forgotten
+const maxSessions = 50
+
+// fileMu serializes reading and writing the sessions file within the process, the lock
+// file next to it serializes processes
+var fileMu sync.Mutex
+
+// Session is a run of elastic-data and the data streams it wrote to
+type Session struct {
+	ID          string    `yaml:"id"`
+	Started     time.Time `yaml:"started"`
+	Profile     string    `yaml:"profile"`
+	DataStreams []string  `yaml:"data_streams"`
+}
+
+// Path returns the file holding the sessions next to the config file
+func Path(configDir string) string {
+	return filepath.Join(configDir, "sessions.yaml")
+}
+
+// Load returns the recorded sessions, the most recent first. A missing file is not an error.
+func Load(configDir string) ([]Session, error) {
+	unlock, err := lock(configDir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer unlock()
+
+	return load(configDir)
+}
+
+// ForProfile returns the sessions that wrote to the cluster of a connection profile
+func ForProfile(sessions []Session, profile string) []Session {
+	var matching []Session
+	for _, session := range sessions {
+		if session.Profile == profile {
+			matching = append(matching, session)
+		}
+	}
+	return matching
+}
+
+// DataStreams returns every data stream written to by the sessions
+func DataStreams(sessions []Session) []string {
+	var dataStreams []string
+	for _, session := range sessions {
+		for _, dataStream := range session.DataStreams {
+			if !slices.Contains(dataStreams, dataStream) {
+				dataStreams = append(dataStreams, dataStream)
+			}
+		}
+	}
+	return dataStreams
+}
+
+// Forget removes data streams from a session, dropping the session once it has none left
+func Forget(configDir, id string, dataStreams []string) error {
+	unlock, err := lock(configDir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	sessions, err := load(configDir)
+	if err != nil {
+		return err
+	}
+
+	for i := range sessions {
+		if sessions[i].ID == id {
+			sessions[i].DataStreams = slices.DeleteFunc(sessions[i].DataStreams, func(dataStream string) bool {
+				return slices.Contains(dataStreams, dataStream)
+			})
+		}
+	}
+
+	sessions = slices.DeleteFunc(sessions, func(session Session) bool {
+		return len(session.DataStreams) == 0
+	})
+
+	return save(configDir, sessions)
+}
+
+// Recorder records the data streams written to during the current session
+type Recorder struct {
+	mu        sync.Mutex
+	configDir string
+	session   Session
+}
+
+// NewRecorder starts a session for the connection profile. Nothing is written until
+// the first data stream is added.
+func NewRecorder(configDir, profile string) *Recorder {
+	started := time.Now()
+
+	return &Recorder{
+		configDir: configDir,
+		session: Session{
+			ID:      newID(started),
+			Started: started,
+			Profile: profile,
+		},
+	}
+}
+
+// ID returns the ID of the current session
+func (r *Recorder) ID() string {
+	return r.session.ID
+}
+
+// Add records a data stream, saving the session the first time the data stream is seen.
+// Adding to a nil recorder does nothing.
+func (r *Recorder) Add(dataStream string) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if slices.Contains(r.session.DataStreams, dataStream) {
+		return
+	}
+	r.session.DataStreams = append(r.session.DataStreams, dataStream)
+
+	if err := r.save(); err != nil {
+		log.Debug(fmt.Sprintf("Failed to record data stream %s in session %s: %v", dataStream, r.session.ID, err))
+	}
+}
+
+func (r *Recorder) save() error {
+	unlock, err := lock(r.configDir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	sessions, err := load(r.configDir)
+	if err != nil {
+		return err
+	}
+
+	sessions = slices.DeleteFunc(sessions, func(session Session) bool {
+		return session.ID == r.session.ID
+	})
+
+	current := r.session
+	current.DataStreams = slices.Clone(r.session.DataStreams)
+
+	return save(r.configDir, append([]Session{current}, sessions...))
+}
+
+// newID returns the start time of a session followed by a random suffix, so sessions
+// started in the same second keep their own entry
+func newID(started time.Time) string {
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		log.Debug(err)
+		return started.Format("20060102-150405.000000000")
+	}
+	return started.Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// lock takes the lock of the sessions file, returning the function releasing it
+func lock(configDir string) (func(), error) {
+	fileMu.Lock()
+
+	file, err := os.OpenFile(Path(configDir)+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		fileMu.Unlock()
+		log.Debug(err)
+		return nil, fmt.Errorf("failed to open sessions lock file: %w", err)
+	}
+
+	if err := lockFile(file); err != nil {
+		file.Close()
+		fileMu.Unlock()
+		log.Debug(err)
+		return nil, fmt.Errorf("failed to lock sessions file: %w", err)
+	}
+
+	return func() {
+		if err := unlockFile(file); err != nil {
+			log.Debug(err)
+		}
+		file.Close()
+		fileMu.Unlock()
+	}, nil
+}
+
+func load(configDir string) ([]Session, error) {
+	data, err := os.ReadFile(Path(configDir))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		log.Debug(err)
+		return nil, fmt.Errorf("failed to read sessions file: %w", err)
+	}
+
+	var sessions []Session
+	if err := yaml.Unmarshal(data, &sessions); err != nil {
+		log.Debug(err)
+		return nil, fmt.Errorf("failed to parse sessions file %s: %w", Path(configDir), err)
+	}
+
+	slices.SortStableFunc(sessions, func(a, b Session) int {
+		return b.Started.Compare(a.Started)
+	})
+
+	return sessions, nil
+}
+
+func save(configDir string, sessions []Session) error {
+	if len(sessions) > maxSessions {
+		sessions = sessions[:maxSessions]
+	}
+
+	data, err := yaml.Marshal(sessions)
+	if err != nil {
+		log.Debug(err)
+		return fmt.Errorf("failed to encode sessions: %w", err)
+	}
+
+	if err := os.WriteFile(Path(configDir), data, 0644); err != nil {
+		log.Debug(err)
+		return fmt.Errorf("failed to write sessions file: %w", err)
+	}
+
+	return nil
+}
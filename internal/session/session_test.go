@@ -0,0 +1,237 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRecorderAdd(t *testing.T) {
+	dir := t.TempDir()
+
+	recorder := NewRecorder(dir, "default")
+	recorder.Add("logs-nginx.access-default")
+
+	if _, err := os.Stat(Path(dir)); err != nil {
+		t.Fatalf("sessions file not written on the first data stream: %v", err)
+	}
+
+	recorder.Add("logs-nginx.access-default")
+	recorder.Add("logs-system.auth-default")
+
+	sessions, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 {
+		t.Fatalf("got %d sessions, want 1", len(sessions))
+	}
+
+	want := []string{"logs-nginx.access-default", "logs-system.auth-default"}
+	if sessions[0].ID != recorder.ID() || sessions[0].Profile != "default" || !slices.Equal(sessions[0].DataStreams, want) {
+		t.Errorf("got session %+v, want ID %s with data streams %v", sessions[0], recorder.ID(), want)
+	}
+}
+
+func TestRecorderAddNil(t *testing.T) {
+	var recorder *Recorder
+	recorder.Add("logs-nginx.access-default")
+}
+
+func TestRecorderAddConcurrent(t *testing.T) {
+	dir := t.TempDir()
+
+	recorder := NewRecorder(dir, "default")
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			recorder.Add(fmt.Sprintf("logs-test.ds%d-default", i))
+		}()
+	}
+	wg.Wait()
+
+	sessions, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || len(sessions[0].DataStreams) != 20 {
+		t.Fatalf("got sessions %+v, want one session with 20 data streams", sessions)
+	}
+}
+
+// TestRecorderAddAcrossProcesses runs the test binary as several processes recording to
+// the same sessions file, each session must keep every data stream it added
+func TestRecorderAddAcrossProcesses(t *testing.T) {
+	if dir := os.Getenv("SESSION_TEST_DIR"); dir != "" {
+		recorder := NewRecorder(dir, "default")
+		for i := range 20 {
+			recorder.Add(fmt.Sprintf("logs-test.ds%d-default", i))
+		}
+		return
+	}
+
+	dir := t.TempDir()
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cmd := exec.Command(os.Args[0], "-test.run=^TestRecorderAddAcrossProcesses$")
+			cmd.Env = append(os.Environ(), "SESSION_TEST_DIR="+dir)
+			if output, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("process failed: %v\n%s", err, output)
+			}
+		}()
+	}
+	wg.Wait()
+
+	sessions, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 4 {
+		t.Fatalf("got %d sessions, want 4", len(sessions))
+	}
+	for _, session := range sessions {
+		if len(session.DataStreams) != 20 {
+			t.Errorf("session %s has %d data streams, want 20", session.ID, len(session.DataStreams))
+		}
+	}
+}
+
+func TestRecordersStartedTogether(t *testing.T) {
+	dir := t.TempDir()
+
+	first := NewRecorder(dir, "default")
+	second := NewRecorder(dir, "default")
+	if first.ID() == second.ID() {
+		t.Fatalf("recorders started together share the ID %s", first.ID())
+	}
+
+	first.Add("logs-nginx.access-default")
+	second.Add("logs-system.auth-default")
+
+	sessions, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("got %d sessions, want 2", len(sessions))
+	}
+}
+
+func TestSaveKeepsNewestSessions(t *testing.T) {
+	dir := t.TempDir()
+
+	started := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	var sessions []Session
+	for i := range maxSessions + 5 {
+		sessions = append(sessions, Session{
+			ID:          fmt.Sprintf("session-%d", i),
+			Started:     started.Add(time.Duration(i) * time.Minute),
+			Profile:     "default",
+			DataStreams: []string{"logs-nginx.access-default"},
+		})
+	}
+	slices.Reverse(sessions)
+
+	if err := save(dir, sessions); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != maxSessions {
+		t.Fatalf("got %d sessions, want %d", len(loaded), maxSessions)
+	}
+	if loaded[0].ID != fmt.Sprintf("session-%d", maxSessions+4) || loaded[len(loaded)-1].ID != "session-5" {
+		t.Errorf("got sessions from %s to %s, want the newest first", loaded[0].ID, loaded[len(loaded)-1].ID)
+	}
+}
+
+func TestForget(t *testing.T) {
+	dir := t.TempDir()
+
+	recorder := NewRecorder(dir, "default")
+	recorder.Add("logs-nginx.access-default")
+	recorder.Add("logs-system.auth-default")
+
+	other := NewRecorder(dir, "default")
+	other.Add("logs-nginx.access-default")
+
+	if err := Forget(dir, recorder.ID(), []string{"logs-nginx.access-default"}); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("got %d sessions, want 2", len(sessions))
+	}
+	for _, session := range sessions {
+		want := []string{"logs-nginx.access-default"}
+		if session.ID == recorder.ID() {
+			want = []string{"logs-system.auth-default"}
+		}
+		if !slices.Equal(session.DataStreams, want) {
+			t.Errorf("session %s has data streams %v, want %v", session.ID, session.DataStreams, want)
+		}
+	}
+
+	if err := Forget(dir, recorder.ID(), []string{"logs-system.auth-default"}); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err = Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].ID != other.ID() {
+		t.Errorf("got sessions %+v, want only %s", sessions, other.ID())
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	for name, dir := range map[string]string{
+		"missing file":      t.TempDir(),
+		"missing directory": filepath.Join(t.TempDir(), "missing"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			sessions, err := Load(dir)
+			if err != nil || sessions != nil {
+				t.Errorf("got %v, %v, want no sessions and no error", sessions, err)
+			}
+		})
+	}
+}
+
+func TestForProfile(t *testing.T) {
+	sessions := []Session{
+		{ID: "a", Profile: "default", DataStreams: []string{"logs-nginx.access-default", "logs-system.auth-default"}},
+		{ID: "b", Profile: "staging", DataStreams: []string{"logs-okta.system-default"}},
+		{ID: "c", Profile: "default", DataStreams: []string{"logs-nginx.access-default", "logs-nginx.error-default"}},
+	}
+
+	matching := ForProfile(sessions, "default")
+	if len(matching) != 2 || matching[0].ID != "a" || matching[1].ID != "c" {
+		t.Fatalf("got sessions %+v, want a and c", matching)
+	}
+
+	want := []string{"logs-nginx.access-default", "logs-system.auth-default", "logs-nginx.error-default"}
+	if got := DataStreams(matching); !slices.Equal(got, want) {
+		t.Errorf("got data streams %v, want %v", got, want)
+	}
+}
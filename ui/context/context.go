@@ -1,13 +1,17 @@
 package context
 
 import (
+	"context"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/charmbracelet/log"
+	es "github.com/elastic/go-elasticsearch/v8"
 	"github.com/tehbooom/elastic-data/internal/config"
 	"github.com/tehbooom/elastic-data/internal/elasticsearch"
 	"github.com/tehbooom/elastic-data/internal/kibana"
+	kb "github.com/tehbooom/go-kibana"
 )
 
 type ProgramContext struct {
@@ -30,6 +34,56 @@ type DatasetConfig struct {
 	Unit                  string
 	PreserveEventOriginal bool
 	Events                []string
+	Type                  string
+	Namespace             string
+	TimestampDistribution string
+	TimestampJitter       time.Duration
+	BackfillVolume        int
+	Output                config.OutputConfig
+	ExcludeTemplates      []string
+	Rate                  config.RateProfile
+	Bulk                  config.BulkConfig
+}
+
+// NewDatasetConfig converts a dataset from the config file into a DatasetConfig
+func NewDatasetConfig(name string, dataset config.Dataset) DatasetConfig {
+	return DatasetConfig{
+		Name:                  name,
+		Selected:              dataset.Enabled,
+		Threshold:             dataset.Threshold,
+		Unit:                  dataset.Unit,
+		PreserveEventOriginal: dataset.PreserveEventOriginal,
+		Events:                dataset.Events,
+		Type:                  dataset.Type,
+		Namespace:             dataset.Namespace,
+		TimestampDistribution: dataset.TimestampDistribution,
+		TimestampJitter:       dataset.TimestampJitter,
+		BackfillVolume:        dataset.BackfillVolume,
+		Output:                dataset.Output,
+		ExcludeTemplates:      dataset.ExcludeTemplates,
+		Rate:                  dataset.Rate,
+		Bulk:                  dataset.Bulk,
+	}
+}
+
+// ToDataset converts the DatasetConfig back into a dataset for the config file
+func (d DatasetConfig) ToDataset() config.Dataset {
+	return config.Dataset{
+		Enabled:               d.Selected,
+		Threshold:             d.Threshold,
+		Unit:                  d.Unit,
+		Events:                d.Events,
+		PreserveEventOriginal: d.PreserveEventOriginal,
+		Type:                  d.Type,
+		Namespace:             d.Namespace,
+		TimestampDistribution: d.TimestampDistribution,
+		TimestampJitter:       d.TimestampJitter,
+		BackfillVolume:        d.BackfillVolume,
+		Output:                d.Output,
+		ExcludeTemplates:      d.ExcludeTemplates,
+		Rate:                  d.Rate,
+		Bulk:                  d.Bulk,
+	}
 }
 
 func NewProgramContext() *ProgramContext {
@@ -88,13 +142,7 @@ func (a *ProgramContext) SaveIntegrations() {
 					}
 
 					if datasetConfig.Selected || hasNonDefaultValues || wasPreviouslyEnabled {
-						datasetsToSave[datasetName] = config.Dataset{
-							Enabled:               datasetConfig.Selected,
-							Threshold:             datasetConfig.Threshold,
-							Unit:                  datasetConfig.Unit,
-							Events:                datasetConfig.Events,
-							PreserveEventOriginal: datasetConfig.PreserveEventOriginal,
-						}
+						datasetsToSave[datasetName] = datasetConfig.ToDataset()
 					}
 				}
 			}
@@ -102,8 +150,10 @@ func (a *ProgramContext) SaveIntegrations() {
 			// Only save the integration if it has datasets worth saving
 			if len(datasetsToSave) > 0 {
 				updatedIntegrations[integration] = config.Integration{
-					Enabled:  true,
-					Datasets: datasetsToSave,
+					Enabled:   true,
+					Namespace: a.Config.Integrations[integration].Namespace,
+					Version:   a.Config.Integrations[integration].Version,
+					Datasets:  datasetsToSave,
 				}
 			}
 		} else {
@@ -111,8 +161,10 @@ func (a *ProgramContext) SaveIntegrations() {
 			existingIntegration, exists := a.Config.Integrations[integration]
 			if exists && len(existingIntegration.Datasets) > 0 {
 				updatedIntegrations[integration] = config.Integration{
-					Enabled:  false,
-					Datasets: existingIntegration.Datasets,
+					Enabled:   false,
+					Namespace: existingIntegration.Namespace,
+					Version:   existingIntegration.Version,
+					Datasets:  existingIntegration.Datasets,
 				}
 			}
 		}
@@ -140,13 +192,7 @@ func (a *ProgramContext) LoadFromConfig(cfg *config.Config, path string) {
 			}
 
 			for datasetName, configDataset := range integrationData.Datasets {
-				datasetMap[datasetName] = DatasetConfig{
-					Name:      datasetName,
-					Selected:  configDataset.Enabled,
-					Threshold: configDataset.Threshold,
-					Unit:      configDataset.Unit,
-					Events:    configDataset.Events,
-				}
+				datasetMap[datasetName] = NewDatasetConfig(datasetName, configDataset)
 			}
 		}
 	}
@@ -165,3 +211,18 @@ func (pc *ProgramContext) SetRunning(running bool) {
 	defer pc.mu.Unlock()
 	pc.Running = running
 }
+
+// SetClients replaces the Elasticsearch and Kibana clients, which are tested again before use
+func (pc *ProgramContext) SetClients(esClient *es.TypedClient, kbClient *kb.Client) {
+	pc.ESClient = &elasticsearch.Config{
+		Client:    esClient,
+		Ctx:       context.Background(),
+		Connected: false,
+		Retry:     pc.Config.Retry,
+	}
+	pc.KBClient = &kibana.Config{
+		Client:    kbClient,
+		Ctx:       context.Background(),
+		Connected: false,
+	}
+}
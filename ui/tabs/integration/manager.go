@@ -110,14 +110,14 @@ func (m *TabModel) updateDatasetConfigs() {
 			continue
 		}
 
-		config := context.DatasetConfig{
-			Name:                  datasetItem.Name,
-			Selected:              datasetItem.Selected,
-			Threshold:             datasetItem.Threshold,
-			Unit:                  datasetItem.Unit,
-			PreserveEventOriginal: datasetItem.PreserveEventOriginal,
-			Events:                datasetItem.Events,
-		}
+		// Start from the existing config so settings not shown in the list are kept
+		config := datasetMap[datasetItem.Name]
+		config.Name = datasetItem.Name
+		config.Selected = datasetItem.Selected
+		config.Threshold = datasetItem.Threshold
+		config.Unit = datasetItem.Unit
+		config.PreserveEventOriginal = datasetItem.PreserveEventOriginal
+		config.Events = datasetItem.Events
 
 		datasetMap[datasetItem.Name] = config
 	}
@@ -0,0 +1,193 @@
+package integration
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/log"
+	"github.com/tehbooom/elastic-data/internal/config"
+	"github.com/tehbooom/elastic-data/internal/session"
+)
+
+var packageMismatchStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("208"))
+
+// packageMsg carries the installed version of a package after it was checked, installed or uninstalled
+type packageMsg struct {
+	integration string
+	installed   string
+	err         error
+}
+
+// checkPackage looks up the installed version of the package in the background
+func (m *TabModel) checkPackage(integration string) tea.Cmd {
+	m.packageStatus = "Checking package..."
+	m.confirmUninstall = false
+
+	kbClient := m.context.KBClient
+	if kbClient == nil {
+		m.packageStatus = ""
+		return nil
+	}
+	version := m.pinnedVersion(integration)
+
+	return func() tea.Msg {
+		if err := kbClient.TestConnection(); err != nil {
+			return packageMsg{integration: integration, err: err}
+		}
+
+		installed, err := kbClient.GetInstalledVersion(integration, version)
+		return packageMsg{integration: integration, installed: installed, err: err}
+	}
+}
+
+// installPackage installs the pinned version of the package, or the latest version when none is pinned
+func (m *TabModel) installPackage() tea.Cmd {
+	if m.context.IsRunning() {
+		m.packageStatus = packageMismatchStyle.Render("Stop generating before changing the package")
+		return nil
+	}
+
+	integration := m.currentIntegration
+	version := m.pinnedVersion(integration)
+	kbClient := m.context.KBClient
+	m.packageStatus = fmt.Sprintf("Installing %s %s...", integration, version)
+
+	return func() tea.Msg {
+		if err := kbClient.TestConnection(); err != nil {
+			return packageMsg{integration: integration, err: err}
+		}
+
+		if err := kbClient.InstallPackage(integration, version); err != nil {
+			return packageMsg{integration: integration, err: err}
+		}
+
+		installed, err := kbClient.GetInstalledVersion(integration, version)
+		return packageMsg{integration: integration, installed: installed, err: err}
+	}
+}
+
+// uninstallPackage deletes the data streams of the integration in the namespaces it
+// writes to and uninstalls its package. The first call only asks for confirmation.
+func (m *TabModel) uninstallPackage() tea.Cmd {
+	if m.context.IsRunning() {
+		m.packageStatus = packageMismatchStyle.Render("Stop generating before uninstalling the package")
+		return nil
+	}
+
+	integration := m.currentIntegration
+	namespaces := m.integrationNamespaces(integration)
+	if !m.confirmUninstall {
+		m.confirmUninstall = true
+		m.packageStatus = packageMismatchStyle.Render(fmt.Sprintf("Press U again to uninstall %s and delete its data streams in the namespaces %s", integration, strings.Join(namespaces, ", ")))
+		return nil
+	}
+
+	m.confirmUninstall = false
+	m.packageStatus = fmt.Sprintf("Uninstalling %s...", integration)
+	esClient := m.context.ESClient
+	kbClient := m.context.KBClient
+
+	return func() tea.Msg {
+		if err := esClient.TestConnection(); err != nil {
+			return packageMsg{integration: integration, err: err}
+		}
+		if err := kbClient.TestConnection(); err != nil {
+			return packageMsg{integration: integration, err: err}
+		}
+
+		// Data streams keep the index templates of the package in use
+		if err := esClient.DeleteIntegrationDataStreams(integration, namespaces); err != nil {
+			return packageMsg{integration: integration, err: err}
+		}
+
+		if err := kbClient.UninstallPackage(integration); err != nil {
+			return packageMsg{integration: integration, err: err}
+		}
+
+		return packageMsg{integration: integration}
+	}
+}
+
+// showPackage describes the installed package, flagging a version other than the pinned one
+func (m *TabModel) showPackage(msg packageMsg) {
+	if msg.integration != m.currentIntegration {
+		return
+	}
+
+	pinned := m.pinnedVersion(msg.integration)
+
+	switch {
+	case msg.err != nil:
+		log.Debug(msg.err)
+		m.packageStatus = previewFailureStyle.Render(fmt.Sprintf("Package: %v", msg.err))
+	case msg.installed == "" && pinned != "":
+		m.packageStatus = fmt.Sprintf("Package not installed, %s is installed when generating", pinned)
+	case msg.installed == "":
+		m.packageStatus = "Package not installed, the latest version is installed when generating"
+	case pinned != "" && msg.installed != pinned:
+		m.packageStatus = packageMismatchStyle.Render(fmt.Sprintf("Package %s installed but %s is pinned, press i to install %s", msg.installed, pinned, pinned))
+	default:
+		m.packageStatus = fmt.Sprintf("Package %s installed", msg.installed)
+	}
+}
+
+// integrationNamespaces returns the namespaces the datasets of the integration are configured
+// with and the namespaces of its data streams recorded by the sessions of the connection profile
+func (m *TabModel) integrationNamespaces(integration string) []string {
+	if m.context.Config == nil {
+		return []string{config.DefaultNamespace}
+	}
+
+	namespaces := []string{m.context.Config.GetNamespace(integration, "")}
+	add := func(namespace string) {
+		if !slices.Contains(namespaces, namespace) {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+
+	for _, dataset := range m.context.Config.Integrations[integration].Datasets {
+		add(m.context.Config.GetNamespace(integration, dataset.Namespace))
+	}
+	for _, dataset := range m.context.DatasetConfigs[integration] {
+		add(m.context.Config.GetNamespace(integration, dataset.Namespace))
+	}
+
+	sessions, err := session.Load(m.context.ConfigPath)
+	if err != nil {
+		log.Debug(err)
+	}
+	for _, dataStream := range session.DataStreams(session.ForProfile(sessions, m.context.Config.Profile())) {
+		if namespace, ok := dataStreamNamespace(dataStream, integration); ok {
+			add(namespace)
+		}
+	}
+
+	slices.Sort(namespaces)
+	return namespaces
+}
+
+// dataStreamNamespace returns the namespace of a <type>-<integration>.<dataset>-<namespace>
+// data stream when it belongs to the integration
+func dataStreamNamespace(dataStream, integration string) (string, bool) {
+	_, name, found := strings.Cut(dataStream, "-")
+	if !found || !strings.HasPrefix(name, integration+".") {
+		return "", false
+	}
+
+	separator := strings.LastIndex(name, "-")
+	if separator < 0 {
+		return "", false
+	}
+
+	return name[separator+1:], true
+}
+
+func (m *TabModel) pinnedVersion(integration string) string {
+	if m.context.Config == nil {
+		return ""
+	}
+	return m.context.Config.Integrations[integration].Version
+}
@@ -0,0 +1,248 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/log"
+	"github.com/tehbooom/elastic-data/internal/generator"
+	"github.com/tehbooom/elastic-data/internal/pipeline"
+	"github.com/tehbooom/elastic-data/ui/errors"
+	"github.com/tehbooom/elastic-data/ui/style"
+)
+
+// previewSamples number of rendered events shown in the preview
+const previewSamples = 5
+
+var previewFailureStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#FF6B6B"))
+
+// openPreview renders sample events of the selected dataset without sending anything
+func (m *TabModel) openPreview() tea.Cmd {
+	item, ok := m.datasetsList.SelectedItem().(DatasetItem)
+	if !ok {
+		return nil
+	}
+
+	content, err := m.renderPreview(item.Name)
+	if err != nil {
+		log.Debug(err)
+		return func() tea.Msg {
+			return errors.ShowErrorMsg{Message: fmt.Sprintf("Error: %v", err)}
+		}
+	}
+
+	m.previewDataset = item.Name
+	m.previewValidating = false
+	m.previewViewport.SetContent(content)
+	m.previewViewport.GotoTop()
+	m.state = StatePreviewingDataset
+
+	return nil
+}
+
+func (m *TabModel) updatePreview(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "r":
+			if m.previewValidating {
+				return m, m.startValidation(m.previewDataset)
+			}
+
+			content, err := m.renderPreview(m.previewDataset)
+			if err != nil {
+				log.Debug(err)
+				return m, func() tea.Msg {
+					return errors.ShowErrorMsg{Message: fmt.Sprintf("Error: %v", err)}
+				}
+			}
+			m.previewViewport.SetContent(content)
+			m.previewViewport.GotoTop()
+			return m, nil
+
+		case "esc", "q":
+			m.state = StateSelectingDatasets
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.previewViewport, cmd = m.previewViewport.Update(msg)
+	return m, cmd
+}
+
+// renderPreview executes every template of the dataset once, listing the templates
+// that fail to parse, execute or produce valid JSON followed by a few of the rendered events
+func (m *TabModel) renderPreview(dataset string) (string, error) {
+	templates, failed, err := generator.LoadTemplatesForDataset(m.currentIntegration, dataset, m.context.Config)
+	if err != nil && len(failed) == 0 {
+		return "", err
+	}
+
+	var failures []string
+	for _, templateErr := range failed {
+		failures = append(failures, templateErr.Error())
+	}
+
+	type sample struct {
+		name string
+		text string
+	}
+	var samples []sample
+
+	for _, template := range templates {
+		name := template.Template.Name()
+
+		template.UpdateValues()
+		message, err := template.ExecuteTemplate()
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("template %s: %v", name, err))
+			continue
+		}
+
+		if template.IsJSON {
+			var indented bytes.Buffer
+			if err := json.Indent(&indented, []byte(message), "", "  "); err != nil {
+				failures = append(failures, fmt.Sprintf("template %s: invalid JSON: %v", name, err))
+				continue
+			}
+			message = indented.String()
+		}
+
+		samples = append(samples, sample{name: name, text: message})
+	}
+
+	rand.Shuffle(len(samples), func(i, j int) {
+		samples[i], samples[j] = samples[j], samples[i]
+	})
+	samples = samples[:min(previewSamples, len(samples))]
+
+	wrap := lipgloss.NewStyle().Width(max(m.previewViewport.Width-4, 20))
+
+	var content strings.Builder
+	content.WriteString(fmt.Sprintf("%d templates, %d failed. Nothing is sent while previewing.\n\n",
+		len(templates)+len(failed), len(failures)))
+
+	for _, failure := range failures {
+		content.WriteString(wrap.Render(previewFailureStyle.Render("✗ "+failure)) + "\n")
+	}
+	if len(failures) > 0 {
+		content.WriteString("\n")
+	}
+
+	for i, sample := range samples {
+		content.WriteString(style.TitleStyle.Render(fmt.Sprintf("Event %d (%s)", i+1, sample.name)) + "\n")
+		content.WriteString(wrap.Render(sample.text) + "\n\n")
+	}
+
+	return content.String(), nil
+}
+
+func (m *TabModel) renderPreviewView() string {
+	var content strings.Builder
+
+	title := "Preview"
+	refresh := "New samples"
+	if m.previewValidating {
+		title = "Validate"
+		refresh = "Validate again"
+	}
+
+	content.WriteString(style.TitleStyle.Render(fmt.Sprintf("%s: %s:%s", title, m.currentIntegration, m.previewDataset)) + "\n")
+	content.WriteString(m.previewViewport.View() + "\n")
+	content.WriteString(style.FormatHelp(
+		"(j/k)", "Scroll",
+		"(r)", refresh,
+		"(q)", "Back",
+	))
+
+	return content.String()
+}
+
+// validationMsg carries the report of a pipeline validation
+type validationMsg struct {
+	dataset string
+	report  *pipeline.Report
+	err     error
+}
+
+// openValidation runs sample events of the selected dataset through its ingest pipeline
+func (m *TabModel) openValidation() tea.Cmd {
+	item, ok := m.datasetsList.SelectedItem().(DatasetItem)
+	if !ok {
+		return nil
+	}
+
+	m.previewDataset = item.Name
+	m.previewValidating = true
+	m.state = StatePreviewingDataset
+
+	return m.startValidation(item.Name)
+}
+
+// startValidation simulates the dataset in the background, the report arrives as a validationMsg
+func (m *TabModel) startValidation(dataset string) tea.Cmd {
+	m.previewViewport.SetContent("Running sample events through the ingest pipeline...")
+	m.previewViewport.GotoTop()
+
+	validator := &pipeline.Validator{
+		Config: m.context.Config,
+		ES:     m.context.ESClient,
+		KB:     m.context.KBClient,
+	}
+	integration := m.currentIntegration
+
+	return func() tea.Msg {
+		if err := validator.ES.TestConnection(); err != nil {
+			return validationMsg{dataset: dataset, err: err}
+		}
+		if err := validator.KB.TestConnection(); err != nil {
+			return validationMsg{dataset: dataset, err: err}
+		}
+
+		report, err := validator.Validate(integration, dataset)
+		return validationMsg{dataset: dataset, report: report, err: err}
+	}
+}
+
+// showValidation renders the report of a validation unless another dataset is shown by now
+func (m *TabModel) showValidation(msg validationMsg) {
+	if !m.previewValidating || msg.dataset != m.previewDataset {
+		return
+	}
+
+	if msg.err != nil {
+		log.Debug(msg.err)
+		m.previewViewport.SetContent(previewFailureStyle.Render(fmt.Sprintf("Error: %v", msg.err)))
+		return
+	}
+
+	wrap := lipgloss.NewStyle().Width(max(m.previewViewport.Width-4, 20))
+
+	var content strings.Builder
+	content.WriteString(fmt.Sprintf("Pipeline %s: %d templates, %d failed. Nothing was indexed.\n",
+		msg.report.Pipeline, len(msg.report.Results), msg.report.Failed()))
+	if msg.report.Failed() > 0 {
+		content.WriteString("Add the names of failed templates to exclude_templates of the dataset to skip them.\n")
+	}
+	content.WriteString("\n")
+
+	// Failures first, they are what the report is read for
+	for _, result := range msg.report.Results {
+		if result.Error != "" {
+			content.WriteString(wrap.Render(previewFailureStyle.Render(fmt.Sprintf("✗ %s: %s", result.Template, result.Error))) + "\n")
+		}
+	}
+	for _, result := range msg.report.Results {
+		if result.Error == "" {
+			content.WriteString(fmt.Sprintf("✓ %s\n", result.Template))
+		}
+	}
+
+	m.previewViewport.SetContent(content.String())
+	m.previewViewport.GotoTop()
+}
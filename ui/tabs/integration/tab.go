@@ -20,6 +20,7 @@ const (
 	StateSelectingIntegration = iota
 	StateSelectingDatasets
 	StateConfiguringDataset
+	StatePreviewingDataset
 
 	FocusDatasetList = iota
 	FocusViewport
@@ -48,6 +49,11 @@ type TabModel struct {
 	searchQuery             string
 	filteredItems           []list.Item
 	onlySelected            bool
+	previewViewport         viewport.Model
+	previewDataset          string
+	previewValidating       bool
+	packageStatus           string
+	confirmUninstall        bool
 }
 
 func ValidateUnit(input string) error {
@@ -127,6 +133,7 @@ func NewTabModel(context *context.ProgramContext, saveController *context.SaveCo
 		readmeRendered:          false,
 		focusedDatasetComponent: FocusDatasetList,
 		onlySelected:            false,
+		previewViewport:         viewport.New(80, 20),
 	}
 }
 
@@ -149,6 +156,9 @@ func (m *TabModel) SetSize(width, height int) {
 	availableHeight := height - viewportHeight - helpHeight - paddingHeight
 	listHeight := max(availableHeight, 5)
 	m.datasetsList.SetSize(width, listHeight)
+
+	m.previewViewport.Width = width - 2
+	m.previewViewport.Height = max(height-paddingHeight-helpHeight, 5)
 }
 
 func (m *TabModel) IsInConfigurationState() bool {
@@ -12,6 +12,16 @@ import (
 )
 
 func (m *TabModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(validationMsg); ok {
+		m.showValidation(msg)
+		return m, nil
+	}
+
+	if msg, ok := msg.(packageMsg); ok {
+		m.showPackage(msg)
+		return m, nil
+	}
+
 	cmd := m.handleGlobalKeys(msg)
 	if cmd != nil {
 		return m, cmd
@@ -24,6 +34,8 @@ func (m *TabModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m.updateDatasetSelection(msg)
 	case StateConfiguringDataset:
 		return m.updateDatasetConfiguration(msg)
+	case StatePreviewingDataset:
+		return m.updatePreview(msg)
 	}
 
 	return m, nil
@@ -187,7 +199,7 @@ func (m *TabModel) updateIntegrationSelection(msg tea.Msg) (tea.Model, tea.Cmd)
 
 					m.selectedIndex = 0
 					m.scrollOffset = 0
-					return m, nil
+					return m, m.checkPackage(item.Name)
 				}
 			case "esc", "q":
 				m.searchMode = false
@@ -209,6 +221,11 @@ func (m *TabModel) updateIntegrationSelection(msg tea.Msg) (tea.Model, tea.Cmd)
 func (m *TabModel) updateDatasetSelection(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if msg.String() != "U" && m.confirmUninstall {
+			m.confirmUninstall = false
+			m.packageStatus = ""
+		}
+
 		switch msg.String() {
 		case "j", "down":
 			if m.focusedDatasetComponent == FocusDatasetList {
@@ -266,6 +283,24 @@ func (m *TabModel) updateDatasetSelection(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			return m, nil
 
+		case "p":
+			if m.focusedDatasetComponent == FocusDatasetList {
+				return m, m.openPreview()
+			}
+			return m, nil
+
+		case "v":
+			if m.focusedDatasetComponent == FocusDatasetList {
+				return m, m.openValidation()
+			}
+			return m, nil
+
+		case "i":
+			return m, m.installPackage()
+
+		case "U":
+			return m, m.uninstallPackage()
+
 		case "enter":
 			if m.focusedDatasetComponent == FocusDatasetList {
 				item, ok := m.datasetsList.SelectedItem().(DatasetItem)
@@ -354,14 +389,14 @@ func (m *TabModel) updateDatasetConfiguration(msg tea.Msg) (tea.Model, tea.Cmd)
 				m.context.DatasetConfigs[m.currentIntegration] = datasetMap
 			}
 
-			datasetMap[item.Name] = context.DatasetConfig{
-				Name:                  item.Name,
-				Selected:              item.Selected,
-				Threshold:             threshold,
-				Unit:                  unit,
-				PreserveEventOriginal: preserve,
-				Events:                item.Events,
-			}
+			datasetConfig := datasetMap[item.Name]
+			datasetConfig.Name = item.Name
+			datasetConfig.Selected = item.Selected
+			datasetConfig.Threshold = threshold
+			datasetConfig.Unit = unit
+			datasetConfig.PreserveEventOriginal = preserve
+			datasetConfig.Events = item.Events
+			datasetMap[item.Name] = datasetConfig
 
 			if !item.Selected {
 				item.Selected = true
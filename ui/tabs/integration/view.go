@@ -60,6 +60,10 @@ func (m *TabModel) View() string {
 			m.datasetsList.Select(0)
 		}
 
+		if m.packageStatus != "" {
+			content.WriteString(" " + m.packageStatus + "\n")
+		}
+
 		listView := m.datasetsList.View()
 
 		if m.focusedDatasetComponent == FocusDatasetList {
@@ -125,6 +129,10 @@ func (m *TabModel) View() string {
 		help := style.FormatHelp(
 			"(space)", "Toggle selection",
 			"(enter)", "Configure selected",
+			"(p)", "Preview",
+			"(v)", "Validate",
+			"(i)", "Install package",
+			"(U)", "Uninstall package",
 			"(q)", "Back",
 			"(tab)", "Switch tabs",
 			"(ctrl+c)", "Quit",
@@ -133,6 +141,9 @@ func (m *TabModel) View() string {
 
 	case StateConfiguringDataset:
 		content.WriteString(m.renderConfigForm())
+
+	case StatePreviewingDataset:
+		content.WriteString(m.renderPreviewView())
 	}
 
 	return content.String()
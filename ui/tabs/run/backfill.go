@@ -0,0 +1,176 @@
+package run
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/tehbooom/elastic-data/internal/generator"
+	programContext "github.com/tehbooom/elastic-data/ui/context"
+)
+
+const (
+	// backfillBatchSize maximum number of events sent in a single backfill bulk request
+	backfillBatchSize = 2000
+	// backfillRetryDelay time to wait before retrying when no backfill event could be sent
+	backfillRetryDelay = time.Second
+)
+
+// Backfill is the time range historical events are generated for
+type Backfill struct {
+	Start time.Time
+	End   time.Time
+}
+
+// ParseBackfill parses an RFC3339 start and end time. An empty end defaults to now.
+func ParseBackfill(start, end string) (Backfill, error) {
+	var backfill Backfill
+	var err error
+
+	backfill.Start, err = time.Parse(time.RFC3339, start)
+	if err != nil {
+		log.Debug(err)
+		return Backfill{}, fmt.Errorf("invalid backfill start %q, expected RFC3339 like 2006-01-02T15:04:05Z: %w", start, err)
+	}
+
+	backfill.End = time.Now()
+	if end != "" {
+		backfill.End, err = time.Parse(time.RFC3339, end)
+		if err != nil {
+			log.Debug(err)
+			return Backfill{}, fmt.Errorf("invalid backfill end %q, expected RFC3339 like 2006-01-02T15:04:05Z: %w", end, err)
+		}
+	}
+
+	if !backfill.End.After(backfill.Start) {
+		return Backfill{}, fmt.Errorf("backfill end %s must be after start %s", backfill.End.Format(time.RFC3339), backfill.Start.Format(time.RFC3339))
+	}
+
+	return backfill, nil
+}
+
+// Volume returns the number of events for eps datasets or bytes for bytes datasets
+// to generate across the backfill. Unless backfill_volume is set an eps dataset
+// keeps its rate over the whole range and a bytes dataset sends its threshold.
+func (b Backfill) Volume(dataset programContext.DatasetConfig) int {
+	if dataset.BackfillVolume > 0 {
+		return dataset.BackfillVolume
+	}
+
+	if dataset.Unit == "eps" {
+		return dataset.Threshold * int(b.End.Sub(b.Start).Seconds())
+	}
+
+	return dataset.Threshold
+}
+
+// clamp keeps timestamp inside the backfill range
+func (b Backfill) clamp(timestamp time.Time) time.Time {
+	if timestamp.Before(b.Start) {
+		return b.Start
+	}
+	if timestamp.After(b.End) {
+		return b.End
+	}
+	return timestamp
+}
+
+// SetBackfill makes the generator send historical events for the range
+// instead of generating live data
+func (dg *DataGenerator) SetBackfill(backfill Backfill) {
+	dg.backfill = &backfill
+}
+
+// startBackfill hands the pipeline batches with timestamps taken from the part of the
+// range matching the progress so far. Once the whole volume is submitted it waits for the
+// pipeline and sends again whatever failed to render or send.
+func (dg *DataGenerator) startBackfill(p *pipeline) {
+	volume := dg.backfill.Volume(dg.config)
+	log.Debug(fmt.Sprintf("Starting backfill for %s from %s to %s with a volume of %d %s",
+		dg.config.Name, dg.backfill.Start.Format(time.RFC3339), dg.backfill.End.Format(time.RFC3339), volume, dg.config.Unit))
+
+	dg.mu.Lock()
+	if dg.config.Unit == "eps" {
+		dg.eventLimit = volume
+	} else {
+		dg.byteLimit = volume
+	}
+	dg.mu.Unlock()
+
+	// planned progress of the batches submitted so far
+	var planned, sent int
+
+	for {
+		select {
+		case <-dg.ctx.Done():
+			log.Debug(fmt.Sprintf("Stopping backfill for %s", dg.config.Name))
+			return
+		default:
+		}
+
+		if planned >= volume {
+			p.drain()
+
+			progress := dg.backfillProgress()
+			if progress >= volume {
+				log.Debug(fmt.Sprintf("Finished backfill for %s", dg.config.Name))
+				return
+			}
+
+			if progress == sent {
+				log.Debug(fmt.Sprintf("No backfill events were sent for %s, retrying", dg.config.Name))
+				select {
+				case <-dg.ctx.Done():
+					return
+				case <-time.After(backfillRetryDelay):
+				}
+			}
+			planned, sent = progress, progress
+		}
+
+		batchSize, eventSize := dg.backfillBatch(volume, planned)
+		if err := p.submit(dg.backfillTimestamps(volume, planned, batchSize, eventSize)); err != nil {
+			log.Debug(fmt.Sprintf("Stopping backfill for %s: %v", dg.config.Name, err))
+			return
+		}
+		planned += batchSize * eventSize
+	}
+}
+
+// backfillProgress returns the events or bytes of the backfill sent so far
+func (dg *DataGenerator) backfillProgress() int {
+	dg.mu.RLock()
+	defer dg.mu.RUnlock()
+
+	if dg.config.Unit == "eps" {
+		return dg.eventsSent
+	}
+	return dg.bytesSent
+}
+
+// backfillBatch returns the number of events of the next batch and the
+// estimated progress each of them makes
+func (dg *DataGenerator) backfillBatch(volume, progress int) (int, int) {
+	eventSize := 1
+	if dg.config.Unit != "eps" {
+		dg.mu.RLock()
+		eventSize = max(dg.averageEventSize, 1)
+		dg.mu.RUnlock()
+	}
+	batchSize := min((volume-progress+eventSize-1)/eventSize, backfillBatchSize)
+	return max(batchSize, 1), eventSize
+}
+
+// backfillTimestamps spreads the timestamps of a batch across the part of the range
+// matching its progress
+func (dg *DataGenerator) backfillTimestamps(volume, progress, batchSize, eventSize int) []time.Time {
+	window := dg.backfill.End.Sub(dg.backfill.Start)
+	from := dg.backfill.Start.Add(time.Duration(float64(window) * float64(progress) / float64(volume)))
+	to := dg.backfill.Start.Add(time.Duration(float64(window) * min(float64(progress+batchSize*eventSize)/float64(volume), 1)))
+
+	timestamps := generator.SpreadTimestamps(from, to.Sub(from), batchSize, dg.config.TimestampDistribution, dg.config.TimestampJitter, dg.random)
+	for i, timestamp := range timestamps {
+		timestamps[i] = dg.backfill.clamp(timestamp)
+	}
+	return timestamps
+}
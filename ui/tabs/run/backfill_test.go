@@ -0,0 +1,165 @@
+package run
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tehbooom/elastic-data/internal/config"
+	programContext "github.com/tehbooom/elastic-data/ui/context"
+)
+
+func TestParseBackfill(t *testing.T) {
+	tests := []struct {
+		name  string
+		start string
+		end   string
+		want  Backfill
+		// now whether the end defaults to now
+		now bool
+		err string
+	}{
+		{
+			name:  "start and end",
+			start: "2025-06-01T00:00:00Z",
+			end:   "2025-06-02T12:00:00Z",
+			want: Backfill{
+				Start: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+				End:   time.Date(2025, 6, 2, 12, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "end defaults to now",
+			start: "2025-06-01T00:00:00Z",
+			want:  Backfill{Start: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)},
+			now:   true,
+		},
+		{name: "invalid start", start: "2025-06-01", err: "invalid backfill start"},
+		{name: "invalid end", start: "2025-06-01T00:00:00Z", end: "tomorrow", err: "invalid backfill end"},
+		{name: "end before start", start: "2025-06-02T00:00:00Z", end: "2025-06-01T00:00:00Z", err: "must be after start"},
+		{name: "empty range", start: "2025-06-01T00:00:00Z", end: "2025-06-01T00:00:00Z", err: "must be after start"},
+		{name: "start in the future", start: "2999-01-01T00:00:00Z", err: "must be after start"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := time.Now()
+			backfill, err := ParseBackfill(tt.start, tt.end)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("ParseBackfill() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !backfill.Start.Equal(tt.want.Start) {
+				t.Errorf("start %s, want %s", backfill.Start, tt.want.Start)
+			}
+			if tt.now {
+				if backfill.End.Before(before) || backfill.End.After(time.Now()) {
+					t.Errorf("end %s, want now", backfill.End)
+				}
+			} else if !backfill.End.Equal(tt.want.End) {
+				t.Errorf("end %s, want %s", backfill.End, tt.want.End)
+			}
+		})
+	}
+}
+
+func TestBackfillVolume(t *testing.T) {
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	backfill := Backfill{Start: start, End: start.Add(time.Hour)}
+
+	tests := []struct {
+		name    string
+		dataset programContext.DatasetConfig
+		want    int
+	}{
+		{name: "eps keeps its rate", dataset: programContext.DatasetConfig{Unit: "eps", Threshold: 10}, want: 36000},
+		{name: "bytes sends the threshold", dataset: programContext.DatasetConfig{Unit: "bytes", Threshold: 5000}, want: 5000},
+		{name: "eps volume override", dataset: programContext.DatasetConfig{Unit: "eps", Threshold: 10, BackfillVolume: 500}, want: 500},
+		{name: "bytes volume override", dataset: programContext.DatasetConfig{Unit: "bytes", Threshold: 5000, BackfillVolume: 800}, want: 800},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := backfill.Volume(tt.dataset); got != tt.want {
+				t.Errorf("Volume() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackfillTimestamps(t *testing.T) {
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	backfill := Backfill{Start: start, End: start.Add(100 * time.Second)}
+
+	tests := []struct {
+		name     string
+		jitter   time.Duration
+		progress int
+		// from and to the part of the range the batch is spread across
+		from, to time.Time
+	}{
+		{name: "first batch", progress: 0, from: start, to: start.Add(10 * time.Second)},
+		{name: "later batch", progress: 50, from: start.Add(50 * time.Second), to: start.Add(60 * time.Second)},
+		{name: "jitter at the start", jitter: time.Minute, progress: 0, from: start, to: start.Add(70 * time.Second)},
+		{name: "jitter at the end", jitter: time.Minute, progress: 90, from: start.Add(30 * time.Second), to: backfill.End},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dg := &DataGenerator{
+				config:   programContext.DatasetConfig{Unit: "eps", TimestampDistribution: config.DistributionRandom, TimestampJitter: tt.jitter},
+				backfill: &backfill,
+				random:   rand.New(rand.NewSource(1)),
+			}
+
+			timestamps := dg.backfillTimestamps(100, tt.progress, 10, 1)
+			if len(timestamps) != 10 {
+				t.Fatalf("got %d timestamps, want 10", len(timestamps))
+			}
+
+			// Jittered timestamps are clamped to the backfill range
+			for _, timestamp := range timestamps {
+				if timestamp.Before(tt.from) || timestamp.After(tt.to) {
+					t.Errorf("timestamp %s outside [%s, %s]", timestamp, tt.from, tt.to)
+				}
+			}
+		})
+	}
+}
+
+func TestStartBackfill(t *testing.T) {
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	backfill := Backfill{Start: start, End: start.Add(time.Hour)}
+
+	// Failed requests and rejected events are sent again after draining the pipeline
+	sink := &fakeSink{failEvery: 3}
+	dg := newPipelineGenerator(t, sink, "accepted one", "accepted two", "rejected")
+	dg.config.Threshold = 1
+	dg.config.BackfillVolume = 5000
+	dg.random = rand.New(rand.NewSource(1))
+	dg.SetBackfill(backfill)
+
+	p := dg.startPipeline()
+	dg.startBackfill(p)
+	p.close()
+
+	if dg.eventsSent != 5000 || sink.indexed != 5000 {
+		t.Errorf("sent %d and indexed %d events, want the volume of 5000", dg.eventsSent, sink.indexed)
+	}
+	if sink.requests <= 3 {
+		t.Errorf("sent %d requests, want failed requests to be retried", sink.requests)
+	}
+
+	for _, timestamp := range sink.timestamps {
+		if timestamp.Before(backfill.Start) || timestamp.After(backfill.End) {
+			t.Fatalf("timestamp %s outside the backfill range", timestamp)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package run
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/log"
+	"github.com/tehbooom/elastic-data/internal/elasticsearch"
+	"github.com/tehbooom/elastic-data/internal/session"
+	uiErrors "github.com/tehbooom/elastic-data/ui/errors"
+	"github.com/tehbooom/elastic-data/ui/style"
+)
+
+// cleanupStatsMsg carries the stats of the data streams of a session
+type cleanupStatsMsg struct {
+	sessionID string
+	stats     []elasticsearch.DataStreamStats
+	err       error
+}
+
+// cleanupDoneMsg reports the data streams of a session were deleted
+type cleanupDoneMsg struct {
+	sessionID string
+	deleted   int
+	err       error
+}
+
+// sessionRecorder returns the recorder of the data streams written to since the TUI started
+// or the profile was last switched, starting it on first use
+func (m *TabModel) sessionRecorder() *session.Recorder {
+	if m.recorder == nil && m.programContext.Config != nil {
+		m.recorder = session.NewRecorder(m.programContext.ConfigPath, m.programContext.Config.Profile())
+	}
+	return m.recorder
+}
+
+// openCleanup lists the sessions of the active profile, most recent first
+func (m *TabModel) openCleanup() tea.Cmd {
+	if m.programContext.Config == nil {
+		return nil
+	}
+
+	if m.programContext.IsRunning() || m.scenarioRunning() {
+		return func() tea.Msg {
+			return uiErrors.ShowErrorMsg{Message: "Stop generating before cleaning up"}
+		}
+	}
+
+	if err := m.loadCleanupSessions(); err != nil {
+		return func() tea.Msg {
+			return uiErrors.ShowErrorMsg{Message: fmt.Sprintf("Error: %v", err)}
+		}
+	}
+
+	m.cleanupIndex = 0
+	m.cleanupPicker = true
+
+	return m.loadCleanupStats()
+}
+
+func (m *TabModel) loadCleanupSessions() error {
+	sessions, err := session.Load(m.programContext.ConfigPath)
+	if err != nil {
+		log.Debug(err)
+		return err
+	}
+
+	m.cleanupSessions = session.ForProfile(sessions, m.programContext.Config.Profile())
+	m.cleanupIndex = min(m.cleanupIndex, max(len(m.cleanupSessions)-1, 0))
+
+	return nil
+}
+
+// loadCleanupStats counts the documents of the data streams of the selected session in the background
+func (m *TabModel) loadCleanupStats() tea.Cmd {
+	m.cleanupStats = nil
+	m.cleanupReady = false
+	m.cleanupConfirm = false
+
+	if len(m.cleanupSessions) == 0 {
+		m.cleanupStatus = "No sessions recorded for this profile"
+		return nil
+	}
+
+	selected := m.cleanupSessions[m.cleanupIndex]
+	esClient := m.programContext.ESClient
+	m.cleanupStatus = "Loading data streams..."
+
+	return func() tea.Msg {
+		if err := esClient.TestConnection(); err != nil {
+			return cleanupStatsMsg{sessionID: selected.ID, err: err}
+		}
+
+		stats, err := esClient.DataStreamStats(selected.DataStreams)
+		return cleanupStatsMsg{sessionID: selected.ID, stats: stats, err: err}
+	}
+}
+
+// showCleanupStats displays the stats unless another session was selected since they were requested
+func (m *TabModel) showCleanupStats(msg cleanupStatsMsg) {
+	if !m.cleanupPicker || len(m.cleanupSessions) == 0 || m.cleanupSessions[m.cleanupIndex].ID != msg.sessionID {
+		return
+	}
+
+	if msg.err != nil {
+		log.Debug(msg.err)
+		m.cleanupStatus = trendUpStyle.Render(fmt.Sprintf("Error: %v", msg.err))
+		return
+	}
+
+	m.cleanupStats = msg.stats
+	m.cleanupReady = true
+	if len(msg.stats) == 0 {
+		m.cleanupStatus = "The data streams of this session no longer exist"
+	} else {
+		m.cleanupStatus = ""
+	}
+}
+
+// deleteCleanup deletes the data streams of the selected session and forgets the session.
+// The first call only asks for confirmation, nothing is deleted until the stats are loaded.
+func (m *TabModel) deleteCleanup() tea.Cmd {
+	if !m.cleanupReady {
+		return nil
+	}
+
+	selected := m.cleanupSessions[m.cleanupIndex]
+	if !m.cleanupConfirm {
+		m.cleanupConfirm = true
+		var documents int64
+		for _, stat := range m.cleanupStats {
+			documents += stat.Documents
+		}
+		if len(m.cleanupStats) == 0 {
+			m.cleanupStatus = trendUpStyle.Render("Press d again to forget this session")
+		} else {
+			m.cleanupStatus = trendUpStyle.Render(fmt.Sprintf("Press d again to delete %d data streams holding %d documents", len(m.cleanupStats), documents))
+		}
+		return nil
+	}
+
+	m.cleanupConfirm = false
+	m.cleanupReady = false
+	m.cleanupStatus = "Deleting data streams..."
+
+	var existing []string
+	for _, stat := range m.cleanupStats {
+		existing = append(existing, stat.Name)
+	}
+	esClient := m.programContext.ESClient
+	configDir := m.programContext.ConfigPath
+
+	return func() tea.Msg {
+		if err := esClient.DeleteDataStreams(existing); err != nil {
+			return cleanupDoneMsg{sessionID: selected.ID, err: err}
+		}
+
+		err := session.Forget(configDir, selected.ID, selected.DataStreams)
+		return cleanupDoneMsg{sessionID: selected.ID, deleted: len(existing), err: err}
+	}
+}
+
+// finishCleanup reloads the sessions once the data streams of one were deleted
+func (m *TabModel) finishCleanup(msg cleanupDoneMsg) tea.Cmd {
+	if msg.err != nil {
+		log.Debug(msg.err)
+		m.cleanupStatus = trendUpStyle.Render(fmt.Sprintf("Error: %v", msg.err))
+		return nil
+	}
+
+	log.Debug(fmt.Sprintf("Deleted %d data streams of session %s", msg.deleted, msg.sessionID))
+
+	// Data streams written to from now on are recorded again in a new session
+	if m.recorder != nil && m.recorder.ID() == msg.sessionID {
+		m.recorder = nil
+	}
+
+	if err := m.loadCleanupSessions(); err != nil {
+		m.cleanupStatus = trendUpStyle.Render(fmt.Sprintf("Error: %v", err))
+		return nil
+	}
+
+	cmd := m.loadCleanupStats()
+	if len(m.cleanupSessions) == 0 {
+		m.cleanupStatus = fmt.Sprintf("Deleted %d data streams, no sessions left", msg.deleted)
+	}
+
+	return cmd
+}
+
+func (m *TabModel) updateCleanup(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "left", "h":
+			if m.cleanupIndex > 0 {
+				m.cleanupIndex--
+				return m, m.loadCleanupStats()
+			}
+		case "right", "l":
+			if m.cleanupIndex < len(m.cleanupSessions)-1 {
+				m.cleanupIndex++
+				return m, m.loadCleanupStats()
+			}
+		case "d":
+			return m, m.deleteCleanup()
+		case "esc", "q":
+			if m.cleanupConfirm {
+				m.cleanupConfirm = false
+				m.cleanupStatus = ""
+				return m, nil
+			}
+			m.cleanupPicker = false
+		}
+	}
+
+	return m, nil
+}
+
+// cleanupView renders the data streams of the selected session with their document count and size
+func (m *TabModel) cleanupView() string {
+	var view strings.Builder
+	view.WriteString(style.TitleStyle.Render("Cleanup") + "\n\n")
+
+	if len(m.cleanupSessions) > 0 {
+		selected := m.cleanupSessions[m.cleanupIndex]
+		current := ""
+		if m.recorder != nil && m.recorder.ID() == selected.ID {
+			current = " (current)"
+		}
+		view.WriteString(fmt.Sprintf("  Session %d/%d: %s started %s%s\n\n", m.cleanupIndex+1, len(m.cleanupSessions), selected.ID, selected.Started.Format(time.RFC3339), current))
+
+		var documents int64
+		for _, stat := range m.cleanupStats {
+			view.WriteString(fmt.Sprintf("    %s: %d documents, %s\n", stat.Name, stat.Documents, stat.Size()))
+			documents += stat.Documents
+		}
+		if len(m.cleanupStats) > 0 {
+			view.WriteString(fmt.Sprintf("\n  Total: %d data streams, %d documents\n", len(m.cleanupStats), documents))
+		}
+	}
+
+	if m.cleanupStatus != "" {
+		view.WriteString("\n  " + m.cleanupStatus + "\n")
+	}
+
+	help := style.FormatHelp(
+		"(d)", "Delete",
+		"(←/→)", "Session",
+		"(esc)", "Back",
+	)
+
+	return baseStyle.Width(m.width-2).Render(view.String()) + "\n" + help
+}
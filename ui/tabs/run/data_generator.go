@@ -2,251 +2,202 @@ package run
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"math/rand"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/charmbracelet/log"
+	"github.com/tehbooom/elastic-data/internal/config"
 	"github.com/tehbooom/elastic-data/internal/elasticsearch"
 	"github.com/tehbooom/elastic-data/internal/generator"
+	"github.com/tehbooom/elastic-data/internal/output"
+	"github.com/tehbooom/elastic-data/internal/session"
 	programContext "github.com/tehbooom/elastic-data/ui/context"
 )
 
+// bytesInterval time between batches when generating a byte threshold
+const bytesInterval = 10 * time.Second
+
 type DataGenerator struct {
 	integrationName  string
+	index            string
 	config           programContext.DatasetConfig
 	ctx              context.Context
 	cancel           context.CancelFunc
 	stats            *IntegrationStats
 	wg               *sync.WaitGroup
 	mu               sync.RWMutex
-	client           *elasticsearch.Config
+	sink             output.Sink
 	templates        []*generator.LogTemplate
+	health           *TemplateHealth
 	bytesSent        int
 	eventsSent       int
 	averageEventSize int
+	backfill         *Backfill
+	session          *session.Recorder
+	// random spreads the timestamps of the batches of the generation loop
+	random *rand.Rand
+	// bytesQueued and eventsQueued count the events sent or waiting in the pipeline
+	bytesQueued  int
+	eventsQueued int
+	// byteLimit and eventLimit stop the pipeline from rendering more once reached
+	byteLimit  int
+	eventLimit int
 }
 
-func (dg *DataGenerator) startBytes() {
-	defer dg.wg.Done()
+// startBytes sends a batch every bytesInterval until the byte threshold is met
+func (dg *DataGenerator) startBytes(p *pipeline) {
+	dg.mu.Lock()
+	dg.byteLimit = dg.config.Threshold
+	dg.mu.Unlock()
 
-	ticker := time.NewTicker(time.Duration(10 * time.Second))
+	ticker := time.NewTicker(bytesInterval)
 	defer ticker.Stop()
 	for {
 		select {
 		case <-dg.ctx.Done():
-			log.Debug("Stopping data generation for %s", dg.config.Name)
+			log.Debug(fmt.Sprintf("Stopping data generation for %s", dg.config.Name))
 			return
 		case <-ticker.C:
-			if err := dg.sendBytes(); err != nil {
+			if err := dg.sendBytes(p); err != nil {
 				log.Debug(err)
-				log.Debug("Error generating data for %s: %v", dg.config.Name, err)
+				log.Debug(fmt.Sprintf("Error generating data for %s: %v", dg.config.Name, err))
+				if errors.Is(err, errNoTemplates) {
+					return
+				}
 			}
-			if dg.bytesSent >= dg.config.Threshold {
-				log.Debug("Reached byte threshold for %s", dg.config.Name)
+			if dg.thresholdMet(p) {
+				log.Debug(fmt.Sprintf("Reached byte threshold for %s", dg.config.Name))
 				return
 			}
 		}
 	}
 }
 
+// thresholdMet reports whether the byte threshold was sent. Once enough events are
+// queued it waits for the pipeline, events that fail to send are generated again.
+func (dg *DataGenerator) thresholdMet(p *pipeline) bool {
+	dg.mu.RLock()
+	queued := dg.bytesQueued
+	dg.mu.RUnlock()
+
+	if queued < dg.config.Threshold {
+		return false
+	}
+
+	p.drain()
+
+	dg.mu.RLock()
+	defer dg.mu.RUnlock()
+	return dg.bytesSent >= dg.config.Threshold
+}
+
 func (dg *DataGenerator) stop() {
 	dg.cancel()
 }
 
-func (dg *DataGenerator) startEPS() {
-	defer dg.wg.Done()
-	targetEPS := dg.config.Threshold
-	batchSize := dg.calculateOptimalBatchSize()
-
-	batchInterval := time.Duration(batchSize) * time.Second / time.Duration(targetEPS)
-	log.Debug(fmt.Sprintf("Batch interval set to %v for %s", batchInterval, dg.config.Name))
-
-	ticker := time.NewTicker(batchInterval)
-	defer ticker.Stop()
-	log.Debug("Starting EPS generation for %s: %d EPS (batch size: %d, interval: %v)",
-		dg.config.Name, targetEPS, batchSize, batchInterval)
+// Start launches the backfill or the generation loop matching the dataset unit, which
+// hand their batches to the pipeline of the generator. The output is closed once the
+// loop returns and the pipeline has sent the events already rendered.
+func (dg *DataGenerator) Start() {
+	dg.wg.Add(1)
+	go func() {
+		defer dg.wg.Done()
+		defer dg.closeOutput()
+
+		p := dg.startPipeline()
+		defer p.close()
+
+		switch {
+		case dg.backfill != nil:
+			dg.startBackfill(p)
+		case dg.config.Unit == "eps":
+			dg.startEPS(p)
+		default:
+			dg.startBytes(p)
+		}
+	}()
+}
 
-	// Send first batch immediately instead of waiting for first tick
-	if err := dg.sendEPS(); err != nil {
+func (dg *DataGenerator) closeOutput() {
+	if err := dg.sink.Close(); err != nil {
 		log.Debug(err)
-		log.Debug("Error sending initial EPS batch for %s: %v", dg.config.Name, err)
+		log.Debug(fmt.Sprintf("Error closing output for %s: %v", dg.config.Name, err))
 	}
+}
 
-	for {
-		select {
-		case <-dg.ctx.Done():
-			log.Debug("Stopping EPS generation for %s", dg.config.Name)
-			return
-		case <-ticker.C:
-			if err := dg.sendEPS(); err != nil {
-				log.Debug(err)
-				log.Debug("Error sending EPS batch for %s: %v", dg.config.Name, err)
-			}
-		}
+// Snapshot returns a copy of the current stats for the generator
+func (dg *DataGenerator) Snapshot() StatsSnapshot {
+	if dg.stats == nil {
+		return StatsSnapshot{}
 	}
+	return dg.stats.Snapshot()
 }
 
-func (dg *DataGenerator) sendEPS() error {
-	// Get batch configuration without holding lock
-	batchSize := dg.calculateOptimalBatchSize()
-
-	dg.mu.Lock()
-	selectedTemplates := dg.selectTemplatesAdaptive(batchSize)
-	preserveOriginal := dg.config.PreserveEventOriginal
-	dg.mu.Unlock()
-
-	// Generate events outside of lock
-	events := make([]map[string]interface{}, 0, batchSize)
-	var batchBytes int
-	timestamp := time.Now().UTC().Format(time.RFC3339)
-	timestampOverhead := 50 // Approximate overhead for @timestamp and other metadata
+// startEPS sends batches at the target rate of the rate profile, which is computed
+// again before every batch. A rate controller decides when the next batch is due and
+// how many events it holds.
+func (dg *DataGenerator) startEPS(p *pipeline) {
+	start := time.Now()
+	profile := newRateProfile(dg.config.Rate, dg.config.Threshold, start)
+	log.Debug(fmt.Sprintf("Starting EPS generation for %s: %d EPS (%s rate)", dg.config.Name, dg.config.Threshold, profile.profile.Shape))
 
-	for i := 0; i < batchSize; i++ {
-		template := selectedTemplates[i%len(selectedTemplates)]
-		template.UpdateValues()
-
-		message, err := template.ExecuteTemplate()
-		if err != nil {
-			log.Debug(err)
-			return err
-		}
+	// Send first batch immediately instead of waiting for a full interval
+	controller := newRateController(start, float64(epsBatchSize(profile.target(start))))
+	timer := time.NewTimer(0)
+	defer timer.Stop()
 
-		messageLen := len(message)
-		var event map[string]interface{}
-
-		if template.IsJSON {
-			decoder := json.NewDecoder(strings.NewReader(message))
-			if err := decoder.Decode(&event); err != nil {
-				log.Debug("Failed to parse JSON message:", err)
-				return err
-			}
-			event["@timestamp"] = timestamp
-		} else {
-			event = map[string]interface{}{
-				"message":    message,
-				"@timestamp": timestamp,
+	for {
+		select {
+		case <-dg.ctx.Done():
+			log.Debug(fmt.Sprintf("Stopping EPS generation for %s", dg.config.Name))
+			return
+		case <-timer.C:
+			now := time.Now()
+			target := profile.target(now)
+			controller.advance(now, target)
+			batchSize, wait := controller.next(target, epsBatchSize(target))
+			dg.setRate(target, controller.lag(target), controller.skipped)
+
+			if batchSize == 0 {
+				timer.Reset(wait)
+				continue
 			}
-		}
 
-		if preserveOriginal {
-			event["tags"] = []string{"preserve_original_event"}
+			interval := time.Duration(float64(batchSize) / target * float64(time.Second))
+			if err := p.submit(dg.eventTimestamps(batchSize, interval)); err != nil {
+				log.Debug(err)
+				log.Debug(fmt.Sprintf("Error sending EPS batch for %s: %v", dg.config.Name, err))
+				if errors.Is(err, errNoTemplates) {
+					return
+				}
+			}
+			timer.Reset(0)
 		}
-
-		log.Debug(fmt.Sprintf("Event %d: %v", i, event))
-
-		events = append(events, event)
-
-		// Approximate size without re-marshaling
-		eventBytes := messageLen + timestampOverhead
-		batchBytes += eventBytes
-	}
-
-	if len(events) == 0 {
-		return nil
-	}
-
-	// Send bulk request without lock
-	duration, err := dg.sendBulkRequest(events)
-	if err != nil {
-		log.Debug(err)
-		return err
 	}
-
-	// Only lock for updating stats
-	dg.mu.Lock()
-	dg.bytesSent += batchBytes
-	dg.updateStats(len(events), duration)
-	dg.mu.Unlock()
-
-	return nil
 }
 
-func (dg *DataGenerator) sendBytes() error {
-	// Get current state and batch configuration with minimal lock time
+// sendBytes hands the next batch to the pipeline, sized from the bytes left to send
+func (dg *DataGenerator) sendBytes(p *pipeline) error {
 	dg.mu.Lock()
 	batchSize := dg.calculateOptimalBatchSize()
-	currentBytesSent := dg.bytesSent
-	threshold := dg.config.Threshold
-	selectedTemplates := dg.selectTemplatesAdaptive(batchSize)
-	preserveOriginal := dg.config.PreserveEventOriginal
 	dg.mu.Unlock()
 
-	log.Debug(fmt.Sprintf("Batch size is %d for %s", batchSize, dg.config.Name))
-
-	// Generate events outside of lock
-	events := make([]map[string]interface{}, 0, batchSize)
-	var batchBytes int
-	timestamp := time.Now().UTC().Format(time.RFC3339)
-	timestampOverhead := 50 // Approximate overhead for @timestamp and other metadata
-
-	for i := 0; i < batchSize; i++ {
-		if dg.config.Unit == "bytes" && currentBytesSent >= threshold {
-			log.Debug(fmt.Sprintf("Threshold %d met for %s", batchSize, dg.config.Name))
-			break
-		}
-		template := selectedTemplates[i%len(selectedTemplates)]
-		template.UpdateValues()
-
-		message, err := template.ExecuteTemplate()
-		if err != nil {
-			log.Debug(err)
-			return err
-		}
-
-		messageLen := len(message)
-		var event map[string]interface{}
-
-		if template.IsJSON {
-			decoder := json.NewDecoder(strings.NewReader(message))
-			if err := decoder.Decode(&event); err != nil {
-				log.Debug("Failed to parse JSON message:", err)
-				return err
-			}
-			event["@timestamp"] = timestamp
-		} else {
-			event = map[string]interface{}{
-				"message":    message,
-				"@timestamp": timestamp,
-			}
-		}
-
-		if preserveOriginal {
-			event["tags"] = []string{"preserve_original_event"}
-		}
-
-		// Approximate size without re-marshaling
-		eventBytes := messageLen + timestampOverhead
-
-		if dg.config.Unit == "bytes" && (currentBytesSent+batchBytes+eventBytes) > threshold {
-			break
-		}
-
-		events = append(events, event)
-		batchBytes += eventBytes
-	}
-
-	if len(events) == 0 {
+	if batchSize <= 0 {
 		return nil
 	}
 
-	// Send bulk request without lock
-	duration, err := dg.sendBulkRequest(events)
-	if err != nil {
-		log.Debug(err)
-		return err
-	}
+	log.Debug(fmt.Sprintf("Batch size is %d for %s", batchSize, dg.config.Name))
 
-	// Only lock for updating stats
-	dg.mu.Lock()
-	dg.bytesSent += batchBytes
-	dg.updateStats(len(events), duration)
-	dg.mu.Unlock()
+	return p.submit(dg.eventTimestamps(batchSize, bytesInterval))
+}
 
-	return nil
+// eventTimestamps spreads count timestamps across the interval that ends now
+func (dg *DataGenerator) eventTimestamps(count int, interval time.Duration) []time.Time {
+	end := time.Now()
+	return generator.SpreadTimestamps(end.Add(-interval), interval, count, dg.config.TimestampDistribution, dg.config.TimestampJitter, dg.random)
 }
 
 func (dg *DataGenerator) selectTemplatesAdaptive(batchSize int) []*generator.LogTemplate {
@@ -328,23 +279,9 @@ func (dg *DataGenerator) selectRandomTemplates(templates []*generator.LogTemplat
 
 func (dg *DataGenerator) calculateOptimalBatchSize() int {
 	if dg.config.Unit == "eps" {
-		target := dg.config.Threshold
-		// Larger batches for higher EPS to reduce overhead
-		if target <= 10 {
-			return 1
-		} else if target <= 50 {
-			return 10
-		} else if target <= 200 {
-			return 50
-		} else if target <= 1000 {
-			return 200
-		} else if target <= 5000 {
-			return 1000
-		} else {
-			return 2000
-		}
+		return epsBatchSize(float64(dg.config.Threshold))
 	} else {
-		remainingBytes := dg.config.Threshold - dg.bytesSent
+		remainingBytes := dg.config.Threshold - dg.bytesQueued
 
 		if remainingBytes <= 0 {
 			return 0
@@ -366,33 +303,92 @@ func (dg *DataGenerator) calculateOptimalBatchSize() int {
 	}
 }
 
-func (dg *DataGenerator) sendBulkRequest(events []map[string]interface{}) (time.Duration, error) {
-	index := "logs-" + dg.integrationName + "." + dg.config.Name + "-default"
-	duration, err := dg.client.BulkRequest(index, events)
+// epsBatchSize returns the number of events sent per batch at a rate,
+// larger batches for higher EPS to reduce overhead
+func epsBatchSize(target float64) int {
+	if target <= 10 {
+		return 1
+	} else if target <= 50 {
+		return 10
+	} else if target <= 200 {
+		return 50
+	} else if target <= 1000 {
+		return 200
+	} else if target <= 5000 {
+		return 1000
+	} else {
+		return 2000
+	}
+}
+
+// setRate shows the rate the generator is aiming for and how far behind it is
+func (dg *DataGenerator) setRate(target float64, lag time.Duration, skipped int) {
+	if dg.stats == nil {
+		return
+	}
+	dg.stats.mu.Lock()
+	defer dg.stats.mu.Unlock()
+
+	// The pipeline sends the first events up to a flush interval after they were
+	// generated, so the achieved rate is measured from the start of the generation
+	if dg.stats.firstSend.IsZero() {
+		dg.stats.firstSend = time.Now()
+	}
+	dg.stats.TargetRate = target
+	dg.stats.Lag = lag
+	dg.stats.SkippedEvents = skipped
+}
+
+// SetSession records the data stream of the generator in the session when writing to Elasticsearch
+func (dg *DataGenerator) SetSession(recorder *session.Recorder) {
+	if dg.config.Output.WithDefaults().Type == config.OutputElasticsearch {
+		dg.session = recorder
+	}
+}
+
+// sendBulkRequest writes events to the output of the dataset
+func (dg *DataGenerator) sendBulkRequest(events []generator.Event) (elasticsearch.BulkResult, error) {
+	dg.session.Add(dg.index)
+	result, err := dg.sink.Write(dg.index, events)
 	if err != nil {
 		log.Debug(err)
-		return duration, err
+		return result, err
+	}
+	return result, nil
+}
+
+// recordBulkError counts a failed bulk request, its events are added by updateStats
+func (dg *DataGenerator) recordBulkError() {
+	if dg.stats == nil {
+		return
 	}
-	return duration, nil
+	dg.stats.mu.Lock()
+	defer dg.stats.mu.Unlock()
+
+	dg.stats.BulkErrors++
 }
 
-func (dg *DataGenerator) updateStats(eventCount int, duration time.Duration) {
+func (dg *DataGenerator) updateStats(eventCount int, result elasticsearch.BulkResult) {
 	if dg.stats == nil {
 		return
 	}
 	dg.stats.mu.Lock()
 	defer dg.stats.mu.Unlock()
 
-	durationNano := float64(duration.Nanoseconds()) / 1e6
+	durationNano := float64(result.Duration.Nanoseconds()) / 1e6
 
-	dg.stats.SentEvents += eventCount
+	// Like eventsSent, only indexed events count as sent
+	dg.stats.SentEvents += result.Indexed
+	dg.stats.IndexedEvents += result.Indexed
+	dg.stats.AddFailures(result)
 
 	if dg.stats.Unit == "bytes" {
 		dg.stats.SetBytesUnit(dg.bytesSent)
 	}
 
-	dg.stats.CalculateLatency(duration)
+	dg.stats.CalculateLatency(result.Duration)
 	now := time.Now()
+	dg.stats.recordRate(now, result.Indexed)
 	dg.stats.EnqueueRecentBatches(BatchInfo{
 		Events:   eventCount,
 		Duration: durationNano,
@@ -0,0 +1,170 @@
+package run
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/charmbracelet/log"
+	"github.com/tehbooom/elastic-data/internal/elasticsearch"
+	"github.com/tehbooom/elastic-data/internal/generator"
+)
+
+// quarantineAfter consecutive failures of a template after which it is not used for the rest of the run
+const quarantineAfter = 3
+
+// Kinds of template failures
+const (
+	FailureParse    = "parse"
+	FailureExecute  = "execute"
+	FailureJSON     = "json"
+	FailureRejected = "rejected"
+)
+
+// errNoTemplates is returned once every template of a dataset is quarantined
+var errNoTemplates = errors.New("every template is quarantined")
+
+// TemplateHealth tracks the failures of the templates of a dataset. A template failing
+// quarantineAfter times in a row is quarantined so it cannot hold back the dataset.
+// Templates are tracked by name so that reloaded templates keep their state.
+type TemplateHealth struct {
+	mu          sync.Mutex
+	templates   int
+	failures    map[string]int
+	consecutive map[string]int
+	quarantined map[string]string
+}
+
+// HealthSnapshot is a copy of the health of a dataset
+type HealthSnapshot struct {
+	// Templates number of templates of the dataset including the ones that failed to parse
+	Templates int
+	// Failures number of failures by kind
+	Failures map[string]int
+	// Quarantined last error of every template that is no longer used
+	Quarantined map[string]string
+}
+
+// newTemplateHealth returns the health of templates, quarantining the templates that failed to parse
+func newTemplateHealth(templates int, parseErrors []generator.TemplateError) *TemplateHealth {
+	health := &TemplateHealth{
+		templates:   templates + len(parseErrors),
+		failures:    make(map[string]int),
+		consecutive: make(map[string]int),
+		quarantined: make(map[string]string),
+	}
+
+	for _, parseErr := range parseErrors {
+		health.failures[FailureParse]++
+		health.quarantined[parseErr.Name] = parseErr.Err.Error()
+	}
+
+	return health
+}
+
+// recordFailure counts a failure of template and reports whether it has just been quarantined
+func (h *TemplateHealth) recordFailure(template *generator.LogTemplate, kind string, reason string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	name := template.Template.Name()
+	h.failures[kind]++
+	h.consecutive[name]++
+
+	if _, exists := h.quarantined[name]; exists || h.consecutive[name] < quarantineAfter {
+		return false
+	}
+
+	h.quarantined[name] = reason
+	return true
+}
+
+// recordSuccess resets the consecutive failures of templates
+func (h *TemplateHealth) recordSuccess(templates []*generator.LogTemplate) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, template := range templates {
+		delete(h.consecutive, template.Template.Name())
+	}
+}
+
+// Snapshot returns a copy of the health
+func (h *TemplateHealth) Snapshot() HealthSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	snapshot := HealthSnapshot{
+		Templates:   h.templates,
+		Failures:    make(map[string]int, len(h.failures)),
+		Quarantined: make(map[string]string, len(h.quarantined)),
+	}
+	for kind, count := range h.failures {
+		snapshot.Failures[kind] = count
+	}
+	for name, reason := range h.quarantined {
+		snapshot.Quarantined[name] = reason
+	}
+
+	return snapshot
+}
+
+// Health returns the template health of the dataset
+func (dg *DataGenerator) Health() HealthSnapshot {
+	return dg.health.Snapshot()
+}
+
+// recordRenderFailure records a template that failed to render and quarantines it
+// once it keeps failing
+func (dg *DataGenerator) recordRenderFailure(template *generator.LogTemplate, err error) {
+	kind := FailureExecute
+	if errors.Is(err, generator.ErrInvalidJSON) {
+		kind = FailureJSON
+	}
+
+	if dg.health.recordFailure(template, kind, err.Error()) {
+		dg.quarantine(template, err.Error())
+	}
+}
+
+// recordBulkResult records the templates of the events Elasticsearch rejected as failed and
+// every other template as successful. templates holds the template of every event sent.
+func (dg *DataGenerator) recordBulkResult(templates []*generator.LogTemplate, result elasticsearch.BulkResult) {
+	rejected := make(map[int]bool, len(result.Rejections))
+	for _, rejection := range result.Rejections {
+		if rejection.Position >= len(templates) {
+			continue
+		}
+
+		rejected[rejection.Position] = true
+		template := templates[rejection.Position]
+		if dg.health.recordFailure(template, FailureRejected, rejection.Reason) {
+			dg.quarantine(template, rejection.Reason)
+		}
+	}
+
+	succeeded := make([]*generator.LogTemplate, 0, len(templates))
+	for i, template := range templates {
+		if !rejected[i] {
+			succeeded = append(succeeded, template)
+		}
+	}
+	dg.health.recordSuccess(succeeded)
+}
+
+// quarantine stops using template for the rest of the run
+func (dg *DataGenerator) quarantine(template *generator.LogTemplate, reason string) {
+	name := template.Template.Name()
+	log.Debug(fmt.Sprintf("Quarantined template %s of %s after %d consecutive failures: %s",
+		name, dg.config.Name, quarantineAfter, reason))
+
+	dg.mu.Lock()
+	defer dg.mu.Unlock()
+
+	for i, candidate := range dg.templates {
+		if candidate.Template.Name() == name {
+			dg.templates = append(dg.templates[:i:i], dg.templates[i+1:]...)
+			return
+		}
+	}
+}
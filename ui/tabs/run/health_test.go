@@ -0,0 +1,175 @@
+package run
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tehbooom/elastic-data/internal/config"
+	"github.com/tehbooom/elastic-data/internal/elasticsearch"
+	"github.com/tehbooom/elastic-data/internal/generator"
+	"github.com/tehbooom/elastic-data/internal/integrations"
+	programContext "github.com/tehbooom/elastic-data/ui/context"
+)
+
+// loadTemplates loads the events as the templates of the test:logs dataset
+func loadTemplates(t testing.TB, events ...string) ([]*generator.LogTemplate, []generator.TemplateError) {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "test"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	content := strings.Join(events, "\n---EVENT_DELIMITER---\n")
+	if err := os.WriteFile(filepath.Join(dir, "test", "logs.tmpl"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	integrations.SetTemplatesDir(dir)
+	defer integrations.SetTemplatesDir("")
+
+	templates, parseErrors, err := generator.LoadTemplatesForDataset("test", "logs", &config.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return templates, parseErrors
+}
+
+func TestTemplateHealthQuarantine(t *testing.T) {
+	templates, _ := loadTemplates(t, "first {{.Users}}", "second {{.Users}}")
+	health := newTemplateHealth(len(templates), nil)
+
+	for i := 1; i < quarantineAfter; i++ {
+		if health.recordFailure(templates[0], FailureExecute, "boom") {
+			t.Fatalf("quarantined after %d failures", i)
+		}
+	}
+
+	// A success resets the consecutive failures
+	health.recordSuccess(templates)
+	for i := 1; i < quarantineAfter; i++ {
+		if health.recordFailure(templates[0], FailureExecute, "boom") {
+			t.Fatalf("quarantined after %d failures following a success", i)
+		}
+	}
+
+	if !health.recordFailure(templates[0], FailureJSON, "bad json") {
+		t.Fatalf("not quarantined after %d failures", quarantineAfter)
+	}
+	// A quarantined template is only reported once
+	if health.recordFailure(templates[0], FailureJSON, "bad json") {
+		t.Error("quarantined twice")
+	}
+
+	snapshot := health.Snapshot()
+	if snapshot.Templates != 2 || len(snapshot.Quarantined) != 1 || snapshot.Quarantined["test_logs_0"] != "bad json" {
+		t.Errorf("Snapshot() = %+v", snapshot)
+	}
+	wantFailures := 2*(quarantineAfter-1) + 2
+	if snapshot.Failures[FailureExecute]+snapshot.Failures[FailureJSON] != wantFailures {
+		t.Errorf("failures %v, want %d", snapshot.Failures, wantFailures)
+	}
+}
+
+func TestTemplateHealthReload(t *testing.T) {
+	templates, _ := loadTemplates(t, "first {{.Users}}", "second {{.Users}}")
+	health := newTemplateHealth(len(templates), nil)
+
+	for i := 1; i < quarantineAfter; i++ {
+		health.recordFailure(templates[0], FailureExecute, "boom")
+	}
+
+	// Reloaded templates are new values with the same names
+	reloaded, _ := loadTemplates(t, "first {{.Users}}", "second {{.Users}}")
+	if reloaded[0] == templates[0] {
+		t.Fatal("templates were not reloaded")
+	}
+	if !health.recordFailure(reloaded[0], FailureExecute, "boom") {
+		t.Error("failures of the template before the reload were lost")
+	}
+
+	// Successes of reloaded templates reset the failures too
+	health.recordFailure(templates[1], FailureExecute, "boom")
+	health.recordSuccess(reloaded[1:])
+	for i := 1; i < quarantineAfter; i++ {
+		if health.recordFailure(templates[1], FailureExecute, "boom") {
+			t.Fatal("success of the reloaded template did not reset the failures")
+		}
+	}
+}
+
+func TestTemplateHealthParseErrors(t *testing.T) {
+	templates, parseErrors := loadTemplates(t, "first {{.Users}}", "broken {{.Users")
+	if len(parseErrors) != 1 {
+		t.Fatalf("parse errors %v", parseErrors)
+	}
+
+	snapshot := newTemplateHealth(len(templates), parseErrors).Snapshot()
+	if snapshot.Templates != 2 || snapshot.Failures[FailureParse] != 1 {
+		t.Errorf("Snapshot() = %+v", snapshot)
+	}
+	if _, ok := snapshot.Quarantined["test_logs_1"]; !ok {
+		t.Errorf("template that failed to parse is not quarantined: %v", snapshot.Quarantined)
+	}
+
+	// Snapshots are copies
+	snapshot.Quarantined["other"] = "changed"
+	if _, ok := newTemplateHealth(len(templates), parseErrors).Snapshot().Quarantined["other"]; ok {
+		t.Error("snapshot shares its maps")
+	}
+}
+
+func TestRecordBulkResult(t *testing.T) {
+	templates, _ := loadTemplates(t, "first {{.Users}}", "second {{.Users}}", "third {{.Users}}")
+	dg := &DataGenerator{
+		config:    programContext.DatasetConfig{Name: "logs"},
+		templates: templates,
+		health:    newTemplateHealth(len(templates), nil),
+	}
+
+	// A template with an event indexed in the same batch is not failing consistently
+	dg.recordBulkResult(
+		[]*generator.LogTemplate{templates[0], templates[1], templates[2], templates[1]},
+		elasticsearch.BulkResult{Rejections: []elasticsearch.BulkRejection{{Position: 1, Reason: "mapper_parsing_exception"}}},
+	)
+
+	// Every batch holds an event of each template and the second is always rejected
+	sent := []*generator.LogTemplate{templates[0], templates[1], templates[2]}
+	result := elasticsearch.BulkResult{Rejections: []elasticsearch.BulkRejection{
+		{Position: 1, Reason: "mapper_parsing_exception"},
+		// Positions out of range are ignored
+		{Position: 10, Reason: "unknown"},
+	}}
+	for i := range quarantineAfter {
+		dg.recordBulkResult(sent, result)
+		if quarantined := len(dg.Health().Quarantined) > 0; quarantined != (i == quarantineAfter-1) {
+			t.Fatalf("quarantined %v after %d rejected batches", quarantined, i+1)
+		}
+	}
+
+	health := dg.Health()
+	if health.Failures[FailureRejected] != quarantineAfter+1 || health.Quarantined["test_logs_1"] != "mapper_parsing_exception" {
+		t.Errorf("Health() = %+v", health)
+	}
+	if len(dg.templates) != 2 || dg.templates[0] != templates[0] || dg.templates[1] != templates[2] {
+		t.Errorf("templates after quarantine %v", dg.templates)
+	}
+}
+
+func TestRecordRenderFailure(t *testing.T) {
+	templates, _ := loadTemplates(t, "first {{.Users}}")
+	dg := &DataGenerator{
+		config:    programContext.DatasetConfig{Name: "logs"},
+		templates: templates,
+		health:    newTemplateHealth(len(templates), nil),
+	}
+
+	dg.recordRenderFailure(templates[0], generator.ErrInvalidJSON)
+	dg.recordRenderFailure(templates[0], errors.New("exec"))
+
+	health := dg.Health()
+	if health.Failures[FailureJSON] != 1 || health.Failures[FailureExecute] != 1 {
+		t.Errorf("failures %v", health.Failures)
+	}
+}
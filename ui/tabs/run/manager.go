@@ -3,10 +3,18 @@ package run
 import (
 	"context"
 	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
 	"github.com/charmbracelet/log"
+	"github.com/tehbooom/elastic-data/internal/config"
+	"github.com/tehbooom/elastic-data/internal/elasticsearch"
 	"github.com/tehbooom/elastic-data/internal/generator"
-	"slices"
-	"strings"
+	"github.com/tehbooom/elastic-data/internal/kibana"
+	"github.com/tehbooom/elastic-data/internal/output"
+	programContext "github.com/tehbooom/elastic-data/ui/context"
 )
 
 func getTrendIndicator(trend string) string {
@@ -71,32 +79,57 @@ func (m *TabModel) RefreshIntegrations() {
 	m.integrations = newIntegrations
 }
 
+// InstallPackage installs the package of an integration when it is missing or
+// installed at another version than the version pinned in the config
 func (m *TabModel) InstallPackage(integrationName string) error {
-	if slices.Contains(m.installedIntegrations, integrationName) {
+	if m.installedIntegrations == nil {
+		installed, err := m.programContext.KBClient.GetInstalledPackages()
+		if err != nil {
+			log.Debug(err)
+			return err
+		}
+		m.installedIntegrations = installed
+	}
+
+	version := m.programContext.Config.Integrations[integrationName].Version
+	if !kibana.NeedsInstall(m.installedIntegrations, integrationName, version) {
 		log.Debug(fmt.Sprintf("Package %s installed", integrationName))
 		return nil
 	}
 
-	log.Debug(fmt.Sprintf("Installing Package %s", integrationName))
-	err := m.programContext.KBClient.InstallPackage(integrationName)
+	log.Debug(fmt.Sprintf("Installing Package %s %s", integrationName, version))
+	err := m.programContext.KBClient.InstallPackage(integrationName, version)
 	if err != nil {
 		log.Debug(err)
 		return err
 	}
 
-	m.installedIntegrations = append(m.installedIntegrations, integrationName)
+	m.installedIntegrations[integrationName] = version
 
 	return nil
 }
 
 func (m *TabModel) StartGeneration() error {
-
 	log.Debug("StartGeneration")
+	return m.startGenerators(nil)
+}
+
+// StartBackfill starts a generator for every selected dataset that sends
+// historical events for the backfill range
+func (m *TabModel) StartBackfill(backfill Backfill) error {
+	log.Debug("StartBackfill")
+	return m.startGenerators(&backfill)
+}
+
+func (m *TabModel) startGenerators(backfill *Backfill) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	m.stopAllGenerators()
 
+	// Packages may have been installed or removed since the last start, or the profile switched
+	m.installedIntegrations = nil
+
 	for fullName, stats := range m.integrations {
 		fullNameSplit := strings.Split(fullName, ":")
 		integrationName := fullNameSplit[0]
@@ -111,47 +144,96 @@ func (m *TabModel) StartGeneration() error {
 		integrationDatasets := m.programContext.DatasetConfigs[integrationName]
 
 		if dataset, ok := integrationDatasets[datasetName]; ok {
-			templates, err := generator.LoadPreGeneratedTemplatesForDataset(integrationName, datasetName, m.programContext.Config)
+			// The TUI owns stdout
+			if dataset.Output.Type == config.OutputStdout {
+				return fmt.Errorf("the stdout output of %s is only supported by the run subcommand", fullName)
+			}
+
+			dataset = ResolveDataStreamType(m.programContext.KBClient, integrationName, m.programContext.Config.Integrations[integrationName].Version, dataset)
+			generator, err := NewDataGenerator(m.mainCtx, integrationName, dataset, m.programContext.Config, m.programContext.ESClient, stats, &m.wg)
 			if err != nil {
 				log.Debug(err)
 				return err
 			}
 
-			if len(templates) == 0 {
-				return fmt.Errorf("loaded 0 templates for %s", fullName)
+			if backfill != nil {
+				generator.SetBackfill(*backfill)
 			}
+			generator.SetSession(m.sessionRecorder())
 
-			var templateSizesTotal int
-			for _, template := range templates {
-				templateSizesTotal += template.Size
-			}
+			m.generators[fullName] = generator
+			generator.Start()
+		}
+	}
+	return nil
+}
 
-			calculateAverageEventSize := templateSizesTotal / len(templates)
+// NewDataGenerator loads the templates for a dataset and opens its output, returning
+// a generator ready to be started. The generator stops when parent is cancelled.
+func NewDataGenerator(parent context.Context, integrationName string, dataset programContext.DatasetConfig, cfg *config.Config, client *elasticsearch.Config, stats *IntegrationStats, wg *sync.WaitGroup) (*DataGenerator, error) {
+	fullName := fmt.Sprintf("%s:%s", integrationName, dataset.Name)
 
-			ctx, cancel := context.WithCancel(m.mainCtx)
+	templates, parseErrors, err := generator.LoadTemplatesForDataset(integrationName, dataset.Name, cfg)
+	if err != nil {
+		log.Debug(err)
+		return nil, err
+	}
 
-			generator := &DataGenerator{
-				config:           dataset,
-				ctx:              ctx,
-				cancel:           cancel,
-				stats:            stats,
-				wg:               &m.wg,
-				templates:        templates,
-				client:           m.programContext.ESClient,
-				averageEventSize: calculateAverageEventSize,
-				integrationName:  integrationName,
-			}
+	if len(templates) == 0 {
+		return nil, fmt.Errorf("loaded 0 templates for %s", fullName)
+	}
 
-			m.generators[fullName] = generator
-			m.wg.Add(1)
-			if generator.config.Unit == "eps" {
-				go generator.startEPS()
-			} else {
-				go generator.startBytes()
-			}
-		}
+	var templateSizesTotal int
+	for _, template := range templates {
+		templateSizesTotal += template.Size
 	}
-	return nil
+
+	dataStreamType := dataset.Type
+	if dataStreamType == "" {
+		dataStreamType = config.DefaultDataStreamType
+	}
+	namespace := cfg.GetNamespace(integrationName, dataset.Namespace)
+
+	sink, err := output.New(dataset.Output, client)
+	if err != nil {
+		log.Debug(err)
+		return nil, fmt.Errorf("failed to open output for %s: %w", fullName, err)
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+
+	return &DataGenerator{
+		config:           dataset,
+		ctx:              ctx,
+		cancel:           cancel,
+		stats:            stats,
+		wg:               wg,
+		templates:        templates,
+		health:           newTemplateHealth(len(templates), parseErrors),
+		sink:             sink,
+		averageEventSize: templateSizesTotal / len(templates),
+		integrationName:  integrationName,
+		index:            config.DataStreamName(dataStreamType, integrationName, dataset.Name, namespace),
+		random:           rand.New(rand.NewSource(time.Now().UnixNano())),
+	}, nil
+}
+
+// ResolveDataStreamType sets the data stream type of a dataset from the manifest of the
+// package at version unless the type is set in the config
+func ResolveDataStreamType(kbClient *kibana.Config, integrationName, version string, dataset programContext.DatasetConfig) programContext.DatasetConfig {
+	if dataset.Type != "" {
+		return dataset
+	}
+
+	dataStreamType, err := kbClient.GetDataStreamType(integrationName, version, dataset.Name)
+	if err != nil {
+		log.Debug(err)
+		log.Debug(fmt.Sprintf("Using data stream type %s for %s:%s", config.DefaultDataStreamType, integrationName, dataset.Name))
+		return dataset
+	}
+
+	dataset.Type = dataStreamType
+	return dataset
 }
 
 func (m *TabModel) stopGeneration() {
@@ -178,12 +260,20 @@ func (dg *DataGenerator) resetStats() {
 		dg.stats.Peak = 0
 		dg.stats.SentBytes = 0
 		dg.stats.SentEvents = 0
+		dg.stats.IndexedEvents = 0
+		dg.stats.FailedEvents = 0
+		dg.stats.FailureReasons = nil
+		dg.stats.BulkErrors = 0
 		dg.stats.SentBytesUnit = ""
 		dg.stats.Trend = "stable"
+		dg.stats.firstSend = time.Time{}
+		dg.stats.recentSends = nil
 		dg.stats.mu.Unlock()
 	}
 
 	dg.bytesSent = 0
 	dg.eventsSent = 0
+	dg.bytesQueued = 0
+	dg.eventsQueued = 0
 	dg.averageEventSize = 0
 }
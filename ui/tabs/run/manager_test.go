@@ -0,0 +1,103 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tehbooom/elastic-data/internal/kibana"
+	programContext "github.com/tehbooom/elastic-data/ui/context"
+	gokibana "github.com/tehbooom/go-kibana"
+)
+
+// fleetPackages fakes the Fleet package API serving the data streams of nginx 1.2.0
+func fleetPackages(t *testing.T) (*kibana.Config, *[]string) {
+	t.Helper()
+
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.Path)
+		if r.URL.Path != "/api/fleet/epm/packages/nginx/1.2.0" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"item":{"name":"nginx","version":"1.2.0","data_streams":[`+
+			`{"type":"logs","dataset":"nginx.access","path":"access"},`+
+			`{"type":"metrics","dataset":"nginx.stubstatus","path":"stubstatus"}]}}`)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := gokibana.NewClient(gokibana.Config{Addresses: []string{server.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &kibana.Config{Client: client, Ctx: context.Background()}, &requested
+}
+
+func TestResolveDataStreamType(t *testing.T) {
+	kbClient, requested := fleetPackages(t)
+
+	tests := []struct {
+		name        string
+		integration string
+		version     string
+		dataset     programContext.DatasetConfig
+		want        string
+		// lookup whether the type is looked up in Fleet
+		lookup bool
+	}{
+		{
+			name:        "configured type",
+			integration: "nginx",
+			version:     "1.2.0",
+			dataset:     programContext.DatasetConfig{Name: "stubstatus", Type: "synthetics"},
+			want:        "synthetics",
+		},
+		{
+			name:        "package type",
+			integration: "nginx",
+			version:     "1.2.0",
+			dataset:     programContext.DatasetConfig{Name: "stubstatus"},
+			want:        "metrics",
+			lookup:      true,
+		},
+		{
+			name:        "unknown dataset",
+			integration: "nginx",
+			version:     "1.2.0",
+			dataset:     programContext.DatasetConfig{Name: "error"},
+			lookup:      true,
+		},
+		{
+			name:        "unknown package version",
+			integration: "nginx",
+			version:     "9.9.9",
+			dataset:     programContext.DatasetConfig{Name: "access"},
+			lookup:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*requested = nil
+
+			got := ResolveDataStreamType(kbClient, tt.integration, tt.version, tt.dataset)
+			if got.Type != tt.want {
+				t.Errorf("ResolveDataStreamType() type = %q, want %q", got.Type, tt.want)
+			}
+			if got.Name != tt.dataset.Name {
+				t.Errorf("ResolveDataStreamType() dataset = %q, want %q", got.Name, tt.dataset.Name)
+			}
+
+			if lookup := len(*requested) > 0; lookup != tt.lookup {
+				t.Errorf("looked up %v, want lookup %v", *requested, tt.lookup)
+			}
+			if tt.lookup && (*requested)[0] != "/api/fleet/epm/packages/nginx/"+tt.version {
+				t.Errorf("requested %v, want version %s", *requested, tt.version)
+			}
+		})
+	}
+}
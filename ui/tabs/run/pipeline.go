@@ -0,0 +1,243 @@
+package run
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/tehbooom/elastic-data/internal/config"
+	"github.com/tehbooom/elastic-data/internal/generator"
+)
+
+// renderJob asks the render workers for an event at every timestamp
+type renderJob struct {
+	timestamps []time.Time
+}
+
+// queuedEvent is a rendered event waiting for a bulk worker
+type queuedEvent struct {
+	event    generator.Event
+	template *generator.LogTemplate
+}
+
+// pipeline sends the events of a generator. Render workers turn jobs into events on a
+// bounded queue drained by bulk workers, each of which sends a bulk request once it holds
+// flush_bytes of events or flush_interval has passed, much like esutil.BulkIndexer.
+type pipeline struct {
+	dg       *DataGenerator
+	settings config.BulkConfig
+	jobs     chan renderJob
+	queue    chan queuedEvent
+	// pending jobs submitted but not rendered yet
+	pending sync.WaitGroup
+	// unsent events rendered but not sent yet
+	unsent    sync.WaitGroup
+	renderers sync.WaitGroup
+	workers   sync.WaitGroup
+	// exhausted is set once every template of the generator is quarantined
+	exhausted atomic.Bool
+}
+
+// startPipeline starts the render and bulk workers of the generator
+func (dg *DataGenerator) startPipeline() *pipeline {
+	settings := dg.config.Bulk.WithDefaults()
+
+	p := &pipeline{
+		dg:       dg,
+		settings: settings,
+		jobs:     make(chan renderJob, settings.RenderWorkers),
+		queue:    make(chan queuedEvent, settings.QueueSize),
+	}
+
+	for range settings.RenderWorkers {
+		p.renderers.Add(1)
+		go p.render(generator.NewRenderer())
+	}
+
+	for range settings.Workers {
+		p.workers.Add(1)
+		go p.send()
+	}
+
+	log.Debug(fmt.Sprintf("Started %d render workers and %d bulk workers for %s", settings.RenderWorkers, settings.Workers, dg.config.Name))
+	return p
+}
+
+// submit hands a job to the render workers, blocking while they are all busy. It fails
+// once the generator is stopped or has no templates left.
+func (p *pipeline) submit(timestamps []time.Time) error {
+	if p.exhausted.Load() {
+		return errNoTemplates
+	}
+
+	p.pending.Add(1)
+	select {
+	case p.jobs <- renderJob{timestamps: timestamps}:
+		return nil
+	case <-p.dg.ctx.Done():
+		p.pending.Done()
+		return p.dg.ctx.Err()
+	}
+}
+
+// drain waits until every submitted job was rendered and its events sent or failed.
+// It must not be called while jobs are being submitted.
+func (p *pipeline) drain() {
+	p.pending.Wait()
+	p.unsent.Wait()
+}
+
+// close stops the pipeline once the events already rendered are sent
+func (p *pipeline) close() {
+	close(p.jobs)
+	p.renderers.Wait()
+	close(p.queue)
+	p.workers.Wait()
+}
+
+// render is a render worker, its renderer is not shared with the other workers
+func (p *pipeline) render(renderer *generator.Renderer) {
+	defer p.renderers.Done()
+
+	for job := range p.jobs {
+		p.renderJob(renderer, job)
+		p.pending.Done()
+	}
+}
+
+func (p *pipeline) renderJob(renderer *generator.Renderer, job renderJob) {
+	dg := p.dg
+
+	dg.mu.Lock()
+	selectedTemplates := dg.selectTemplatesAdaptive(len(job.timestamps))
+	preserveOriginal := dg.config.PreserveEventOriginal
+	dg.mu.Unlock()
+
+	if len(selectedTemplates) == 0 {
+		p.exhausted.Store(true)
+		return
+	}
+
+	for i, timestamp := range job.timestamps {
+		// Events not rendered yet are dropped once the generator is stopped
+		if dg.ctx.Err() != nil {
+			return
+		}
+
+		template := selectedTemplates[i%len(selectedTemplates)]
+		event, err := renderer.Render(template, timestamp, preserveOriginal)
+		if err != nil {
+			log.Debug(err)
+			dg.recordRenderFailure(template, err)
+			continue
+		}
+
+		size := len(event.Document)
+		if !dg.reserve(size) {
+			return
+		}
+
+		p.unsent.Add(1)
+		select {
+		case p.queue <- queuedEvent{event: event, template: template}:
+		case <-dg.ctx.Done():
+			dg.release(1, size)
+			p.unsent.Done()
+			return
+		}
+	}
+}
+
+// send is a bulk worker, it drains the queue until the pipeline is closed
+func (p *pipeline) send() {
+	defer p.workers.Done()
+
+	ticker := time.NewTicker(p.settings.FlushInterval)
+	defer ticker.Stop()
+
+	var batch []queuedEvent
+	var batchBytes int
+
+	for {
+		select {
+		case queued, ok := <-p.queue:
+			if !ok {
+				p.flush(batch, batchBytes)
+				return
+			}
+
+			batch = append(batch, queued)
+			batchBytes += len(queued.event.Document)
+			if batchBytes >= p.settings.FlushBytes {
+				p.flush(batch, batchBytes)
+				batch, batchBytes = nil, 0
+			}
+		case <-ticker.C:
+			p.flush(batch, batchBytes)
+			batch, batchBytes = nil, 0
+		}
+	}
+}
+
+// flush sends a batch in a single bulk request and adds its result to the stats
+func (p *pipeline) flush(batch []queuedEvent, batchBytes int) {
+	if len(batch) == 0 {
+		return
+	}
+	defer p.unsent.Add(-len(batch))
+
+	dg := p.dg
+	events := make([]generator.Event, len(batch))
+	eventTemplates := make([]*generator.LogTemplate, len(batch))
+	for i, queued := range batch {
+		events[i] = queued.event
+		eventTemplates[i] = queued.template
+	}
+
+	result, err := dg.sendBulkRequest(events)
+	if err != nil {
+		log.Debug(fmt.Sprintf("Error sending bulk request for %s: %v", dg.config.Name, err))
+		dg.recordBulkError()
+	} else {
+		dg.recordBulkResult(eventTemplates, result)
+	}
+
+	// Only indexed events count as sent, the others count towards the limits again.
+	// A failed request may still have indexed events on an earlier attempt.
+	dg.mu.Lock()
+	defer dg.mu.Unlock()
+	dg.bytesSent += result.IndexedBytes
+	dg.eventsSent += result.Indexed
+	dg.bytesQueued -= batchBytes - result.IndexedBytes
+	dg.eventsQueued -= len(batch) - result.Indexed
+	dg.updateStats(len(batch), result)
+}
+
+// reserve counts a rendered event towards the limits of the generator,
+// refusing it once a limit is reached
+func (dg *DataGenerator) reserve(size int) bool {
+	dg.mu.Lock()
+	defer dg.mu.Unlock()
+
+	if dg.byteLimit > 0 && dg.bytesQueued >= dg.byteLimit {
+		return false
+	}
+	if dg.eventLimit > 0 && dg.eventsQueued >= dg.eventLimit {
+		return false
+	}
+
+	dg.bytesQueued += size
+	dg.eventsQueued++
+	return true
+}
+
+// release stops counting events that were dropped or failed to send
+func (dg *DataGenerator) release(events, size int) {
+	dg.mu.Lock()
+	defer dg.mu.Unlock()
+
+	dg.eventsQueued -= events
+	dg.bytesQueued -= size
+}
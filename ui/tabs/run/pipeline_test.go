@@ -0,0 +1,195 @@
+package run
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tehbooom/elastic-data/internal/config"
+	"github.com/tehbooom/elastic-data/internal/elasticsearch"
+	"github.com/tehbooom/elastic-data/internal/generator"
+	programContext "github.com/tehbooom/elastic-data/ui/context"
+)
+
+// fakeSink rejects the events containing "rejected" and fails every failEvery request
+// after indexing its first event, as when a retry of the request fails
+type fakeSink struct {
+	mu        sync.Mutex
+	failEvery int
+	requests  int
+	// indexed events and bytes of the events indexed
+	indexed      int
+	indexedBytes int
+	// timestamps of the events indexed
+	timestamps []time.Time
+}
+
+func (s *fakeSink) Write(_ string, events []generator.Event) (elasticsearch.BulkResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result elasticsearch.BulkResult
+	s.requests++
+	if s.failEvery > 0 && s.requests%s.failEvery == 0 {
+		result.Indexed = 1
+		result.IndexedBytes = len(events[0].Document)
+		result.Failed = len(events) - 1
+		s.timestamps = append(s.timestamps, events[0].Timestamp)
+		s.indexed += result.Indexed
+		s.indexedBytes += result.IndexedBytes
+		return result, errors.New("connection refused")
+	}
+
+	for i, event := range events {
+		if bytes.Contains(event.Document, []byte("rejected")) {
+			result.Failed++
+			result.Rejections = append(result.Rejections, elasticsearch.BulkRejection{Position: i, Reason: "mapper_parsing_exception: bad"})
+			continue
+		}
+		result.Indexed++
+		result.IndexedBytes += len(event.Document)
+		s.timestamps = append(s.timestamps, event.Timestamp)
+	}
+
+	s.indexed += result.Indexed
+	s.indexedBytes += result.IndexedBytes
+	return result, nil
+}
+
+func (s *fakeSink) Close() error {
+	return nil
+}
+
+func newPipelineGenerator(t *testing.T, sink *fakeSink, events ...string) *DataGenerator {
+	t.Helper()
+
+	templates, _ := loadTemplates(t, events...)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	return &DataGenerator{
+		config: programContext.DatasetConfig{
+			Name: "logs",
+			Unit: "eps",
+			Bulk: config.BulkConfig{RenderWorkers: 4, Workers: 4, FlushBytes: 2048, FlushInterval: time.Millisecond, QueueSize: 16},
+		},
+		ctx:       ctx,
+		cancel:    cancel,
+		stats:     &IntegrationStats{Unit: "eps"},
+		sink:      sink,
+		templates: templates,
+		health:    newTemplateHealth(len(templates), nil),
+	}
+}
+
+// submitConcurrently submits jobs of size timestamps from several goroutines
+func submitConcurrently(t *testing.T, p *pipeline, jobs, size int) {
+	t.Helper()
+
+	var wg sync.WaitGroup
+	for range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			timestamps := make([]time.Time, size)
+			for i := range timestamps {
+				timestamps[i] = time.Now()
+			}
+			if err := p.submit(timestamps); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestPipelineStats(t *testing.T) {
+	sink := &fakeSink{}
+	dg := newPipelineGenerator(t, sink, "accepted one", "accepted two")
+
+	p := dg.startPipeline()
+	submitConcurrently(t, p, 20, 50)
+	p.drain()
+	p.close()
+
+	stats := dg.Snapshot()
+	if dg.eventsSent != 1000 || sink.indexed != 1000 || stats.SentEvents != 1000 || stats.IndexedEvents != 1000 {
+		t.Errorf("sent %d, indexed %d, stats sent %d and indexed %d, want 1000",
+			dg.eventsSent, sink.indexed, stats.SentEvents, stats.IndexedEvents)
+	}
+	if dg.bytesSent != sink.indexedBytes || dg.bytesQueued != dg.bytesSent || dg.eventsQueued != dg.eventsSent {
+		t.Errorf("sent %d bytes and queued %d events and %d bytes, want %d bytes", dg.bytesSent, dg.eventsQueued, dg.bytesQueued, sink.indexedBytes)
+	}
+}
+
+func TestPipelineStatsCountIndexedEvents(t *testing.T) {
+	sink := &fakeSink{failEvery: 5}
+	dg := newPipelineGenerator(t, sink, "accepted one", "accepted two", "rejected")
+
+	p := dg.startPipeline()
+	submitConcurrently(t, p, 20, 60)
+	p.drain()
+	p.close()
+
+	// Rejected events and events of failed requests are not sent
+	if dg.eventsSent != sink.indexed || dg.bytesSent != sink.indexedBytes {
+		t.Errorf("sent %d events and %d bytes, want the %d events and %d bytes indexed",
+			dg.eventsSent, dg.bytesSent, sink.indexed, sink.indexedBytes)
+	}
+	if dg.eventsSent >= 1200 {
+		t.Errorf("sent %d events, want fewer than the 1200 rendered", dg.eventsSent)
+	}
+
+	// Only indexed events count towards the limits
+	if dg.eventsQueued != dg.eventsSent || dg.bytesQueued != dg.bytesSent {
+		t.Errorf("queued %d events and %d bytes, want the %d events and %d bytes sent",
+			dg.eventsQueued, dg.bytesQueued, dg.eventsSent, dg.bytesSent)
+	}
+
+	// Events indexed before a request failed count as indexed, the others as failed once
+	stats := dg.Snapshot()
+	if stats.IndexedEvents != sink.indexed {
+		t.Errorf("stats indexed %d, want %d", stats.IndexedEvents, sink.indexed)
+	}
+	if stats.SentEvents != sink.indexed {
+		t.Errorf("stats sent %d, want the %d indexed", stats.SentEvents, sink.indexed)
+	}
+	var rated int
+	for _, sample := range dg.stats.recentSends {
+		rated += sample.events
+	}
+	if rated != sink.indexed {
+		t.Errorf("rate window holds %d events, want the %d indexed", rated, sink.indexed)
+	}
+	if stats.IndexedEvents+stats.FailedEvents != 1200 {
+		t.Errorf("%d indexed and %d failed events, want 1200 in total", stats.IndexedEvents, stats.FailedEvents)
+	}
+	if stats.BulkErrors != sink.requests/sink.failEvery {
+		t.Errorf("%d bulk errors, want %d", stats.BulkErrors, sink.requests/sink.failEvery)
+	}
+}
+
+func TestPipelineEventLimit(t *testing.T) {
+	sink := &fakeSink{}
+	dg := newPipelineGenerator(t, sink, "accepted", "rejected")
+	dg.eventLimit = 100
+
+	p := dg.startPipeline()
+
+	// Rejected events are generated again until the limit is indexed
+	for i := 0; dg.backfillProgress() < 100; i++ {
+		if i == 50 {
+			t.Fatalf("sent %d events after %d rounds, want 100", dg.backfillProgress(), i)
+		}
+		submitConcurrently(t, p, 4, 50)
+		p.drain()
+	}
+	p.close()
+
+	if dg.eventsSent != 100 || sink.indexed != 100 {
+		t.Errorf("sent %d and indexed %d events, want the limit of 100", dg.eventsSent, sink.indexed)
+	}
+}
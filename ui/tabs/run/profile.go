@@ -0,0 +1,140 @@
+package run
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/log"
+	es "github.com/elastic/go-elasticsearch/v8"
+	"github.com/tehbooom/elastic-data/internal/elasticsearch"
+	"github.com/tehbooom/elastic-data/internal/kibana"
+	uiErrors "github.com/tehbooom/elastic-data/ui/errors"
+	"github.com/tehbooom/elastic-data/ui/style"
+	kb "github.com/tehbooom/go-kibana"
+)
+
+// ProfileSwitchedMsg carries the clients of the connection profile that was picked
+type ProfileSwitchedMsg struct {
+	Profile  string
+	ESClient *es.TypedClient
+	KBClient *kb.Client
+	Err      error
+}
+
+// openProfilePicker lists the connection profiles of the config
+func (m *TabModel) openProfilePicker() tea.Cmd {
+	if m.programContext.Config == nil {
+		return nil
+	}
+
+	if m.programContext.IsRunning() || m.scenarioRunning() {
+		return func() tea.Msg {
+			return uiErrors.ShowErrorMsg{Message: "Stop generating before switching profiles"}
+		}
+	}
+
+	m.profileNames = m.programContext.Config.ProfileNames()
+	m.profileIndex = max(slices.Index(m.profileNames, m.programContext.Config.Profile()), 0)
+	m.profilePicker = true
+
+	return nil
+}
+
+func (m *TabModel) updateProfilePicker(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "up", "k":
+			if m.profileIndex > 0 {
+				m.profileIndex--
+			}
+		case "down", "j":
+			if m.profileIndex < len(m.profileNames)-1 {
+				m.profileIndex++
+			}
+		case "enter":
+			m.profilePicker = false
+			profile := m.profileNames[m.profileIndex]
+			m.status = fmt.Sprintf("Connecting to %s...", profile)
+			return m, m.connectProfile(profile)
+		case "esc", "q":
+			m.profilePicker = false
+		}
+	}
+
+	return m, nil
+}
+
+// connectProfile builds the clients of a profile in the background, they arrive as a ProfileSwitchedMsg
+func (m *TabModel) connectProfile(profile string) tea.Cmd {
+	connection, err := m.programContext.Config.ProfileConnection(profile)
+
+	return func() tea.Msg {
+		if err != nil {
+			return ProfileSwitchedMsg{Profile: profile, Err: err}
+		}
+
+		esClient, err := elasticsearch.SetClient(connection)
+		if err != nil {
+			return ProfileSwitchedMsg{Profile: profile, Err: fmt.Errorf("error setting up Elasticsearch client: %w", err)}
+		}
+
+		kbClient, err := kibana.SetClient(connection)
+		if err != nil {
+			return ProfileSwitchedMsg{Profile: profile, Err: fmt.Errorf("error setting up Kibana client: %w", err)}
+		}
+
+		return ProfileSwitchedMsg{Profile: profile, ESClient: esClient, KBClient: kbClient}
+	}
+}
+
+// switchProfile makes the profile active with its clients and saves it as the active profile
+func (m *TabModel) switchProfile(msg ProfileSwitchedMsg) tea.Cmd {
+	m.status = StopedMsg
+
+	if msg.Err == nil {
+		msg.Err = m.programContext.Config.UseProfile(msg.Profile)
+	}
+	if msg.Err != nil {
+		log.Debug(msg.Err)
+		return func() tea.Msg {
+			return uiErrors.ShowErrorMsg{Message: fmt.Sprintf("Error: %v", msg.Err)}
+		}
+	}
+
+	m.programContext.SetClients(msg.ESClient, msg.KBClient)
+	m.saveController.MarkDirty()
+
+	// Sessions belong to a profile, writes to the new cluster start another one
+	m.recorder = nil
+
+	log.Debug(fmt.Sprintf("Switched to profile %s", msg.Profile))
+	return nil
+}
+
+// profilePickerView renders the list of connection profiles
+func (m *TabModel) profilePickerView() string {
+	active := m.programContext.Config.Profile()
+
+	var list strings.Builder
+	list.WriteString(style.TitleStyle.Render("Profiles") + "\n\n")
+	for i, name := range m.profileNames {
+		if name == active {
+			name += " (active)"
+		}
+		if i == m.profileIndex {
+			list.WriteString(fmt.Sprintf("  > %s\n", name))
+		} else {
+			list.WriteString(fmt.Sprintf("    %s\n", name))
+		}
+	}
+
+	help := style.FormatHelp(
+		"(enter)", "Connect",
+		"(↑/↓)", "Navigate",
+		"(esc)", "Cancel",
+	)
+
+	return baseStyle.Width(m.width-2).Render(list.String()) + "\n" + help
+}
@@ -0,0 +1,165 @@
+package run
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/tehbooom/elastic-data/internal/config"
+)
+
+const (
+	// rateIdleInterval time before the target is checked again while it is 0
+	rateIdleInterval = time.Second
+	// maxCatchUp longest backlog caught up with, events due before it are skipped
+	maxCatchUp = 30 * time.Second
+	// catchUpBatches number of batches a single catch-up batch holds at most
+	catchUpBatches = 4
+)
+
+// rateProfile computes the target events per second of a dataset from its rate profile
+type rateProfile struct {
+	profile   config.RateProfile
+	threshold float64
+	start     time.Time
+	random    *rand.Rand
+	// burstStart start of the current or next burst
+	burstStart time.Time
+}
+
+func newRateProfile(profile config.RateProfile, threshold int, start time.Time) *rateProfile {
+	r := &rateProfile{
+		profile:   profile.WithDefaults(),
+		threshold: float64(threshold),
+		start:     start,
+		random:    rand.New(rand.NewSource(start.UnixNano())),
+	}
+
+	if r.profile.Bursts != nil {
+		r.burstStart = start.Add(r.nextBurst())
+	}
+
+	return r
+}
+
+// target returns the events per second the dataset should be sent at
+func (r *rateProfile) target(now time.Time) float64 {
+	elapsed := now.Sub(r.start)
+	low := float64(r.profile.Min)
+
+	var rate float64
+	switch r.profile.Shape {
+	case config.RateRamp:
+		progress := math.Min(float64(elapsed)/float64(r.profile.Duration), 1)
+		rate = low + (r.threshold-low)*progress
+	case config.RateSine:
+		phase := 2 * math.Pi * float64(elapsed) / float64(r.profile.Period)
+		rate = low + (r.threshold-low)*(1+math.Sin(phase))/2
+	case config.RateDiurnal:
+		hour := float64(now.Hour()) + float64(now.Minute())/60 + float64(now.Second())/3600
+		phase := 2 * math.Pi * (hour - float64(*r.profile.PeakHour)) / 24
+		rate = low + (r.threshold-low)*(1+math.Cos(phase))/2
+	case config.RateSteps:
+		rate = r.step(elapsed)
+	default:
+		rate = r.threshold
+	}
+
+	if r.inBurst(now) {
+		rate *= r.profile.Bursts.Multiplier
+	}
+
+	return rate
+}
+
+// step returns the rate of the last step started, repeating the schedule every period when set
+func (r *rateProfile) step(elapsed time.Duration) float64 {
+	if r.profile.Period > 0 {
+		elapsed %= r.profile.Period
+	}
+
+	rate := r.threshold
+	for _, step := range r.profile.Steps {
+		if elapsed < step.After {
+			break
+		}
+		rate = float64(step.Rate)
+	}
+	return rate
+}
+
+// inBurst reports whether a burst is under way, scheduling the next one once it is over
+func (r *rateProfile) inBurst(now time.Time) bool {
+	bursts := r.profile.Bursts
+	if bursts == nil {
+		return false
+	}
+
+	for !now.Before(r.burstStart.Add(bursts.Duration)) {
+		r.burstStart = r.burstStart.Add(bursts.Duration + r.nextBurst())
+	}
+
+	return !now.Before(r.burstStart)
+}
+
+// nextBurst returns the random time until the next burst, bursts arriving independently
+// at the configured average interval
+func (r *rateProfile) nextBurst() time.Duration {
+	return time.Duration(r.random.ExpFloat64() * float64(r.profile.Bursts.Every))
+}
+
+// rateController paces batches with a token bucket. Events become due at the target rate
+// and a batch is sent once enough are due, so time lost to a slow bulk request is caught
+// up with by larger batches sent back to back instead of quietly lowering the rate.
+type rateController struct {
+	// due events that should have been sent by now
+	due  float64
+	last time.Time
+	// skipped events dropped from a backlog longer than maxCatchUp
+	skipped int
+}
+
+// newRateController starts pacing at start with the first events already due
+func newRateController(start time.Time, due float64) *rateController {
+	return &rateController{due: due, last: start}
+}
+
+// advance makes the events of the time since the last call due at the target rate
+func (c *rateController) advance(now time.Time, target float64) {
+	c.due += now.Sub(c.last).Seconds() * target
+	c.last = now
+
+	// Nothing is owed while the profile asks for no events
+	if target <= 0 {
+		c.due = 0
+		return
+	}
+
+	if limit := target * maxCatchUp.Seconds(); c.due > limit {
+		c.skipped += int(c.due - limit)
+		c.due = limit
+	}
+}
+
+// next returns the number of events to send now, or when none, the time until a full batch is due
+func (c *rateController) next(target float64, batchSize int) (int, time.Duration) {
+	if target <= 0 {
+		return 0, rateIdleInterval
+	}
+
+	if c.due < float64(batchSize) {
+		return 0, time.Duration((float64(batchSize) - c.due) / target * float64(time.Second))
+	}
+
+	count := min(int(c.due), batchSize*catchUpBatches)
+	c.due -= float64(count)
+	return count, 0
+}
+
+// lag returns how far sending is behind the target rate
+func (c *rateController) lag(target float64) time.Duration {
+	if target <= 0 {
+		return 0
+	}
+	return time.Duration(c.due / target * float64(time.Second))
+}
@@ -0,0 +1,300 @@
+package run
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/tehbooom/elastic-data/internal/config"
+)
+
+func hour(h int) *int {
+	return &h
+}
+
+func TestRateProfileTarget(t *testing.T) {
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		profile config.RateProfile
+		// target by time since start
+		targets map[time.Duration]float64
+	}{
+		{
+			name:    "constant",
+			profile: config.RateProfile{},
+			targets: map[time.Duration]float64{0: 100, time.Hour: 100},
+		},
+		{
+			name:    "ramp",
+			profile: config.RateProfile{Shape: config.RateRamp, Min: 20, Duration: 10 * time.Minute},
+			targets: map[time.Duration]float64{0: 20, 5 * time.Minute: 60, 10 * time.Minute: 100, time.Hour: 100},
+		},
+		{
+			name:    "sine",
+			profile: config.RateProfile{Shape: config.RateSine, Min: 20, Period: 4 * time.Minute},
+			targets: map[time.Duration]float64{0: 60, time.Minute: 100, 2 * time.Minute: 60, 3 * time.Minute: 20, 4 * time.Minute: 60},
+		},
+		{
+			name:    "sine default period",
+			profile: config.RateProfile{Shape: config.RateSine},
+			targets: map[time.Duration]float64{15 * time.Minute: 100, 45 * time.Minute: 0},
+		},
+		{
+			name:    "diurnal default peak",
+			profile: config.RateProfile{Shape: config.RateDiurnal, Min: 20},
+			targets: map[time.Duration]float64{14 * time.Hour: 100, 2 * time.Hour: 20, 8 * time.Hour: 60},
+		},
+		{
+			name:    "diurnal peak at midnight",
+			profile: config.RateProfile{Shape: config.RateDiurnal, PeakHour: hour(0)},
+			targets: map[time.Duration]float64{0: 100, 24 * time.Hour: 100, 12 * time.Hour: 0, 6 * time.Hour: 50},
+		},
+		{
+			name:    "diurnal peak at 23",
+			profile: config.RateProfile{Shape: config.RateDiurnal, PeakHour: hour(23)},
+			targets: map[time.Duration]float64{23 * time.Hour: 100, 11 * time.Hour: 0},
+		},
+		{
+			name: "steps",
+			profile: config.RateProfile{Shape: config.RateSteps, Steps: []config.RateStep{
+				{After: time.Minute, Rate: 10},
+				{After: 5 * time.Minute, Rate: 300},
+			}},
+			targets: map[time.Duration]float64{0: 100, time.Minute: 10, 4 * time.Minute: 10, 5 * time.Minute: 300, time.Hour: 300},
+		},
+		{
+			name: "repeating steps",
+			profile: config.RateProfile{Shape: config.RateSteps, Period: 10 * time.Minute, Steps: []config.RateStep{
+				{After: 0, Rate: 10},
+				{After: 5 * time.Minute, Rate: 300},
+			}},
+			targets: map[time.Duration]float64{0: 10, 5 * time.Minute: 300, 10 * time.Minute: 10, 16 * time.Minute: 300},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile := newRateProfile(tt.profile, 100, start)
+			for elapsed, want := range tt.targets {
+				if got := profile.target(start.Add(elapsed)); math.Abs(got-want) > 1e-9 {
+					t.Errorf("target after %s = %f, want %f", elapsed, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRateProfileBursts(t *testing.T) {
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, shape := range []string{config.RateConstant, config.RateRamp, config.RateSine, config.RateDiurnal, config.RateSteps} {
+		t.Run(shape, func(t *testing.T) {
+			profile := newRateProfile(config.RateProfile{
+				Shape:    shape,
+				Min:      10,
+				Duration: time.Hour,
+				Period:   time.Hour,
+				Steps:    []config.RateStep{{After: time.Minute, Rate: 40}},
+				Bursts:   &config.RateBursts{Every: 10 * time.Minute, Duration: time.Minute, Multiplier: 3},
+			}, 100, start)
+
+			// Place the next burst so the test does not depend on the random schedule
+			burstStart := start.Add(20 * time.Minute)
+			profile.burstStart = burstStart
+
+			if profile.inBurst(burstStart.Add(-time.Second)) {
+				t.Error("in a burst before it starts")
+			}
+
+			base := newRateProfile(config.RateProfile{Shape: shape, Min: 10, Duration: time.Hour, Period: time.Hour, Steps: profile.profile.Steps}, 100, start)
+			for _, offset := range []time.Duration{0, 30 * time.Second, time.Minute - time.Nanosecond} {
+				now := burstStart.Add(offset)
+				if !profile.inBurst(now) {
+					t.Fatalf("not in a burst %s after it started", offset)
+				}
+				if got, want := profile.target(now), 3*base.target(now); math.Abs(got-want) > 1e-9 {
+					t.Errorf("target %s into a burst = %f, want %f", offset, got, want)
+				}
+			}
+
+			// Once over the next burst is scheduled after the end of this one
+			end := burstStart.Add(time.Minute)
+			profile.inBurst(end)
+			if profile.burstStart.Before(end) {
+				t.Errorf("next burst at %s, want after %s", profile.burstStart, end)
+			}
+		})
+	}
+}
+
+func TestRateProfileBurstSchedule(t *testing.T) {
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	profile := newRateProfile(config.RateProfile{Bursts: &config.RateBursts{Every: time.Minute}}, 100, start)
+
+	var inBurst time.Duration
+	for now := start; now.Before(start.Add(24 * time.Hour)); now = now.Add(time.Second) {
+		if profile.inBurst(now) {
+			inBurst += time.Second
+		}
+	}
+
+	// Bursts take 30s by default and the next one starts on average a minute after
+	if share := inBurst.Seconds() / (24 * time.Hour).Seconds(); share < 0.25 || share > 0.42 {
+		t.Errorf("in a burst %.2f of the time, want about a third", share)
+	}
+}
+
+// fakeClock is the time seen by a rate controller, moved forward by the test
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+// sendDue sends the batches due now the way startEPS does, returning their sizes
+func sendDue(clock *fakeClock, controller *rateController, target float64, batchSize int) ([]int, time.Duration) {
+	var batches []int
+	for {
+		controller.advance(clock.Now(), target)
+		count, wait := controller.next(target, batchSize)
+		if count == 0 {
+			return batches, wait
+		}
+		batches = append(batches, count)
+	}
+}
+
+func TestRateControllerSteady(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)}
+	controller := newRateController(clock.Now(), 100)
+
+	sent := 0
+	for i := 0; i < 60; i++ {
+		batches, wait := sendDue(clock, controller, 100, 100)
+		for _, batch := range batches {
+			sent += batch
+		}
+		if lag := controller.lag(100); lag != 0 {
+			t.Fatalf("lag %s after %d batches, want 0", lag, i)
+		}
+		clock.Advance(wait)
+	}
+
+	// The first batch is due at the start, then one every second
+	if sent != 6000 {
+		t.Errorf("sent %d events in a minute at 100 EPS, want 6000", sent)
+	}
+	if controller.skipped != 0 {
+		t.Errorf("skipped %d events", controller.skipped)
+	}
+}
+
+func TestRateControllerCatchUp(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)}
+	controller := newRateController(clock.Now(), 0)
+
+	// A bulk request taking 10s leaves 1000 events due at 100 EPS
+	clock.Advance(10 * time.Second)
+	controller.advance(clock.Now(), 100)
+	if lag := controller.lag(100); lag != 10*time.Second {
+		t.Errorf("lag %s after a 10s stall, want 10s", lag)
+	}
+
+	// The backlog is sent in batches of at most four batch sizes, back to back
+	batches, wait := sendDue(clock, controller, 100, 100)
+	if fmt.Sprint(batches) != "[400 400 200]" {
+		t.Errorf("caught up with batches %v, want [400 400 200]", batches)
+	}
+	if wait != time.Second {
+		t.Errorf("next batch in %s, want 1s", wait)
+	}
+	if lag := controller.lag(100); lag != 0 {
+		t.Errorf("lag %s once caught up, want 0", lag)
+	}
+	if controller.skipped != 0 {
+		t.Errorf("skipped %d events of a backlog under %s", controller.skipped, maxCatchUp)
+	}
+}
+
+func TestRateControllerSlowRequests(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)}
+	controller := newRateController(clock.Now(), 100)
+
+	// Every request takes 3s, the batches grow so the rate is kept
+	sent := 0
+	for i := 0; i < 20; i++ {
+		controller.advance(clock.Now(), 100)
+		count, wait := controller.next(100, 100)
+		if count == 0 {
+			clock.Advance(wait)
+			continue
+		}
+		if count > 100*catchUpBatches {
+			t.Fatalf("batch of %d events, want at most %d", count, 100*catchUpBatches)
+		}
+		sent += count
+		clock.Advance(3 * time.Second)
+	}
+
+	elapsed := clock.Now().Sub(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC))
+	if want := int(elapsed.Seconds()*100) - 300; sent < want {
+		t.Errorf("sent %d events in %s at 100 EPS, want at least %d", sent, elapsed, want)
+	}
+	if lag := controller.lag(100); lag > 3*time.Second {
+		t.Errorf("lag %s, want at most the duration of a request", lag)
+	}
+}
+
+func TestRateControllerSkipped(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)}
+	controller := newRateController(clock.Now(), 0)
+
+	// Events due more than maxCatchUp ago are skipped instead of sent
+	clock.Advance(time.Minute)
+	controller.advance(clock.Now(), 100)
+	if controller.skipped != 3000 {
+		t.Errorf("skipped %d events after a minute stall, want 3000", controller.skipped)
+	}
+	if lag := controller.lag(100); lag != maxCatchUp {
+		t.Errorf("lag %s, want %s", lag, maxCatchUp)
+	}
+
+	// Skipped events add up over stalls
+	sendDue(clock, controller, 100, 100)
+	clock.Advance(40 * time.Second)
+	controller.advance(clock.Now(), 100)
+	if controller.skipped != 4000 {
+		t.Errorf("skipped %d events after a second stall, want 4000", controller.skipped)
+	}
+}
+
+func TestRateControllerIdle(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)}
+	controller := newRateController(clock.Now(), 0)
+
+	// Nothing is owed while the target is 0, so no backlog builds up
+	clock.Advance(time.Hour)
+	batches, wait := sendDue(clock, controller, 0, 100)
+	if len(batches) != 0 || wait != rateIdleInterval {
+		t.Errorf("sent %v and waits %s at 0 EPS, want nothing and %s", batches, wait, rateIdleInterval)
+	}
+	if lag := controller.lag(0); lag != 0 || controller.skipped != 0 {
+		t.Errorf("lag %s and %d skipped at 0 EPS", lag, controller.skipped)
+	}
+
+	// Sending resumes at the new target without a backlog
+	clock.Advance(500 * time.Millisecond)
+	batches, wait = sendDue(clock, controller, 100, 100)
+	if len(batches) != 0 || wait != 500*time.Millisecond {
+		t.Errorf("sent %v and waits %s after resuming, want nothing and 500ms", batches, wait)
+	}
+}
@@ -0,0 +1,168 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/log"
+	"github.com/tehbooom/elastic-data/internal/scenario"
+	uiErrors "github.com/tehbooom/elastic-data/ui/errors"
+	"github.com/tehbooom/elastic-data/ui/style"
+)
+
+// openScenarioPicker lists the scenarios next to the config file
+func (m *TabModel) openScenarioPicker() tea.Cmd {
+	configDir := m.programContext.ConfigPath
+
+	names, err := scenario.List(configDir)
+	if err != nil {
+		log.Debug(err)
+		return func() tea.Msg {
+			return uiErrors.ShowErrorMsg{Message: fmt.Sprintf("Error: %v", err)}
+		}
+	}
+
+	if len(names) == 0 {
+		return func() tea.Msg {
+			return uiErrors.ShowErrorMsg{Message: fmt.Sprintf("No scenarios found in %s", scenario.Dir(configDir))}
+		}
+	}
+
+	m.scenarioNames = names
+	m.scenarioIndex = 0
+	m.scenarioPicker = true
+
+	return nil
+}
+
+func (m *TabModel) updateScenarioPicker(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "up", "k":
+			if m.scenarioIndex > 0 {
+				m.scenarioIndex--
+			}
+		case "down", "j":
+			if m.scenarioIndex < len(m.scenarioNames)-1 {
+				m.scenarioIndex++
+			}
+		case "enter":
+			m.scenarioPicker = false
+			if err := m.startScenario(m.scenarioNames[m.scenarioIndex]); err != nil {
+				log.Debug(err)
+				return m, func() tea.Msg {
+					return uiErrors.ShowErrorMsg{Message: fmt.Sprintf("Error: %v", err)}
+				}
+			}
+			return m, CreateTickCmd()
+		case "esc", "q":
+			m.scenarioPicker = false
+		}
+	}
+
+	return m, nil
+}
+
+// startScenario plays a scenario alongside any running generators
+func (m *TabModel) startScenario(name string) error {
+	if m.scenarioRunning() {
+		return fmt.Errorf("a scenario is already running")
+	}
+
+	playbook, err := scenario.Load(m.programContext.ConfigPath, name)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(m.mainCtx)
+	runner := &scenario.Runner{
+		Config: m.programContext.Config,
+		ES:     m.programContext.ESClient,
+		KB:     m.programContext.KBClient,
+		OnStep: func(index int, step scenario.Step) {
+			m.setScenarioStatus(fmt.Sprintf("Scenario %s: step %d/%d %s", playbook.Name, index+1, len(playbook.Steps), step.Name))
+		},
+		Session: m.sessionRecorder(),
+	}
+
+	m.scenarioMu.Lock()
+	m.scenarioCancel = cancel
+	m.scenarioMu.Unlock()
+
+	go func() {
+		defer cancel()
+		results, err := runner.Run(ctx, playbook)
+
+		var sent, failed int
+		for _, result := range results {
+			sent += result.Sent
+			failed += result.Failed
+		}
+
+		status := fmt.Sprintf("Scenario %s finished: %d events sent, %d failed", playbook.Name, sent, failed)
+		switch {
+		case errors.Is(err, context.Canceled):
+			status = fmt.Sprintf("Scenario %s stopped", playbook.Name)
+		case err != nil:
+			log.Debug(err)
+			status = fmt.Sprintf("Scenario %s failed: %v", playbook.Name, err)
+		}
+
+		m.scenarioMu.Lock()
+		m.scenarioCancel = nil
+		m.scenarioStatus = status
+		m.scenarioMu.Unlock()
+	}()
+
+	return nil
+}
+
+func (m *TabModel) stopScenario() {
+	m.scenarioMu.Lock()
+	defer m.scenarioMu.Unlock()
+	if m.scenarioCancel != nil {
+		m.scenarioCancel()
+	}
+}
+
+func (m *TabModel) scenarioRunning() bool {
+	m.scenarioMu.Lock()
+	defer m.scenarioMu.Unlock()
+	return m.scenarioCancel != nil
+}
+
+func (m *TabModel) setScenarioStatus(status string) {
+	m.scenarioMu.Lock()
+	defer m.scenarioMu.Unlock()
+	m.scenarioStatus = status
+}
+
+func (m *TabModel) getScenarioStatus() string {
+	m.scenarioMu.Lock()
+	defer m.scenarioMu.Unlock()
+	return m.scenarioStatus
+}
+
+// scenarioPickerView renders the list of scenarios
+func (m *TabModel) scenarioPickerView() string {
+	var list strings.Builder
+	list.WriteString(style.TitleStyle.Render("Scenarios") + "\n\n")
+	for i, name := range m.scenarioNames {
+		if i == m.scenarioIndex {
+			list.WriteString(fmt.Sprintf("  > %s\n", name))
+		} else {
+			list.WriteString(fmt.Sprintf("    %s\n", name))
+		}
+	}
+
+	help := style.FormatHelp(
+		"(enter)", "Play scenario",
+		"(↑/↓)", "Navigate",
+		"(esc)", "Cancel",
+	)
+
+	return baseStyle.Width(m.width-2).Render(list.String()) + "\n" + help
+}
@@ -1,19 +1,33 @@
 package run
 
 import (
+	"maps"
 	"math"
 	"sync"
 	"time"
+
+	"github.com/tehbooom/elastic-data/internal/elasticsearch"
 )
 
+// rateWindow time the achieved rate is averaged over
+const rateWindow = 10 * time.Second
+
 type IntegrationStats struct {
 	// SentBytes amount of bytes sent
 	SentBytes float64
 	// SentBytesUnit unit of storage values are b, KB, MB,
 	// GB, TB, PB, EB, ZB, YB
 	SentBytesUnit string
-	// SentEvents number of events sent for this integration
+	// SentEvents number of events sent for this integration and indexed
 	SentEvents int
+	// IndexedEvents number of events indexed by Elasticsearch
+	IndexedEvents int
+	// FailedEvents number of events Elasticsearch failed to index
+	FailedEvents int
+	// FailureReasons failed events grouped by error type
+	FailureReasons map[string]int
+	// BulkErrors number of bulk requests that failed
+	BulkErrors int
 	// Current the latency in milliseconds for each bulk request to Elasticsearch
 	Current float64
 	// Peak the largest latency spike in milliseconds for bulk request to Elasticsearch
@@ -22,6 +36,16 @@ type IntegrationStats struct {
 	Unit string
 	// Trend up down or neutral for the msot recent latency duration compared to the median
 	Trend string
+	// TargetRate events per second the rate profile currently asks for
+	TargetRate float64
+	// Lag how far sending is behind the target rate
+	Lag time.Duration
+	// SkippedEvents events that were due but dropped as sending fell too far behind
+	SkippedEvents int
+	// recentSends events sent within the rate window, used for the achieved rate
+	recentSends []rateSample
+	// firstSend time events were first sent
+	firstSend time.Time
 	// recentBatches a queue of recent bulk requests
 	recentBatches []BatchInfo
 	// lastUpdate time the stats were last updated
@@ -29,11 +53,85 @@ type IntegrationStats struct {
 	mu         sync.RWMutex
 }
 
+type rateSample struct {
+	at     time.Time
+	events int
+}
+
 type BatchInfo struct {
 	Events   int
 	Duration float64
 }
 
+// Snapshot returns a copy of the stats that is safe to read without holding the lock
+func (stats *IntegrationStats) Snapshot() StatsSnapshot {
+	stats.mu.RLock()
+	defer stats.mu.RUnlock()
+
+	return StatsSnapshot{
+		Current:        stats.Current,
+		Peak:           stats.Peak,
+		Trend:          stats.Trend,
+		Unit:           stats.Unit,
+		SentBytes:      stats.SentBytes,
+		SentBytesUnit:  stats.SentBytesUnit,
+		SentEvents:     stats.SentEvents,
+		IndexedEvents:  stats.IndexedEvents,
+		FailedEvents:   stats.FailedEvents,
+		FailureReasons: maps.Clone(stats.FailureReasons),
+		BulkErrors:     stats.BulkErrors,
+		TargetRate:     stats.TargetRate,
+		AchievedRate:   stats.achievedRate(time.Now()),
+		Lag:            stats.Lag,
+		SkippedEvents:  stats.SkippedEvents,
+	}
+}
+
+// recordRate adds indexed events to the rate window, dropping the ones that fell out of it
+func (stats *IntegrationStats) recordRate(now time.Time, events int) {
+	if stats.firstSend.IsZero() {
+		stats.firstSend = now
+	}
+
+	stats.recentSends = append(stats.recentSends, rateSample{at: now, events: events})
+	for len(stats.recentSends) > 0 && now.Sub(stats.recentSends[0].at) > rateWindow {
+		stats.recentSends = stats.recentSends[1:]
+	}
+}
+
+// achievedRate returns the events per second indexed over the rate window
+func (stats *IntegrationStats) achievedRate(now time.Time) float64 {
+	if stats.firstSend.IsZero() {
+		return 0
+	}
+
+	var events int
+	for _, sample := range stats.recentSends {
+		if now.Sub(sample.at) <= rateWindow {
+			events += sample.events
+		}
+	}
+
+	span := min(rateWindow, max(now.Sub(stats.firstSend), time.Second))
+	return float64(events) / span.Seconds()
+}
+
+// AddFailures adds the failed events of a bulk request to the stats
+func (stats *IntegrationStats) AddFailures(result elasticsearch.BulkResult) {
+	if result.Failed == 0 {
+		return
+	}
+
+	if stats.FailureReasons == nil {
+		stats.FailureReasons = make(map[string]int)
+	}
+
+	stats.FailedEvents += result.Failed
+	for errorType, failure := range result.Failures {
+		stats.FailureReasons[errorType] += failure.Count
+	}
+}
+
 func (stats *IntegrationStats) EnqueueRecentBatches(batch BatchInfo) {
 	stats.recentBatches = append(stats.recentBatches, batch)
 
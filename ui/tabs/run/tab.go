@@ -5,14 +5,21 @@ import (
 	"sync"
 	"time"
 
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss/table"
+	"github.com/tehbooom/elastic-data/internal/elasticsearch"
+	"github.com/tehbooom/elastic-data/internal/session"
 	ProgramContext "github.com/tehbooom/elastic-data/ui/context"
 )
 
 const (
-	StopedMsg  string = "Waiting to start"
-	StartedMsg string = "Running"
+	StopedMsg      string = "Waiting to start"
+	StartedMsg     string = "Running"
+	BackfillingMsg string = "Backfilling"
+
+	// defaultBackfillRange how far back the backfill form starts by default
+	defaultBackfillRange = 7 * 24 * time.Hour
 )
 
 // TabModel represents the integrations tab
@@ -24,32 +31,68 @@ type TabModel struct {
 	integrations          map[string]*IntegrationStats
 	table                 *table.Table
 	status                string
-	installedIntegrations []string
+	installedIntegrations map[string]string
 	generators            map[string]*DataGenerator
 	mu                    sync.RWMutex
 	mainCtx               context.Context
 	mainCancel            context.CancelFunc
 	wg                    sync.WaitGroup
+	backfillForm          bool
+	backfillStartInput    textinput.Model
+	backfillEndInput      textinput.Model
+	scenarioPicker        bool
+	scenarioNames         []string
+	scenarioIndex         int
+	scenarioStatus        string
+	scenarioCancel        context.CancelFunc
+	scenarioMu            sync.Mutex
+	profilePicker         bool
+	profileNames          []string
+	profileIndex          int
+	cleanupPicker         bool
+	cleanupSessions       []session.Session
+	cleanupIndex          int
+	cleanupStats          []elasticsearch.DataStreamStats
+	cleanupStatus         string
+	cleanupConfirm        bool
+	cleanupReady          bool
+	recorder              *session.Recorder
 }
 
 // NewTabModel creates a new run tab model
 func NewTabModel(programContext *ProgramContext.ProgramContext, saveController *ProgramContext.SaveController) *TabModel {
 	ctx, cancel := context.WithCancel(context.Background())
+
+	startInput := textinput.New()
+	startInput.Placeholder = "2006-01-02T15:04:05Z"
+	startInput.CharLimit = 35
+
+	endInput := textinput.New()
+	endInput.Placeholder = "now"
+	endInput.CharLimit = 35
+
 	model := &TabModel{
-		programContext:        programContext,
-		saveController:        saveController,
-		integrations:          make(map[string]*IntegrationStats),
-		status:                StopedMsg,
-		installedIntegrations: []string{},
-		mainCtx:               ctx,
-		mainCancel:            cancel,
-		generators:            make(map[string]*DataGenerator),
+		programContext:     programContext,
+		saveController:     saveController,
+		integrations:       make(map[string]*IntegrationStats),
+		status:             StopedMsg,
+		mainCtx:            ctx,
+		mainCancel:         cancel,
+		generators:         make(map[string]*DataGenerator),
+		backfillStartInput: startInput,
+		backfillEndInput:   endInput,
 	}
 	model.RefreshIntegrations()
 
 	return model
 }
 
+// IsInForm reports whether the backfill range is being entered, a scenario or profile picked
+// or a session cleaned up
+func (m *TabModel) IsInForm() bool {
+	return m.backfillForm || m.scenarioPicker || m.profilePicker || m.cleanupPicker
+}
+
 // TabTitle returns the title of the tab
 func (m *TabModel) TabTitle() string {
 	return "Run"
@@ -89,6 +132,10 @@ func (m *RunTabModel) TabTitle() string {
 	return m.TabModel.TabTitle()
 }
 
+func (m *RunTabModel) IsInForm() bool {
+	return m.TabModel.IsInForm()
+}
+
 func (m *RunTabModel) SetSize(width, height int) {
 	m.TabModel.SetSize(width, height)
 }
@@ -12,9 +12,35 @@ import (
 type TickMsg struct{}
 
 func (m *TabModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case ProfileSwitchedMsg:
+		return m, m.switchProfile(msg)
+	case cleanupStatsMsg:
+		m.showCleanupStats(msg)
+		return m, nil
+	case cleanupDoneMsg:
+		return m, m.finishCleanup(msg)
+	}
+
+	if m.backfillForm {
+		return m.updateBackfillForm(msg)
+	}
+
+	if m.scenarioPicker {
+		return m.updateScenarioPicker(msg)
+	}
+
+	if m.profilePicker {
+		return m.updateProfilePicker(msg)
+	}
+
+	if m.cleanupPicker {
+		return m.updateCleanup(msg)
+	}
+
 	switch msg := msg.(type) {
 	case TickMsg:
-		if !m.programContext.IsRunning() {
+		if !m.programContext.IsRunning() && !m.scenarioRunning() {
 			return m, nil
 		}
 
@@ -25,6 +51,7 @@ func (m *TabModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "q", "esc":
+			m.stopScenario()
 			if m.programContext.IsRunning() {
 				m.programContext.SetRunning(false)
 				m.status = "Stopping..."
@@ -32,37 +59,25 @@ func (m *TabModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.status = "Waiting to start"
 			}
 			return m, nil
-		case "enter":
+		case "s":
+			return m, m.openScenarioPicker()
+		case "c":
+			return m, m.openProfilePicker()
+		case "x":
+			return m, m.openCleanup()
+		case "b":
 			if !m.programContext.IsRunning() {
-				m.status = StartedMsg
-				err := m.programContext.ESClient.TestConnection()
-				if err != nil {
-					log.Debug(err)
-					return m, func() tea.Msg {
-						return errors.ShowErrorMsg{Message: fmt.Sprintf("Error: %v", err)}
-					}
-				}
-
-				err = m.programContext.KBClient.TestConnection()
-				if err != nil {
-					log.Debug(err)
-					return m, func() tea.Msg {
-						return errors.ShowErrorMsg{Message: fmt.Sprintf("Error: %v", err)}
-					}
-				}
-
-				err = m.StartGeneration()
-				if err != nil {
-					log.Debug(err)
-					return m, func() tea.Msg {
-						return errors.ShowErrorMsg{Message: fmt.Sprintf("Error generating data: %v", err)}
-					}
+				if m.backfillStartInput.Value() == "" {
+					m.backfillStartInput.SetValue(time.Now().UTC().Add(-defaultBackfillRange).Format(time.RFC3339))
 				}
-
-				m.programContext.SetRunning(true)
-				return m, tea.Tick(time.Second, func(time.Time) tea.Msg {
-					return TickMsg{}
-				})
+				m.backfillStartInput.Focus()
+				m.backfillEndInput.Blur()
+				m.backfillForm = true
+			}
+			return m, nil
+		case "enter":
+			if !m.programContext.IsRunning() {
+				return m.start(nil)
 			} else {
 				m.programContext.SetRunning(false)
 				m.status = "Stopping..."
@@ -75,3 +90,83 @@ func (m *TabModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	return m, nil
 }
+
+func (m *TabModel) updateBackfillForm(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "tab":
+			if m.backfillStartInput.Focused() {
+				m.backfillStartInput.Blur()
+				m.backfillEndInput.Focus()
+			} else {
+				m.backfillEndInput.Blur()
+				m.backfillStartInput.Focus()
+			}
+			return m, nil
+
+		case "enter":
+			backfill, err := ParseBackfill(m.backfillStartInput.Value(), m.backfillEndInput.Value())
+			if err != nil {
+				log.Debug(err)
+				return m, func() tea.Msg {
+					return errors.ShowErrorMsg{Message: fmt.Sprintf("Error: %v", err)}
+				}
+			}
+
+			m.backfillForm = false
+			return m.start(&backfill)
+
+		case "esc":
+			m.backfillForm = false
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	if m.backfillStartInput.Focused() {
+		m.backfillStartInput, cmd = m.backfillStartInput.Update(msg)
+	} else {
+		m.backfillEndInput, cmd = m.backfillEndInput.Update(msg)
+	}
+	return m, cmd
+}
+
+// start tests the connections and starts generating, sending historical
+// events for the range when backfill is set
+func (m *TabModel) start(backfill *Backfill) (tea.Model, tea.Cmd) {
+	m.status = StartedMsg
+	err := m.programContext.ESClient.TestConnection()
+	if err != nil {
+		log.Debug(err)
+		return m, func() tea.Msg {
+			return errors.ShowErrorMsg{Message: fmt.Sprintf("Error: %v", err)}
+		}
+	}
+
+	err = m.programContext.KBClient.TestConnection()
+	if err != nil {
+		log.Debug(err)
+		return m, func() tea.Msg {
+			return errors.ShowErrorMsg{Message: fmt.Sprintf("Error: %v", err)}
+		}
+	}
+
+	if backfill != nil {
+		m.status = BackfillingMsg
+		err = m.StartBackfill(*backfill)
+	} else {
+		err = m.StartGeneration()
+	}
+	if err != nil {
+		log.Debug(err)
+		return m, func() tea.Msg {
+			return errors.ShowErrorMsg{Message: fmt.Sprintf("Error generating data: %v", err)}
+		}
+	}
+
+	m.programContext.SetRunning(true)
+	return m, tea.Tick(time.Second, func(time.Time) tea.Msg {
+		return TickMsg{}
+	})
+}
@@ -3,7 +3,9 @@ package run
 import (
 	"fmt"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/lipgloss/table"
@@ -23,13 +25,21 @@ var (
 )
 
 type StatsSnapshot struct {
-	Current       float64
-	Peak          float64
-	Trend         string
-	Unit          string
-	SentBytes     float64
-	SentBytesUnit string
-	SentEvents    int
+	Current        float64
+	Peak           float64
+	Trend          string
+	Unit           string
+	SentBytes      float64
+	SentBytesUnit  string
+	SentEvents     int
+	IndexedEvents  int
+	FailedEvents   int
+	FailureReasons map[string]int
+	BulkErrors     int
+	TargetRate     float64
+	AchievedRate   float64
+	Lag            time.Duration
+	SkippedEvents  int
 }
 
 func (m *TabModel) getStatsSnapshot() map[string]StatsSnapshot {
@@ -39,42 +49,35 @@ func (m *TabModel) getStatsSnapshot() map[string]StatsSnapshot {
 	snapshot := make(map[string]StatsSnapshot)
 	for integration, generator := range m.generators {
 		if generator.stats != nil {
-			generator.stats.mu.RLock()
-			snapshot[integration] = StatsSnapshot{
-				Current:       generator.stats.Current,
-				Peak:          generator.stats.Peak,
-				Trend:         generator.stats.Trend,
-				Unit:          generator.stats.Unit,
-				SentBytes:     generator.stats.SentBytes,
-				SentBytesUnit: generator.stats.SentBytesUnit,
-				SentEvents:    generator.stats.SentEvents,
-			}
-			generator.stats.mu.RUnlock()
+			snapshot[integration] = generator.stats.Snapshot()
 		}
 	}
 
 	for integration, stat := range m.integrations {
 		if _, exists := snapshot[integration]; !exists {
-			stat.mu.RLock()
-			snapshot[integration] = StatsSnapshot{
-				Current:       stat.Current,
-				Peak:          stat.Peak,
-				Trend:         stat.Trend,
-				Unit:          stat.Unit,
-				SentBytes:     stat.SentBytes,
-				SentBytesUnit: stat.SentBytesUnit,
-				SentEvents:    stat.SentEvents,
-			}
-			stat.mu.RUnlock()
+			snapshot[integration] = stat.Snapshot()
 		}
 	}
 
 	return snapshot
 }
 
+func (m *TabModel) getHealthSnapshot() map[string]HealthSnapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snapshot := make(map[string]HealthSnapshot)
+	for integration, generator := range m.generators {
+		snapshot[integration] = generator.Health()
+	}
+
+	return snapshot
+}
+
 func (m *TabModel) RunTable() *table.Table {
-	headers := []string{"Integration", "Dataset", "Sent", "Current", "Peak", "Trend"}
+	headers := []string{"Integration", "Dataset", "Templates", "Sent", "Target", "Achieved", "Lag", "Indexed", "Failed", "Current", "Peak", "Trend"}
 	statsSnapshot := m.getStatsSnapshot()
+	healthSnapshot := m.getHealthSnapshot()
 
 	var integrationNames []string
 	for integration := range statsSnapshot {
@@ -88,9 +91,13 @@ func (m *TabModel) RunTable() *table.Table {
 		currentValue := formatLatencyAdaptive(stat.Current)
 		peakValue := formatLatencyAdaptive(stat.Peak)
 		var sent string
+		target, achieved, lag := "-", "-", "-"
 
 		if stat.Unit == "eps" {
 			sent = fmt.Sprintf("%d events", stat.SentEvents)
+			target = formatRate(stat.TargetRate) + " eps"
+			achieved = formatRate(stat.AchievedRate) + " eps"
+			lag = formatLag(stat.Lag, stat.SkippedEvents)
 		} else {
 			switch stat.SentBytesUnit {
 			case "YB":
@@ -117,7 +124,20 @@ func (m *TabModel) RunTable() *table.Table {
 
 		integrationSplit := strings.Split(integration, ":")
 
-		row := []string{integrationSplit[0], integrationSplit[1], sent, currentValue, peakValue, styledTrendIndicator}
+		failed := strconv.Itoa(stat.FailedEvents)
+		if stat.FailedEvents > 0 {
+			failed = trendUpStyle.Render(failed)
+		}
+
+		templates := "-"
+		if health, exists := healthSnapshot[integration]; exists {
+			templates = fmt.Sprintf("%d/%d", health.Templates-len(health.Quarantined), health.Templates)
+			if len(health.Quarantined) > 0 {
+				templates = trendUpStyle.Render(templates)
+			}
+		}
+
+		row := []string{integrationSplit[0], integrationSplit[1], templates, sent, target, achieved, lag, strconv.Itoa(stat.IndexedEvents), failed, currentValue, peakValue, styledTrendIndicator}
 
 		rows = append(rows, row)
 	}
@@ -156,24 +176,139 @@ func (m *TabModel) View() string {
 		statusStyle = statusStyle.Foreground(lipgloss.Color("208"))
 	}
 
-	statusDisplay := statusStyle.Render(m.status)
+	status := m.status
+	if m.programContext.Config != nil && len(m.programContext.Config.Profiles) > 0 {
+		status = fmt.Sprintf("%s (profile %s)", status, m.programContext.Config.Profile())
+	}
+
+	statusDisplay := statusStyle.Render(status)
+	if scenarioStatus := m.getScenarioStatus(); scenarioStatus != "" {
+		statusDisplay = lipgloss.JoinVertical(lipgloss.Left, statusDisplay, statusStyle.Render(scenarioStatus))
+	}
+
+	if m.backfillForm {
+		return lipgloss.JoinVertical(lipgloss.Left, "\n"+statusDisplay, m.backfillView())
+	}
+
+	if m.scenarioPicker {
+		return lipgloss.JoinVertical(lipgloss.Left, "\n"+statusDisplay, m.scenarioPickerView())
+	}
+
+	if m.profilePicker {
+		return lipgloss.JoinVertical(lipgloss.Left, "\n"+statusDisplay, m.profilePickerView())
+	}
+
+	if m.cleanupPicker {
+		return lipgloss.JoinVertical(lipgloss.Left, "\n"+statusDisplay, m.cleanupView())
+	}
 
 	m.table = m.RunTable()
 	help := style.FormatHelp(
 		"(enter)", "Start/Stop",
+		"(b)", "Backfill",
+		"(s)", "Scenario",
+		"(c)", "Profile",
+		"(x)", "Cleanup",
 		"(q)", "Stop",
 		"(tab)", "Switch tabs",
 		"(ctrl+c)", "Quit",
 	)
 
-	return lipgloss.JoinVertical(lipgloss.Left, "\n"+statusDisplay, baseStyle.Render(m.table.String())+"\n"+help)
+	tableView := baseStyle.Render(m.table.String())
+	if health := m.healthView(); health != "" {
+		tableView += "\n" + health
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left, "\n"+statusDisplay, tableView+"\n"+help)
+
+}
+
+// healthView summarizes the template failures of every dataset that had any,
+// listing the templates quarantined for the rest of the run
+func (m *TabModel) healthView() string {
+	healthSnapshot := m.getHealthSnapshot()
+
+	var names []string
+	for name, health := range healthSnapshot {
+		if len(health.Failures) > 0 {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	if len(names) == 0 {
+		return ""
+	}
+
+	wrap := lipgloss.NewStyle().Width(max(m.width-4, 20))
+
+	var view strings.Builder
+	view.WriteString(style.TitleStyle.Render("Template health") + "\n")
+	for _, name := range names {
+		health := healthSnapshot[name]
+
+		var failures []string
+		for _, kind := range []string{FailureParse, FailureExecute, FailureJSON, FailureRejected} {
+			if count := health.Failures[kind]; count > 0 {
+				failures = append(failures, fmt.Sprintf("%s %d", kind, count))
+			}
+		}
+		view.WriteString(fmt.Sprintf("  %s: %s\n", name, strings.Join(failures, ", ")))
+
+		var quarantined []string
+		for template := range health.Quarantined {
+			quarantined = append(quarantined, template)
+		}
+		slices.Sort(quarantined)
+		for _, template := range quarantined {
+			view.WriteString(wrap.Render(trendUpStyle.Render(fmt.Sprintf("    ✗ %s: %s", template, health.Quarantined[template]))) + "\n")
+		}
+	}
+
+	return view.String()
+}
+
+// backfillView renders the form for the backfill range
+func (m *TabModel) backfillView() string {
+	var form strings.Builder
+	form.WriteString(style.TitleStyle.Render("Backfill") + "\n\n")
+	form.WriteString(fmt.Sprintf("  Start: %s\n", m.backfillStartInput.View()))
+	form.WriteString(fmt.Sprintf("  End:   %s\n", m.backfillEndInput.View()))
 
+	help := style.FormatHelp(
+		"(enter)", "Start backfill",
+		"(tab)", "Next field",
+		"(esc)", "Cancel",
+	)
+
+	return baseStyle.Width(m.width-2).Render(form.String()) + "\n" + help
 }
 
 var baseStyle = lipgloss.NewStyle().
 	BorderStyle(lipgloss.RoundedBorder()).
 	BorderForeground(lipgloss.Color("240"))
 
+// formatRate formats events per second, keeping a decimal for low rates
+func formatRate(eps float64) string {
+	if eps < 10 {
+		return strconv.FormatFloat(eps, 'f', 1, 64)
+	}
+	return strconv.FormatFloat(eps, 'f', 0, 64)
+}
+
+// formatLag formats how far sending is behind, flagging a lag over a second or skipped events
+func formatLag(lag time.Duration, skipped int) string {
+	formatted := lag.Round(100 * time.Millisecond).String()
+	if skipped > 0 {
+		formatted = fmt.Sprintf("%s, %d skipped", formatted, skipped)
+	}
+
+	if lag > time.Second || skipped > 0 {
+		return trendUpStyle.Render(formatted)
+	}
+	return formatted
+}
+
 func formatLatencyAdaptive(ms float64) string {
 	switch {
 	case ms >= 1000:
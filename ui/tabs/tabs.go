@@ -46,6 +46,17 @@ func (m TabsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case errors.ShowErrorMsg:
 		return m, func() tea.Msg { return msg }
+	case run.ProfileSwitchedMsg:
+		// The clients are swapped even when another tab is shown by now
+		for i, tab := range m.Tabs {
+			if runTab, ok := tab.(*run.RunTabModel); ok {
+				tabModel, cmd := runTab.Update(msg)
+				if updatedTab, ok := tabModel.(TabModel); ok {
+					m.Tabs[i] = updatedTab
+				}
+				return m, cmd
+			}
+		}
 	case tea.KeyMsg:
 		if integrationsTab, ok := m.Tabs[m.ActiveTab].(*integration.IntegrationsTabModel); ok {
 			if integrationsTab.IsInConfigurationState() {
@@ -58,6 +69,15 @@ func (m TabsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 
+		if runTab, ok := m.Tabs[m.ActiveTab].(*run.RunTabModel); ok && runTab.IsInForm() {
+			tabModel, cmd := runTab.Update(msg)
+
+			if updatedTab, ok := tabModel.(TabModel); ok {
+				m.Tabs[m.ActiveTab] = updatedTab
+			}
+			return m, cmd
+		}
+
 		switch msg.String() {
 		case "tab":
 			m.ActiveTab = (m.ActiveTab + 1) % len(m.Tabs)
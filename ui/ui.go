@@ -1,7 +1,6 @@
 package ui
 
 import (
-	"context"
 	"fmt"
 	"time"
 
@@ -38,6 +37,7 @@ type Model struct {
 	tabs           tabs.TabsModel
 	saveController *ProgramContext.SaveController
 	error          *errors.ErrorOverlay
+	profile        string
 }
 
 type ConfigLoadedMsg struct {
@@ -47,7 +47,8 @@ type ConfigLoadedMsg struct {
 	KBClient   *kb.Client
 }
 
-func NewModel() Model {
+// NewModel returns the TUI, connecting with profile instead of the active profile of the config when set
+func NewModel(profile string) Model {
 	programContext := ProgramContext.NewProgramContext()
 	saveController := ProgramContext.NewSaveController(programContext)
 	h := help.New()
@@ -74,6 +75,7 @@ func NewModel() Model {
 		saveController: saveController,
 		screen:         TabsScreen, // Go directly to tabs
 		tabs:           tabs.NewTabsModel(initTabs, programContext),
+		profile:        profile,
 	}
 }
 
@@ -85,13 +87,28 @@ func (m Model) Init() tea.Cmd {
 			return errors.ShowErrorMsg{Message: fmt.Sprintf("Error loading config: %v", err), Fatal: true}
 		}
 
-		esClient, err := elasticsearch.SetClient(cfg.Connection)
+		integrations.SetTemplatesDir(cfg.GetTemplatesDir(cfgPath))
+
+		if m.profile != "" {
+			if err := cfg.OverrideProfile(m.profile); err != nil {
+				log.Debug(err)
+				return errors.ShowErrorMsg{Message: fmt.Sprintf("Error: %v", err), Fatal: true}
+			}
+		}
+
+		connection, err := cfg.ActiveConnection()
+		if err != nil {
+			log.Debug(err)
+			return errors.ShowErrorMsg{Message: fmt.Sprintf("Error: %v", err), Fatal: true}
+		}
+
+		esClient, err := elasticsearch.SetClient(connection)
 		if err != nil {
 			log.Debug(err)
 			return errors.ShowErrorMsg{Message: fmt.Sprintf("Error setting up Elasticsearch client: %v", err), Fatal: true}
 		}
 
-		kbClient, err := kibana.SetClient(cfg.Connection)
+		kbClient, err := kibana.SetClient(connection)
 		if err != nil {
 			log.Debug(err)
 			return errors.ShowErrorMsg{Message: fmt.Sprintf("Error setting up Kibana client: %v", err), Fatal: true}
@@ -127,30 +144,12 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.programContext.DatasetConfigs[integration] = datasetMap
 				}
 				for datasetName, configDataset := range integrationData.Datasets {
-					datasetConfig := ProgramContext.DatasetConfig{
-						Name:                  datasetName,
-						Selected:              configDataset.Enabled,
-						Unit:                  configDataset.Unit,
-						Threshold:             configDataset.Threshold,
-						PreserveEventOriginal: configDataset.PreserveEventOriginal,
-						Events:                configDataset.Events,
-					}
-					datasetMap[datasetName] = datasetConfig
+					datasetMap[datasetName] = ProgramContext.NewDatasetConfig(datasetName, configDataset)
 				}
 			}
 		}
 
-		ctx := context.Background()
-		m.programContext.ESClient = &elasticsearch.Config{
-			Client:    msg.ESClient,
-			Ctx:       ctx,
-			Connected: false,
-		}
-		m.programContext.KBClient = &kibana.Config{
-			Client:    msg.KBClient,
-			Ctx:       context.Background(),
-			Connected: false,
-		}
+		m.programContext.SetClients(msg.ESClient, msg.KBClient)
 
 		// Refresh integrations list to reflect enabled state from config
 		integrationsList, err := integrations.GetIntegrationsFromTemplates()
@@ -169,7 +168,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case errors.ShowErrorMsg:
 		if msg.Fatal {
-			log.Debug("Fatal error: %s", msg.Message)
+			log.Debug(fmt.Sprintf("Fatal error: %s", msg.Message))
 
 			m.error = errors.NewErrorOverlay(msg.Message, true)
 			return m, tea.Batch(